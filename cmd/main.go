@@ -1,20 +1,33 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"time"
+	_ "time/tzdata" // zona waktu tetap ada walau image tidak punya /usr/share/zoneinfo
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 
+	"github.com/rifqi535/expense-tracker-api/internal/blob"
+	"github.com/rifqi535/expense-tracker-api/internal/budget"
 	"github.com/rifqi535/expense-tracker-api/internal/config"
+	"github.com/rifqi535/expense-tracker-api/internal/export"
+	"github.com/rifqi535/expense-tracker-api/internal/fx"
 	"github.com/rifqi535/expense-tracker-api/internal/handlers"
+	"github.com/rifqi535/expense-tracker-api/internal/mailer"
 	"github.com/rifqi535/expense-tracker-api/internal/middleware"
+	"github.com/rifqi535/expense-tracker-api/internal/models"
+	"github.com/rifqi535/expense-tracker-api/internal/notify"
+	"github.com/rifqi535/expense-tracker-api/internal/recurring"
 	"github.com/rifqi535/expense-tracker-api/internal/repository"
+	"github.com/rifqi535/expense-tracker-api/internal/seed"
+	"github.com/rifqi535/expense-tracker-api/internal/token"
 )
 
 // init() dipanggil otomatis sebelum main()
@@ -33,15 +46,22 @@ func main() {
 		log.Fatal("❌ DB_DSN tidak ditemukan di environment")
 	}
 
-	jwtSecret := getEnv("JWT_SECRET", "")
-	if jwtSecret == "" {
-		log.Fatal("❌ JWT_SECRET tidak ditemukan di environment")
-	}
-	log.Println("✅ JWT_SECRET terbaca")
-
 	// ⬇️ Tambahin ini
 	cfg := config.Load()
-	middleware.InitJWT(cfg.JWTSecret)
+	// JWT sudah pakai kunci asimetris, secret ini cuma untuk link bertanda tangan di email
+	if cfg.EmailVerificationSecret == "" {
+		log.Fatal("❌ EMAIL_VERIFICATION_SECRET tidak ditemukan di environment")
+	}
+	jwtService, err := token.NewService(token.Options{
+		KeysDir:        cfg.JWTKeysDir,
+		ActiveKID:      cfg.JWTActiveKID,
+		Issuer:         cfg.JWTIssuer,
+		AccessTokenTTL: cfg.AccessTokenTTL,
+	})
+	if err != nil {
+		log.Fatalf("❌ gagal load JWT keys: %v", err)
+	}
+	middleware.InitJWT(jwtService)
 	// ⬆️
 
 	// 🔹 Koneksi ke database
@@ -51,44 +71,268 @@ func main() {
 	}
 	fmt.Println("✅ Database connected")
 
+	// 🔹 Mailer (smtp / log)
+	mail, err := mailer.New(cfg)
+	if err != nil {
+		log.Fatalf("❌ gagal setup mailer: %v", err)
+	}
+
+	// 🔹 kategori default user baru (set bawaan atau DEFAULT_CATEGORIES_FILE)
+	var defaultCategories seed.CategorySet
+	if cfg.SeedDefaultCategories {
+		if defaultCategories, err = seed.LoadCategories(cfg.DefaultCategoriesFile); err != nil {
+			log.Fatalf("❌ gagal baca kategori default: %v", err)
+		}
+	}
+
+	// 🔹 Storage lampiran (local / s3)
+	blobStore, err := blob.New(cfg)
+	if err != nil {
+		log.Fatalf("❌ gagal setup storage: %v", err)
+	}
+
 	// 🔹 Setup Gin & route
 	r := gin.Default()
+	// IP client dipakai untuk limit login per IP, X-Forwarded-For cuma dipercaya dari proxy yang terdaftar
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatalf("❌ TRUSTED_PROXIES tidak valid: %v", err)
+	}
 
 	// repo & handler
 	categoryRepo := repository.NewCategoryRepo(db)
 	expenseRepo := repository.NewExpenseRepo(db)
-	authHandler := handlers.NewAuthHandler(db)
+	authHandler := handlers.NewAuthHandler(db, cfg, mail, jwtService, defaultCategories)
 	categoryHandler := handlers.NewCategoryHandler(categoryRepo)
-	expHandler := handlers.NewExpenseHandler(expenseRepo)
+	tagHandler := handlers.NewTagHandler(repository.NewTagRepo(db))
+	attachmentRepo := repository.NewAttachmentRepo(db)
+	attachmentHandler := handlers.NewAttachmentHandler(attachmentRepo, expenseRepo, blobStore, cfg)
+	exchangeRateRepo := repository.NewExchangeRateRepo(db)
+	expHandler := handlers.NewExpenseHandler(expenseRepo, repository.NewUserRepo(db), exchangeRateRepo, cfg.DefaultCurrency)
+	exchangeRateHandler := handlers.NewExchangeRateHandler(exchangeRateRepo)
+	budgetRepo := repository.NewBudgetRepo(db)
+	allocationRepo := repository.NewAllocationRepo(db)
+	budgetTracker := budget.NewTracker(budgetRepo, allocationRepo, categoryRepo, expenseRepo, repository.NewUserRepo(db), exchangeRateRepo, cfg.DefaultCurrency)
+	budgetHandler := handlers.NewBudgetHandler(budgetRepo, allocationRepo, repository.NewUserRepo(db), budgetTracker)
+	notificationRepo := repository.NewNotificationRepo(db)
+	notificationHandler := handlers.NewNotificationHandler(notificationRepo)
+	apiKeyRepo := repository.NewAPIKeyRepo(db)
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyRepo)
+	jwksHandler := handlers.NewJWKSHandler(jwtService)
+	adminHandler := handlers.NewAdminHandler(repository.NewUserRepo(db), repository.NewAdminRepo(db), authHandler)
+
+	// 🔹 import kurs dari file (opsional)
+	if cfg.ExchangeRatesFile != "" {
+		loadExchangeRates(exchangeRateRepo, cfg.ExchangeRatesFile)
+	}
+
+	// expense berulang, dibuat scheduler di background
+	recurringRepo := repository.NewRecurringExpenseRepo(db)
+	recurringScheduler := recurring.NewScheduler(recurringRepo, cfg.RecurringInterval)
+	recurringHandler := handlers.NewRecurringExpenseHandler(recurringRepo, repository.NewUserRepo(db), recurringScheduler)
+	go recurringScheduler.Run(context.Background())
+
+	// alert budget 50/80/100%, dicek di background setiap expense berubah lalu dikirim ke inbox, email & webhook
+	notifier := notify.NewDispatcher(repository.NewUserRepo(db), notificationRepo,
+		notify.NewInApp(notificationRepo),
+		notify.NewEmail(mail),
+		notify.NewWebhook(cfg.WebhookTimeout, cfg.WebhookAllowPrivate),
+	)
+	budgetAlerter := budget.NewAlerter(budgetTracker, notificationRepo, notifier)
+	expenseRepo.Observe(budgetAlerter)
+	recurringRepo.Observe(budgetAlerter)
+	categoryRepo.Observe(budgetAlerter)
+	go budgetAlerter.Run(context.Background())
+
+	// export data pribadi, zip dibuat worker di background
+	exportRepo := repository.NewDataExportRepo(db)
+	exportBuilder := export.NewBuilder(repository.NewUserRepo(db), categoryRepo, expenseRepo, recurringRepo, attachmentRepo, budgetRepo, allocationRepo, notificationRepo)
+	exportWorker := export.NewWorker(exportRepo, exportBuilder, cfg.ExportDir, cfg.ExportRetention)
+	exportHandler := handlers.NewExportHandler(exportRepo, exportWorker, cfg)
+	go exportWorker.Run(context.Background())
+
+	// logout / revoke harus langsung berlaku di AuthMiddleware
+	middleware.InitSessionStore(repository.NewSessionRepo(db))
+	middleware.InitAPIKeyStore(apiKeyRepo)
+
+	// 🔹 public keys buat verifikasi JWT
+	r.GET("/.well-known/jwks.json", jwksHandler.Get)
 
 	// 🔹 auth routes (public)
 	r.POST("/register", authHandler.Register)
 	r.POST("/login", authHandler.Login)
+	r.POST("/auth/refresh", authHandler.Refresh)
+	r.POST("/auth/password/forgot", authHandler.ForgotPassword)
+	r.POST("/auth/password/reset", authHandler.ResetPassword)
+	r.GET("/auth/verify-email", authHandler.VerifyEmail)
+	r.POST("/auth/verify-email", authHandler.VerifyEmail)
+	r.POST("/auth/verify-email/resend", authHandler.ResendVerification)
+	r.POST("/auth/mfa/verify", authHandler.VerifyMFA)
+	r.GET("/auth/unlock", authHandler.Unlock)
+	r.POST("/auth/unlock", authHandler.Unlock)
+	r.GET("/auth/email/confirm", authHandler.ConfirmEmailChange)
+	r.POST("/auth/email/confirm", authHandler.ConfirmEmailChange)
+	r.GET("/auth/account/restore", authHandler.RestoreAccount)
+	r.POST("/auth/account/restore", authHandler.RestoreAccount)
+	r.GET("/exports/download", exportHandler.Download)
+	r.GET("/auth/oidc/:provider/start", authHandler.OIDCStart)
+	r.GET("/auth/oidc/:provider/callback", authHandler.OIDCCallback)
+
+	// 🔹 auth routes (protected, token restricted juga boleh)
+	authRoutes := r.Group("/auth")
+	authRoutes.Use(middleware.AuthMiddleware(middleware.AllowRestricted()))
+	{
+		authRoutes.POST("/logout", authHandler.Logout)
+		authRoutes.POST("/logout-all", authHandler.LogoutAll)
+	}
+
+	// 🔹 MFA management (protected)
+	mfaRoutes := r.Group("/auth/mfa")
+	mfaRoutes.Use(middleware.AuthMiddleware())
+	{
+		mfaRoutes.POST("/enroll", authHandler.EnrollMFA)
+		mfaRoutes.POST("/confirm", authHandler.ConfirmMFA)
+		mfaRoutes.POST("/disable", authHandler.DisableMFA)
+	}
 
 	// 🔹 user routes (protected)
 	userRoutes := r.Group("/user")
-	userRoutes.Use(middleware.AuthMiddleware())
+	userRoutes.Use(middleware.AuthMiddleware(middleware.AllowRestricted(), middleware.AllowAPIKey()))
+	{
+		userRoutes.GET("/profile", middleware.RequireScope(middleware.ScopeRead), authHandler.GetProfile)
+		userRoutes.GET("/login-events", middleware.RequireScope(middleware.ScopeRead), authHandler.ListLoginEvents)
+	}
+
+	// 🔹 account self-service (JWT only)
+	accountRoutes := r.Group("/user")
+	accountRoutes.Use(middleware.AuthMiddleware(middleware.AllowRestricted()))
+	{
+		accountRoutes.PATCH("/profile", authHandler.UpdateProfile)
+		accountRoutes.POST("/password", authHandler.ChangePassword)
+		accountRoutes.POST("/email", authHandler.RequestEmailChange)
+		accountRoutes.DELETE("", authHandler.DeleteAccount)
+	}
+
+	// 🔹 export data pribadi (JWT only)
+	exportRoutes := r.Group("/user/exports")
+	exportRoutes.Use(middleware.AuthMiddleware(middleware.AllowRestricted()))
+	{
+		exportRoutes.POST("", exportHandler.Create)
+		exportRoutes.GET("", exportHandler.List)
+		exportRoutes.GET("/:id", exportHandler.Get)
+	}
+
+	// 🔹 sesi login (JWT only, API key tidak punya sesi)
+	sessionRoutes := r.Group("/user/sessions")
+	sessionRoutes.Use(middleware.AuthMiddleware(middleware.AllowRestricted()))
+	{
+		sessionRoutes.GET("", authHandler.ListSessions)
+		sessionRoutes.DELETE("/:id", authHandler.RevokeSession)
+	}
+
+	// 🔹 inbox & setting notifikasi (JWT only)
+	notificationRoutes := r.Group("/user/notifications")
+	notificationRoutes.Use(middleware.AuthMiddleware())
 	{
-		userRoutes.GET("/profile", authHandler.GetProfile)
+		notificationRoutes.GET("", notificationHandler.List)
+		notificationRoutes.POST("/read-all", notificationHandler.ReadAll)
+		notificationRoutes.POST("/:id/read", notificationHandler.Read)
+		notificationRoutes.POST("/:id/unread", notificationHandler.Unread)
+		notificationRoutes.DELETE("/:id", notificationHandler.Delete)
+		notificationRoutes.GET("/settings", notificationHandler.GetSettings)
+		notificationRoutes.PUT("/settings", notificationHandler.UpdateSettings)
 	}
 
-	// 🔹 protected routes
+	// 🔹 API key management (JWT only, key tidak bisa bikin key lain)
+	keyRoutes := r.Group("/user/api-keys")
+	keyRoutes.Use(middleware.AuthMiddleware())
+	{
+		keyRoutes.GET("", apiKeyHandler.List)
+		keyRoutes.POST("", apiKeyHandler.Create)
+		keyRoutes.PATCH("/:id", apiKeyHandler.Update)
+		keyRoutes.DELETE("/:id", apiKeyHandler.Delete)
+	}
+
+	// 🔹 protected routes (JWT atau API key)
 	api := r.Group("/")
-	api.Use(middleware.AuthMiddleware())
+	api.Use(middleware.AuthMiddleware(middleware.AllowAPIKey()))
 	{
+		read := middleware.RequireScope(middleware.ScopeRead)
+		writeCategories := middleware.RequireScope(middleware.ScopeCategoriesWrite)
+		writeExpenses := middleware.RequireScope(middleware.ScopeExpensesWrite)
+		writeBudgets := middleware.RequireScope(middleware.ScopeBudgetsWrite)
+
 		// categories
-		api.GET("/categories", categoryHandler.List)
-		api.POST("/categories", categoryHandler.Create)
-		api.PUT("/categories/:id", categoryHandler.Update)
-		api.DELETE("/categories/:id", categoryHandler.Delete)
+		api.GET("/categories", read, categoryHandler.List)
+		api.POST("/categories", writeCategories, categoryHandler.Create)
+		api.PUT("/categories/:id", writeCategories, categoryHandler.Update)
+		api.DELETE("/categories/:id", writeCategories, categoryHandler.Delete)
+		api.POST("/categories/:id/merge", writeCategories, categoryHandler.Merge)
+
+		// tags, ikut scope expenses:write karena tag juga otomatis dibuat saat simpan expense
+		api.GET("/tags", read, tagHandler.List)
+		api.POST("/tags", writeExpenses, tagHandler.Create)
+		api.PUT("/tags/:id", writeExpenses, tagHandler.Update)
+		api.DELETE("/tags/:id", writeExpenses, tagHandler.Delete)
 
 		// expenses
-		api.GET("/expenses", expHandler.List)
-		api.POST("/expenses", expHandler.Create)
-		api.PUT("/expenses/:id", expHandler.Update)
-		api.DELETE("/expenses/:id", expHandler.Delete)
+		api.GET("/expenses", read, expHandler.List)
+		api.GET("/expenses/report", read, expHandler.Report)
+		api.POST("/expenses", writeExpenses, expHandler.Create)
+		api.PUT("/expenses/:id", writeExpenses, expHandler.Update)
+		api.DELETE("/expenses/:id", writeExpenses, expHandler.Delete)
+
+		// lampiran expense (foto / PDF struk)
+		api.GET("/expenses/:id/attachments", read, attachmentHandler.List)
+		api.POST("/expenses/:id/attachments", writeExpenses, attachmentHandler.Upload)
+		api.GET("/expenses/:id/attachments/:attachment_id", read, attachmentHandler.Download)
+		api.DELETE("/expenses/:id/attachments/:attachment_id", writeExpenses, attachmentHandler.Delete)
+
+		// expense berulang
+		api.GET("/recurring-expenses", read, recurringHandler.List)
+		api.POST("/recurring-expenses", writeExpenses, recurringHandler.Create)
+		api.GET("/recurring-expenses/:id", read, recurringHandler.Get)
+		api.PUT("/recurring-expenses/:id", writeExpenses, recurringHandler.Update)
+		api.DELETE("/recurring-expenses/:id", writeExpenses, recurringHandler.Delete)
+		api.GET("/recurring-expenses/:id/occurrences", read, recurringHandler.Occurrences)
+		api.POST("/recurring-expenses/:id/occurrences/:date/skip", writeExpenses, recurringHandler.SkipOccurrence)
+		api.PUT("/recurring-expenses/:id/occurrences/:date", writeExpenses, recurringHandler.UpdateOccurrence)
+		api.DELETE("/recurring-expenses/:id/occurrences/:date", writeExpenses, recurringHandler.ResetOccurrence)
+
+		// budget bulanan
+		api.GET("/budgets", read, budgetHandler.List)
+		api.GET("/budgets/status", read, budgetHandler.Status)
+		api.POST("/budgets", writeBudgets, budgetHandler.Create)
+		api.PUT("/budgets/:id", writeBudgets, budgetHandler.Update)
+		api.DELETE("/budgets/:id", writeBudgets, budgetHandler.Delete)
+
+		// envelope: saldo dengan rollover + ledger alokasi
+		api.GET("/budgets/envelopes", read, budgetHandler.Envelopes)
+		api.GET("/budgets/allocations", read, budgetHandler.ListAllocations)
+		api.POST("/budgets/allocations", writeBudgets, budgetHandler.Allocate)
+		api.POST("/budgets/allocations/transfer", writeBudgets, budgetHandler.Transfer)
+		api.DELETE("/budgets/allocations/:id", writeBudgets, budgetHandler.DeleteAllocation)
+	}
+
+	// 🔹 admin routes
+	admin := r.Group("/admin")
+	admin.Use(middleware.AuthMiddleware(), middleware.RequireRole(models.RoleAdmin))
+	{
+		admin.GET("/users", adminHandler.ListUsers)
+		admin.GET("/users/:id", adminHandler.GetUser)
+		admin.POST("/users/:id/disable", adminHandler.DisableUser)
+		admin.POST("/users/:id/enable", adminHandler.EnableUser)
+		admin.PUT("/users/:id/role", adminHandler.SetRole)
+		admin.POST("/users/:id/force-password-reset", adminHandler.ForcePasswordReset)
+		admin.POST("/users/:id/unlock", adminHandler.UnlockUser)
+		admin.GET("/stats", adminHandler.Stats)
+		admin.GET("/exchange-rates", exchangeRateHandler.List)
+		admin.PUT("/exchange-rates", exchangeRateHandler.Upsert)
+		admin.DELETE("/exchange-rates/:id", exchangeRateHandler.Delete)
 	}
 
+	// 🔹 hapus permanen akun yang masa tenggangnya sudah lewat
+	go purgeDeletedAccounts(repository.NewUserRepo(db), blobStore, cfg.AccountDeletionGrace, cfg.AccountPurgeInterval)
+
 	// Jalankan server
 	srv := &http.Server{
 		Addr:    ":" + port,
@@ -108,3 +352,42 @@ func getEnv(key, def string) string {
 	}
 	return def
 }
+
+// purgeDeletedAccounts jalan di background, cek berkala akun yang sudah waktunya dihapus
+func purgeDeletedAccounts(users *repository.UserRepo, store blob.BlobStore, grace, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for ; ; <-ticker.C {
+		ctx := context.Background()
+		n, keys, err := users.PurgeDeleted(ctx, grace)
+		if err != nil {
+			log.Println("❌ gagal hapus akun:", err)
+			continue
+		}
+		if n > 0 {
+			log.Printf("🗑️ %d akun dihapus permanen", n)
+		}
+		// file lampiran ikut dibuang setelah datanya terhapus
+		for _, key := range keys {
+			if err := store.Delete(ctx, key); err != nil {
+				log.Println("❌ gagal hapus lampiran:", err)
+			}
+		}
+	}
+}
+
+// loadExchangeRates import kurs dari EXCHANGE_RATES_FILE, gagal cuma di-log supaya server tetap jalan
+func loadExchangeRates(repo *repository.ExchangeRateRepo, path string) {
+	rates, err := fx.LoadFile(path)
+	if err != nil {
+		log.Println("❌ gagal baca file kurs:", err)
+		return
+	}
+	n, err := repo.Upsert(context.Background(), rates)
+	if err != nil {
+		log.Println("❌ gagal simpan kurs:", err)
+		return
+	}
+	log.Printf("✅ %d kurs di-import dari %s", n, path)
+}
@@ -0,0 +1,60 @@
+// Package blob penyimpanan file (lampiran expense) di filesystem lokal atau object storage S3-compatible
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/rifqi535/expense-tracker-api/internal/config"
+)
+
+// ErrNotFound object dengan key tersebut tidak ada
+var ErrNotFound = errors.New("blob not found")
+
+// BlobStore simpan / ambil / hapus object berdasarkan key (path dipisah "/")
+type BlobStore interface {
+	// Put tulis object, size = jumlah byte dari r. Key yang sama ditimpa.
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get buka object, caller wajib Close. ErrNotFound kalau tidak ada.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete hapus object, tidak error kalau memang sudah tidak ada
+	Delete(ctx context.Context, key string) error
+}
+
+// New pilih implementasi BlobStore sesuai STORAGE_DRIVER
+func New(cfg *config.Config) (BlobStore, error) {
+	switch strings.ToLower(cfg.StorageDriver) {
+	case "", "local":
+		return NewLocalStore(cfg.StorageDir)
+	case "s3":
+		if cfg.S3Endpoint == "" || cfg.S3Bucket == "" {
+			return nil, fmt.Errorf("S3_ENDPOINT and S3_BUCKET are required for STORAGE_DRIVER=s3")
+		}
+		return NewS3Store(S3Options{
+			Endpoint:        cfg.S3Endpoint,
+			Region:          cfg.S3Region,
+			Bucket:          cfg.S3Bucket,
+			AccessKeyID:     cfg.S3AccessKeyID,
+			SecretAccessKey: cfg.S3SecretAccessKey,
+			ForcePathStyle:  cfg.S3ForcePathStyle,
+		})
+	default:
+		return nil, fmt.Errorf("unknown STORAGE_DRIVER %q", cfg.StorageDriver)
+	}
+}
+
+// validKey key relatif tanpa segmen kosong / "." / ".." supaya tidak bisa keluar dari root
+func validKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return fmt.Errorf("invalid blob key %q", key)
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return fmt.Errorf("invalid blob key %q", key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package budget
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rifqi535/expense-tracker-api/internal/calendar"
+	"github.com/rifqi535/expense-tracker-api/internal/models"
+	"github.com/rifqi535/expense-tracker-api/internal/notify"
+	"github.com/rifqi535/expense-tracker-api/internal/repository"
+)
+
+// AlertThresholds persen pemakaian budget yang dikabarkan, urut naik
+var AlertThresholds = []int{50, 80, 100}
+
+// alertQueueSize antrian cek per user, kalau penuh cek dilewati (dicek lagi di perubahan berikutnya)
+const alertQueueSize = 256
+
+type alertJob struct {
+	userID  uuid.UUID
+	spentAt []time.Time
+}
+
+// Alerter cek ambang batas budget setiap kali expense user berubah, di background
+// supaya request expense tidak ikut menunggu. Tiap ambang cuma dikirim sekali per budget per bulan.
+type Alerter struct {
+	tracker       *Tracker
+	notifications *repository.NotificationRepo
+	dispatcher    *notify.Dispatcher
+	queue         chan alertJob
+}
+
+func NewAlerter(tracker *Tracker, notifications *repository.NotificationRepo, dispatcher *notify.Dispatcher) *Alerter {
+	return &Alerter{tracker: tracker, notifications: notifications, dispatcher: dispatcher, queue: make(chan alertJob, alertQueueSize)}
+}
+
+// ExpensesChanged implement repository.ExpenseObserver (tidak nge-block)
+func (a *Alerter) ExpensesChanged(userID uuid.UUID, spentAt ...time.Time) {
+	select {
+	case a.queue <- alertJob{userID: userID, spentAt: spentAt}:
+	default:
+		log.Printf("⚠️ antrian alert budget penuh, cek user %s dilewati", userID)
+	}
+}
+
+// Run loop utama, berhenti kalau ctx selesai
+func (a *Alerter) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case job := <-a.queue:
+			if err := a.Check(ctx, job.userID, time.Now(), job.spentAt...); err != nil {
+				log.Printf("❌ gagal cek alert budget user %s: %v", job.userID, err)
+			}
+		}
+	}
+}
+
+// Check budget bulan berjalan (zona waktu user). Bulan lalu tidak dikabarkan lagi,
+// jadi kalau semua tanggal di at ada di luar bulan ini tidak ada yang dicek.
+func (a *Alerter) Check(ctx context.Context, userID uuid.UUID, now time.Time, at ...time.Time) error {
+	cal, _, err := a.tracker.prefs(ctx, userID)
+	if err != nil {
+		return err
+	}
+	start := cal.Truncate(now, calendar.Month)
+	if len(at) > 0 {
+		end := cal.Next(start, calendar.Month)
+		inMonth := false
+		for _, t := range at {
+			if !t.Before(start) && t.Before(end) {
+				inMonth = true
+				break
+			}
+		}
+		if !inMonth {
+			return nil
+		}
+	}
+
+	report, err := a.tracker.Month(ctx, userID, "", now)
+	if err != nil {
+		return err
+	}
+	for _, s := range report.Budgets {
+		var crossed []int
+		for _, th := range AlertThresholds {
+			if s.Limit.IsPositive() && s.Spent.ReachedPercent(s.Limit, int64(th)) {
+				crossed = append(crossed, th)
+			}
+		}
+		if len(crossed) == 0 {
+			continue
+		}
+		claimed, err := a.notifications.ClaimBudgetAlerts(ctx, s.BudgetID, start, crossed)
+		if err != nil {
+			return err
+		}
+		if len(claimed) == 0 {
+			continue
+		}
+		// beberapa ambang terlewati sekaligus → cukup satu notifikasi untuk yang tertinggi
+		if err := a.dispatcher.Send(ctx, alertNotification(userID, report.Month, s, claimed[len(claimed)-1])); err != nil {
+			log.Printf("❌ alert budget %s tidak terkirim ke semua channel: %v", s.BudgetID, err)
+		}
+	}
+	return nil
+}
+
+func alertNotification(userID uuid.UUID, month string, s Status, threshold int) *models.Notification {
+	name := "Total budget"
+	if s.CategoryTitle != nil {
+		name = fmt.Sprintf("Budget %q", *s.CategoryTitle)
+	}
+	title := fmt.Sprintf("%s reached %d%%", name, threshold)
+	if threshold >= 100 {
+		title = fmt.Sprintf("%s exceeded", name)
+	}
+	body := fmt.Sprintf("%s for %s: spent %s of %s %s (%.2f%%), projected %s by month end.",
+		name, month, s.Spent.String(), s.Limit.String(), s.Currency, s.PercentUsed, s.Projected.String())
+
+	data := map[string]interface{}{
+		"budget_id":    s.BudgetID,
+		"category_id":  s.CategoryID,
+		"month":        month,
+		"threshold":    threshold,
+		"percent_used": s.PercentUsed,
+		"limit":        s.Limit,
+		"spent":        s.Spent,
+		"projected":    s.Projected,
+		"currency":     s.Currency,
+	}
+	return &models.Notification{
+		UserID: userID,
+		Kind:   models.NotificationBudgetAlert,
+		Title:  title,
+		Body:   body,
+		Data:   data,
+	}
+}
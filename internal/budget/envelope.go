@@ -0,0 +1,293 @@
+package budget
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rifqi535/expense-tracker-api/internal/calendar"
+	"github.com/rifqi535/expense-tracker-api/internal/fx"
+	"github.com/rifqi535/expense-tracker-api/internal/models"
+	"github.com/rifqi535/expense-tracker-api/internal/money"
+	"github.com/rifqi535/expense-tracker-api/internal/repository"
+)
+
+// Envelope saldo satu kategori di satu bulan, semua nominal dalam mata uang dasar user
+type Envelope struct {
+	CategoryID    uuid.UUID   `json:"category_id"`
+	CategoryTitle string      `json:"category_title"`
+	Rollover      string      `json:"rollover"`
+	CarriedIn     money.Money `json:"carried_in"` // dari bulan sebelumnya, negatif = defisit
+	Budgeted      money.Money `json:"budgeted"`   // limit budget kategori yang berlaku bulan ini
+	Allocated     money.Money `json:"allocated"`  // total ledger alokasi bulan ini
+	Spent         money.Money `json:"spent"`
+	Available     money.Money `json:"available"` // carried_in + budgeted + allocated - spent
+	CarryOut      money.Money `json:"carry_out"` // yang dibawa ke bulan berikutnya sesuai aturan rollover
+	// MissingRates ada nominal mata uang lain (bulan ini atau sebelumnya) yang kursnya tidak tersedia
+	MissingRates bool `json:"missing_rates"`
+}
+
+// EnvelopeReport semua envelope di satu bulan
+type EnvelopeReport struct {
+	Month     string     `json:"month"` // YYYY-MM
+	Timezone  string     `json:"timezone"`
+	Currency  string     `json:"currency"`
+	Envelopes []Envelope `json:"envelopes"`
+}
+
+// envelope state perhitungan satu envelope dari bulan pertamanya
+type envelope struct {
+	first     string // YYYY-MM pertama yang dihitung
+	budgeted  map[string]money.Money
+	allocated map[string]money.Money
+	spent     map[string]money.Money
+	missing   bool
+	// sinceExpense envelope cuma karena aturan rollover (tanpa budget / alokasi):
+	// first = bulan expense pertamanya, supaya carry-in tidak tergantung bulan yang diminta
+	sinceExpense bool
+}
+
+// Envelopes saldo envelope bulan month ("YYYY-MM", "" = bulan ini).
+// Envelope = kategori yang punya aturan rollover, budget kategori, atau alokasi.
+// Saldo selalu dihitung ulang dari expenses + budget + ledger sejak bulan pertama envelope
+// (budget / alokasi pertama, atau expense pertama kalau envelope cuma punya aturan rollover),
+// jadi expense lama yang diubah / dihapus / dipindah kategori langsung ikut terhitung.
+// Expense di subkategori masuk ke envelope leluhur terdekat kalau subkategori itu bukan envelope.
+func (t *Tracker) Envelopes(ctx context.Context, userID uuid.UUID, month string, now time.Time) (*EnvelopeReport, error) {
+	cal, base, err := t.prefs(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	start, err := monthStart(cal, month, now)
+	if err != nil {
+		return nil, err
+	}
+	current := start.Format("2006-01")
+	report := &EnvelopeReport{Month: current, Timezone: cal.Location.String(), Currency: base, Envelopes: []Envelope{}}
+
+	categories, err := t.categories.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	allocations, err := t.allocations.ListUntil(ctx, userID, start)
+	if err != nil {
+		return nil, err
+	}
+	budgets, err := t.budgets.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// --- DAFTAR ENVELOPE + BULAN PERTAMANYA ---
+	parents := make(map[uuid.UUID]*uuid.UUID, len(categories))
+	envelopes := map[uuid.UUID]*envelope{}
+	track := func(c models.Category, first string) {
+		e, ok := envelopes[c.ID]
+		if !ok {
+			e = &envelope{
+				first:     first,
+				budgeted:  map[string]money.Money{},
+				allocated: map[string]money.Money{},
+				spent:     map[string]money.Money{},
+			}
+			envelopes[c.ID] = e
+		}
+		if first < e.first {
+			e.first = first
+		}
+	}
+	byID := make(map[uuid.UUID]models.Category, len(categories))
+	for _, c := range categories {
+		byID[c.ID] = c
+		parents[c.ID] = c.ParentID
+	}
+	for _, a := range allocations {
+		if c, ok := byID[a.CategoryID]; ok {
+			track(c, a.Month.Format("2006-01"))
+		}
+	}
+	for _, b := range budgets {
+		if b.CategoryID == nil || b.StartMonth.Format("2006-01") > current {
+			continue
+		}
+		if c, ok := byID[*b.CategoryID]; ok {
+			track(c, b.StartMonth.Format("2006-01"))
+		}
+	}
+	for _, c := range categories {
+		if _, ok := envelopes[c.ID]; !ok && c.Rollover != models.RolloverNone {
+			track(c, current)
+			envelopes[c.ID].sinceExpense = true
+		}
+	}
+	if len(envelopes) == 0 {
+		return report, nil
+	}
+
+	earliest, sinceExpense := current, false
+	for _, e := range envelopes {
+		if e.first < earliest {
+			earliest = e.first
+		}
+		sinceExpense = sinceExpense || e.sinceExpense
+	}
+	from, err := time.ParseInLocation("2006-01", earliest, cal.Location)
+	if err != nil {
+		return nil, err
+	}
+	to := cal.Next(start, calendar.Month)
+	filter := repository.ExpenseFilter{StartDate: &from, EndDate: &to}
+	if sinceExpense {
+		// bulan expense pertama belum diketahui, ambil semua expense sampai bulan ini
+		filter.StartDate = nil
+	}
+
+	// --- LIMIT BUDGET & LEDGER PER BULAN (kurs tanggal 1 bulan itu) ---
+	conv := fx.NewConverter(t.rates)
+	for _, b := range budgets {
+		if b.CategoryID == nil {
+			continue
+		}
+		e, ok := envelopes[*b.CategoryID]
+		if !ok {
+			continue
+		}
+		for m := monthOf(e.first); !monthAfter(m, current); m = m.AddDate(0, 1, 0) {
+			key := m.Format("2006-01")
+			if key < b.StartMonth.Format("2006-01") || (b.EndMonth != nil && key > b.EndMonth.Format("2006-01")) {
+				continue
+			}
+			if err := e.add(ctx, conv, e.budgeted, key, b.Amount, b.Currency, base, cal); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, a := range allocations {
+		if e, ok := envelopes[a.CategoryID]; ok {
+			if err := e.add(ctx, conv, e.allocated, a.Month.Format("2006-01"), a.Amount, a.Currency, base, cal); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// --- EXPENSE PER BULAN, DIKONVERSI PER HARI ---
+	rows, err := t.expenses.CategoryDailyTotals(ctx, userID, cal.Location.String(), filter)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		e := owner(envelopes, parents, row.CategoryID)
+		if e == nil {
+			continue
+		}
+		if e.sinceExpense && row.Day[:7] < e.first {
+			e.first = row.Day[:7]
+		}
+		if row.Day[:7] < e.first {
+			continue
+		}
+		day, err := cal.ParseDate(row.Day)
+		if err != nil {
+			continue
+		}
+		converted, _, found, err := conv.Convert(ctx, row.Total, row.Currency, base, day)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			e.missing = true
+			continue
+		}
+		e.spent[row.Day[:7]] = e.spent[row.Day[:7]].Add(converted)
+	}
+
+	// --- SALDO BULAN DEMI BULAN ---
+	for _, c := range categories {
+		e, ok := envelopes[c.ID]
+		if !ok {
+			continue
+		}
+		var carry money.Money
+		for m := monthOf(e.first); !monthAfter(m, current); m = m.AddDate(0, 1, 0) {
+			key := m.Format("2006-01")
+			available := carry.Add(e.budgeted[key]).Add(e.allocated[key]).Sub(e.spent[key])
+			next := rollover(c.Rollover, available)
+			if key == current {
+				report.Envelopes = append(report.Envelopes, Envelope{
+					CategoryID:    c.ID,
+					CategoryTitle: c.Title,
+					Rollover:      c.Rollover,
+					CarriedIn:     carry,
+					Budgeted:      e.budgeted[key],
+					Allocated:     e.allocated[key],
+					Spent:         e.spent[key],
+					Available:     available,
+					CarryOut:      next,
+					MissingRates:  e.missing,
+				})
+			}
+			carry = next
+		}
+	}
+	return report, nil
+}
+
+// add tambahkan amount (dikonversi ke base dengan kurs tanggal 1 bulan key) ke totals[key]
+func (e *envelope) add(ctx context.Context, conv *fx.Converter, totals map[string]money.Money, key string, amount money.Money, currency, base string, cal calendar.Calendar) error {
+	day, err := time.ParseInLocation("2006-01", key, cal.Location)
+	if err != nil {
+		return err
+	}
+	converted, _, found, err := conv.Convert(ctx, amount, currency, base, day)
+	if err != nil {
+		return err
+	}
+	if !found {
+		e.missing = true
+		return nil
+	}
+	totals[key] = totals[key].Add(converted)
+	return nil
+}
+
+// owner envelope untuk expense di kategori id: kategori itu sendiri atau leluhur terdekat yang envelope
+func owner(envelopes map[uuid.UUID]*envelope, parents map[uuid.UUID]*uuid.UUID, id uuid.UUID) *envelope {
+	for depth := 0; depth < repository.MaxCategoryDepth; depth++ {
+		if e, ok := envelopes[id]; ok {
+			return e
+		}
+		parent := parents[id]
+		if parent == nil {
+			return nil
+		}
+		id = *parent
+	}
+	return nil
+}
+
+// rollover bagian saldo akhir bulan yang dibawa ke bulan berikutnya
+func rollover(rule string, available money.Money) money.Money {
+	switch rule {
+	case models.RolloverFull:
+		return available
+	case models.RolloverSurplus:
+		if available.IsPositive() {
+			return available
+		}
+	case models.RolloverDeficit:
+		if available.IsNegative() {
+			return available
+		}
+	}
+	return money.Money{}
+}
+
+// monthOf "YYYY-MM" → tanggal 1 (UTC), cuma dipakai untuk iterasi bulan
+func monthOf(key string) time.Time {
+	m, _ := time.Parse("2006-01", key)
+	return m
+}
+
+func monthAfter(m time.Time, key string) bool {
+	return m.Format("2006-01") > key
+}
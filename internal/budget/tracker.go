@@ -0,0 +1,198 @@
+// Package budget hitung pemakaian budget bulanan dari tabel expenses
+package budget
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rifqi535/expense-tracker-api/internal/calendar"
+	"github.com/rifqi535/expense-tracker-api/internal/fx"
+	"github.com/rifqi535/expense-tracker-api/internal/models"
+	"github.com/rifqi535/expense-tracker-api/internal/money"
+	"github.com/rifqi535/expense-tracker-api/internal/repository"
+	"gorm.io/gorm"
+)
+
+var ErrInvalidMonth = errors.New("invalid month, use YYYY-MM")
+
+// Status pemakaian satu budget di satu bulan, semua nominal dalam mata uang budget
+type Status struct {
+	BudgetID      uuid.UUID   `json:"budget_id"`
+	CategoryID    *uuid.UUID  `json:"category_id"` // nil = budget total
+	CategoryTitle *string     `json:"category_title"`
+	Currency      string      `json:"currency"`
+	Limit         money.Money `json:"limit"`
+	Spent         money.Money `json:"spent"`
+	Remaining     money.Money `json:"remaining"` // negatif = lewat batas
+	PercentUsed   float64     `json:"percent_used"`
+	// Projected perkiraan total akhir bulan dengan kecepatan belanja sejauh ini
+	Projected money.Money `json:"projected"`
+	// MissingRates ada expense mata uang lain yang kursnya tidak tersedia (tidak ikut dihitung)
+	MissingRates bool `json:"missing_rates"`
+}
+
+// Report status semua budget yang berlaku di satu bulan
+type Report struct {
+	Month       string   `json:"month"` // YYYY-MM
+	Timezone    string   `json:"timezone"`
+	StartDate   string   `json:"start_date"`
+	EndDate     string   `json:"end_date"`
+	DaysInMonth int      `json:"days_in_month"`
+	DaysElapsed int      `json:"days_elapsed"` // hari yang sudah lewat, termasuk hari ini
+	Budgets     []Status `json:"budgets"`
+}
+
+// Tracker hitung Report lewat repo yang sama dengan API, jadi angkanya sama dengan laporan expense
+type Tracker struct {
+	budgets     *repository.BudgetRepo
+	allocations *repository.AllocationRepo
+	categories  *repository.CategoryRepo
+	expenses    *repository.ExpenseRepo
+	users       *repository.UserRepo
+	rates       *repository.ExchangeRateRepo
+	// fallback kalau mata uang user tidak ketemu
+	currency string
+}
+
+func NewTracker(budgets *repository.BudgetRepo, allocations *repository.AllocationRepo, categories *repository.CategoryRepo, expenses *repository.ExpenseRepo, users *repository.UserRepo, rates *repository.ExchangeRateRepo, currency string) *Tracker {
+	return &Tracker{budgets: budgets, allocations: allocations, categories: categories, expenses: expenses, users: users, rates: rates, currency: currency}
+}
+
+// Month status bulan month ("YYYY-MM" di zona waktu user, "" = bulan ini) per waktu now
+func (t *Tracker) Month(ctx context.Context, userID uuid.UUID, month string, now time.Time) (*Report, error) {
+	cal, _, err := t.prefs(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	start, err := monthStart(cal, month, now)
+	if err != nil {
+		return nil, err
+	}
+	end := cal.Next(start, calendar.Month)
+	last := end.AddDate(0, 0, -1)
+
+	report := &Report{
+		Month:       start.Format("2006-01"),
+		Timezone:    cal.Location.String(),
+		StartDate:   start.Format("2006-01-02"),
+		EndDate:     last.Format("2006-01-02"),
+		DaysInMonth: last.Day(),
+		Budgets:     []Status{},
+	}
+	switch {
+	case !now.Before(end):
+		report.DaysElapsed = report.DaysInMonth
+	case !now.Before(start):
+		report.DaysElapsed = now.In(cal.Location).Day()
+	}
+
+	budgets, err := t.budgets.ActiveIn(ctx, userID, start)
+	if err != nil {
+		return nil, err
+	}
+	if len(budgets) == 0 {
+		return report, nil
+	}
+	titles, err := t.titles(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	conv := fx.NewConverter(t.rates)
+	for _, b := range budgets {
+		s, err := t.status(ctx, conv, cal, b, start, end)
+		if err != nil {
+			return nil, err
+		}
+		if b.CategoryID != nil {
+			if title, ok := titles[*b.CategoryID]; ok {
+				s.CategoryTitle = &title
+			}
+		}
+		s.Projected = s.Spent
+		if report.DaysElapsed > 0 && report.DaysElapsed < report.DaysInMonth {
+			if s.Projected, err = s.Spent.MulDiv(int64(report.DaysInMonth), int64(report.DaysElapsed), money.Exponent(b.Currency)); err != nil {
+				return nil, err
+			}
+		}
+		report.Budgets = append(report.Budgets, s)
+	}
+	return report, nil
+}
+
+// status total expense (kategori + subkategorinya, atau semua kalau budget total) di [start, end),
+// dikonversi per hari supaya kurs sesuai tanggal transaksi
+func (t *Tracker) status(ctx context.Context, conv *fx.Converter, cal calendar.Calendar, b models.Budget, start, end time.Time) (Status, error) {
+	s := Status{BudgetID: b.ID, CategoryID: b.CategoryID, Currency: b.Currency, Limit: b.Amount}
+
+	filter := repository.ExpenseFilter{CategoryID: b.CategoryID, StartDate: &start, EndDate: &end}
+	rows, err := t.expenses.DailyTotals(ctx, b.UserID, cal.Location.String(), filter)
+	if err != nil {
+		return s, err
+	}
+	for _, row := range rows {
+		day, err := cal.ParseDate(row.Day)
+		if err != nil {
+			continue
+		}
+		converted, _, found, err := conv.Convert(ctx, row.Total, row.Currency, b.Currency, day)
+		if err != nil {
+			return s, err
+		}
+		if !found {
+			s.MissingRates = true
+			continue
+		}
+		s.Spent = s.Spent.Add(converted)
+	}
+
+	s.Remaining = s.Limit.Sub(s.Spent)
+	if s.Limit.IsPositive() {
+		s.PercentUsed = math.Round(s.Spent.Float64()/s.Limit.Float64()*10000) / 100
+	}
+	return s, nil
+}
+
+// prefs zona waktu & mata uang dasar user, UTC / t.currency kalau user atau zonanya tidak ketemu
+func (t *Tracker) prefs(ctx context.Context, userID uuid.UUID) (calendar.Calendar, string, error) {
+	p, err := t.users.GetPreferences(ctx, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return calendar.New(time.UTC, time.Monday), t.currency, nil
+	}
+	if err != nil {
+		return calendar.Calendar{}, "", err
+	}
+	loc, err := calendar.LoadLocation(p.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	return calendar.New(loc, time.Weekday(p.WeekStart)), p.Currency, nil
+}
+
+// monthStart awal bulan month ("YYYY-MM", "" = bulan now) di zona cal
+func monthStart(cal calendar.Calendar, month string, now time.Time) (time.Time, error) {
+	if month == "" {
+		return cal.Truncate(now, calendar.Month), nil
+	}
+	start, err := time.ParseInLocation("2006-01", month, cal.Location)
+	if err != nil {
+		return time.Time{}, ErrInvalidMonth
+	}
+	return start, nil
+}
+
+func (t *Tracker) titles(ctx context.Context, userID uuid.UUID) (map[uuid.UUID]string, error) {
+	categories, err := t.categories.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	titles := make(map[uuid.UUID]string, len(categories))
+	for _, c := range categories {
+		titles[c.ID] = c.Title
+	}
+	return titles, nil
+}
@@ -0,0 +1,166 @@
+// Package calendar batas hari/minggu/bulan di zona waktu & awal minggu milik user
+package calendar
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidTimezone  = errors.New("invalid timezone, use an IANA name like Asia/Jakarta")
+	ErrInvalidLocale    = errors.New("invalid locale, use a tag like id-ID or en-US")
+	ErrInvalidWeekStart = errors.New("invalid week_start, use a day name like monday")
+)
+
+// satuan bucket laporan
+const (
+	Day   = "day"
+	Week  = "week"
+	Month = "month"
+)
+
+// LoadLocation seperti time.LoadLocation tapi hanya nama IANA ("Local" & "" ditolak)
+func LoadLocation(name string) (*time.Location, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || name == "Local" {
+		return nil, ErrInvalidTimezone
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, ErrInvalidTimezone
+	}
+	return loc, nil
+}
+
+// NormalizeLocale rapikan tag bahasa: "en_us" → "en-US", "id" → "id"
+func NormalizeLocale(tag string) (string, error) {
+	tag = strings.ReplaceAll(strings.TrimSpace(tag), "_", "-")
+	lang, region, hasRegion := strings.Cut(tag, "-")
+
+	if len(lang) < 2 || len(lang) > 3 || !isLetters(lang) {
+		return "", ErrInvalidLocale
+	}
+	lang = strings.ToLower(lang)
+	if !hasRegion {
+		return lang, nil
+	}
+
+	switch {
+	case len(region) == 2 && isLetters(region):
+		region = strings.ToUpper(region)
+	case len(region) == 3 && isDigits(region): // UN M.49, mis. es-419
+	default:
+		return "", ErrInvalidLocale
+	}
+	return lang + "-" + region, nil
+}
+
+// negara yang umumnya mulai minggu di hari Minggu (sisanya Senin, ISO 8601)
+var sundayFirst = map[string]bool{
+	"US": true, "CA": true, "MX": true, "BR": true, "JP": true, "KR": true, "PH": true,
+	"IL": true, "IN": true, "TW": true, "HK": true, "SA": true, "ZA": true,
+}
+
+// DefaultWeekStart awal minggu yang lazim untuk locale
+func DefaultWeekStart(locale string) time.Weekday {
+	_, region, _ := strings.Cut(locale, "-")
+	if sundayFirst[region] {
+		return time.Sunday
+	}
+	return time.Monday
+}
+
+// ParseWeekday "monday" / "mon" / "1" (0 = Minggu)
+func ParseWeekday(s string) (time.Weekday, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if n, err := strconv.Atoi(s); err == nil {
+		if n < 0 || n > 6 {
+			return 0, ErrInvalidWeekStart
+		}
+		return time.Weekday(n), nil
+	}
+	if len(s) >= 3 {
+		for d := time.Sunday; d <= time.Saturday; d++ {
+			if strings.HasPrefix(strings.ToLower(d.String()), s) {
+				return d, nil
+			}
+		}
+	}
+	return 0, ErrInvalidWeekStart
+}
+
+// Calendar zona waktu + awal minggu satu user
+type Calendar struct {
+	Location  *time.Location
+	WeekStart time.Weekday
+}
+
+func New(loc *time.Location, weekStart time.Weekday) Calendar {
+	if loc == nil {
+		loc = time.UTC
+	}
+	return Calendar{Location: loc, WeekStart: weekStart}
+}
+
+// ParseDate "YYYY-MM-DD" sebagai awal hari itu di zona user
+func (c Calendar) ParseDate(s string) (time.Time, error) {
+	return time.ParseInLocation("2006-01-02", strings.TrimSpace(s), c.Location)
+}
+
+// StartOfDay jam 00:00 lokal (aman untuk hari DST yang tidak mulai jam 00:00)
+func (c Calendar) StartOfDay(t time.Time) time.Time {
+	y, m, d := t.In(c.Location).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, c.Location)
+}
+
+// Truncate awal bucket (hari / minggu / bulan) yang memuat t
+func (c Calendar) Truncate(t time.Time, unit string) time.Time {
+	day := c.StartOfDay(t)
+	switch unit {
+	case Week:
+		back := (int(day.Weekday()) - int(c.WeekStart) + 7) % 7
+		return day.AddDate(0, 0, -back)
+	case Month:
+		y, m, _ := day.Date()
+		return time.Date(y, m, 1, 0, 0, 0, 0, c.Location)
+	default:
+		return day
+	}
+}
+
+// Next awal bucket berikutnya; pakai AddDate supaya hari 23/25 jam (DST) tetap benar
+func (c Calendar) Next(start time.Time, unit string) time.Time {
+	switch unit {
+	case Week:
+		return start.AddDate(0, 0, 7)
+	case Month:
+		return start.AddDate(0, 1, 0)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
+// ValidUnit cek satuan bucket
+func ValidUnit(unit string) bool {
+	return unit == Day || unit == Week || unit == Month
+}
+
+func isLetters(s string) bool {
+	for _, r := range s {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
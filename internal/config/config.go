@@ -3,29 +3,245 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/rifqi535/expense-tracker-api/internal/calendar"
+	"github.com/rifqi535/expense-tracker-api/internal/money"
 )
 
+// pilihan UNVERIFIED_LOGIN: user yang emailnya belum diverifikasi boleh login atau tidak
+const (
+	UnverifiedLoginAllow      = "allow"      // login normal
+	UnverifiedLoginRestricted = "restricted" // dapat token terbatas
+	UnverifiedLoginDeny       = "deny"       // ditolak
+)
+
+// OIDCProvider konfigurasi satu provider OpenID Connect
+type OIDCProvider struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	// opsional, kosong = pakai discovery dari issuer
+	AuthURL  string
+	TokenURL string
+	JWKSURL  string
+}
+
 type Config struct {
-	Port      string
-	DB_DSN    string
-	JWTSecret string
+	Port            string
+	DB_DSN          string
+	JWTKeysDir      string
+	JWTActiveKID    string
+	JWTIssuer       string
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+
+	// dipakai buat bikin link di email (reset password, dll)
+	AppBaseURL       string
+	PasswordResetTTL time.Duration
+
+	// verifikasi email, secret juga dipakai untuk semua link bertanda tangan (ganti email, unlock, download export)
+	EmailVerificationSecret   string
+	EmailVerificationTTL      time.Duration
+	EmailVerificationCooldown time.Duration
+	UnverifiedLogin           string // allow | restricted | deny
+
+	// proteksi brute-force login
+	LoginBackoffAfter  int           // mulai backoff eksponensial setelah sekian gagal
+	LoginBackoffMax    time.Duration // batas atas jeda backoff
+	LoginLockAfter     int           // akun dikunci setelah sekian gagal (0 = nonaktif)
+	LoginLockDuration  time.Duration
+	LoginIPMaxFailures int // maksimum gagal per IP dalam LoginIPWindow (0 = nonaktif)
+	LoginIPWindow      time.Duration
+	// proxy yang boleh set X-Forwarded-For (IP / CIDR), kosong = IP client diambil dari koneksi langsung
+	TrustedProxies []string
+
+	// hapus akun: dinonaktifkan dulu selama masa tenggang, baru dihapus permanen
+	AccountDeletionGrace time.Duration
+	AccountPurgeInterval time.Duration
+
+	// mata uang default user baru (ISO 4217)
+	DefaultCurrency string
+	// zona waktu & locale default user baru
+	DefaultTimezone string
+	DefaultLocale   string
+	// kategori default user baru, file JSON per locale (kosong = set bawaan)
+	SeedDefaultCategories bool
+	DefaultCategoriesFile string
+	// file kurs (.csv / .json) yang di-import saat start, kosong = tidak ada
+	ExchangeRatesFile string
+
+	// seberapa sering scheduler expense berulang cek jadwal yang jatuh tempo
+	RecurringInterval time.Duration
+
+	// penyimpanan file lampiran: STORAGE_DRIVER=local (default) atau s3
+	StorageDriver     string
+	StorageDir        string // folder untuk driver local
+	S3Endpoint        string // mis. https://s3.ap-southeast-1.amazonaws.com atau http://localhost:9000 (MinIO)
+	S3Region          string
+	S3Bucket          string
+	S3AccessKeyID     string
+	S3SecretAccessKey string
+	S3ForcePathStyle  bool // endpoint/bucket/key, bukan bucket.endpoint/key
+
+	// lampiran expense
+	AttachmentMaxBytes      int64 // ukuran maksimal satu file
+	AttachmentQuotaBytes    int64 // total per user (0 = tanpa batas)
+	AttachmentMaxPerExpense int
+
+	// export data pribadi
+	ExportDir       string        // folder file zip
+	ExportRetention time.Duration // file dihapus setelah ini
+	ExportLinkTTL   time.Duration // umur signed URL download
+
+	// webhook notifikasi: timeout per request, alamat privat / localhost ditolak kecuali diizinkan (buat stub lokal)
+	WebhookTimeout      time.Duration
+	WebhookAllowPrivate bool
+
+	// nama issuer yang muncul di aplikasi authenticator
+	MFAIssuer string
+
+	// login lewat OIDC, OIDC_PROVIDERS=google,keycloak lalu OIDC_<NAMA>_* per provider
+	OIDCProviders []OIDCProvider
+
+	// mail: MAIL_DRIVER=smtp atau log (default)
+	MailDriver   string
+	MailFrom     string
+	MailLogFile  string
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUser     string
+	SMTPPassword string
 }
 
 func Load() *Config {
 	_ = godotenv.Load()
 
 	c := &Config{
-		Port:      getEnv("PORT", "8081"),
-		DB_DSN:    getEnv("DB_DSN", "postgres://postgres:uu@localhost:5432/expense_tracker?sslmode=disable"),
-		JWTSecret: getEnv("JWT_SECRET", "supersecretultra"),
+		Port:            getEnv("PORT", "8081"),
+		DB_DSN:          getEnv("DB_DSN", "postgres://postgres:uu@localhost:5432/expense_tracker?sslmode=disable"),
+		JWTKeysDir:      getEnv("JWT_KEYS_DIR", ""),
+		JWTActiveKID:    getEnv("JWT_ACTIVE_KID", ""),
+		AccessTokenTTL:  getDuration("ACCESS_TOKEN_TTL", 15*time.Minute),
+		RefreshTokenTTL: getDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour),
+
+		AppBaseURL:       getEnv("APP_BASE_URL", "http://localhost:8081"),
+		PasswordResetTTL: getDuration("PASSWORD_RESET_TTL", time.Hour),
+
+		EmailVerificationSecret:   getEnv("EMAIL_VERIFICATION_SECRET", ""),
+		EmailVerificationTTL:      getDuration("EMAIL_VERIFICATION_TTL", 48*time.Hour),
+		EmailVerificationCooldown: getDuration("EMAIL_VERIFICATION_COOLDOWN", time.Minute),
+		UnverifiedLogin:           getEnv("UNVERIFIED_LOGIN", UnverifiedLoginRestricted),
+
+		LoginBackoffAfter:  getInt("LOGIN_BACKOFF_AFTER", 3),
+		LoginBackoffMax:    getDuration("LOGIN_BACKOFF_MAX", 5*time.Minute),
+		LoginLockAfter:     getInt("LOGIN_LOCK_AFTER", 10),
+		LoginLockDuration:  getDuration("LOGIN_LOCK_DURATION", 30*time.Minute),
+		LoginIPMaxFailures: getInt("LOGIN_IP_MAX_FAILURES", 50),
+		LoginIPWindow:      getDuration("LOGIN_IP_WINDOW", 15*time.Minute),
+		TrustedProxies:     getList("TRUSTED_PROXIES"),
+
+		AccountDeletionGrace: getDuration("ACCOUNT_DELETION_GRACE", 30*24*time.Hour),
+		AccountPurgeInterval: getDuration("ACCOUNT_PURGE_INTERVAL", time.Hour),
+
+		DefaultCurrency:   strings.ToUpper(getEnv("DEFAULT_CURRENCY", "IDR")),
+		ExchangeRatesFile: getEnv("EXCHANGE_RATES_FILE", ""),
+
+		SeedDefaultCategories: getBool("SEED_DEFAULT_CATEGORIES", true),
+		DefaultCategoriesFile: getEnv("DEFAULT_CATEGORIES_FILE", ""),
+
+		DefaultTimezone: getEnv("DEFAULT_TIMEZONE", "Asia/Jakarta"),
+		DefaultLocale:   getEnv("DEFAULT_LOCALE", "id-ID"),
+
+		RecurringInterval: getDuration("RECURRING_INTERVAL", time.Minute),
+
+		StorageDriver:     getEnv("STORAGE_DRIVER", "local"),
+		StorageDir:        getEnv("STORAGE_DIR", "uploads"),
+		S3Endpoint:        getEnv("S3_ENDPOINT", ""),
+		S3Region:          getEnv("S3_REGION", "us-east-1"),
+		S3Bucket:          getEnv("S3_BUCKET", ""),
+		S3AccessKeyID:     getEnv("S3_ACCESS_KEY_ID", ""),
+		S3SecretAccessKey: getEnv("S3_SECRET_ACCESS_KEY", ""),
+		S3ForcePathStyle:  getBool("S3_FORCE_PATH_STYLE", false),
+
+		AttachmentMaxBytes:      int64(getInt("ATTACHMENT_MAX_BYTES", 10<<20)),
+		AttachmentQuotaBytes:    int64(getInt("ATTACHMENT_QUOTA_BYTES", 200<<20)),
+		AttachmentMaxPerExpense: getInt("ATTACHMENT_MAX_PER_EXPENSE", 10),
+
+		ExportDir:       getEnv("EXPORT_DIR", "exports"),
+		ExportRetention: getDuration("EXPORT_RETENTION", 7*24*time.Hour),
+		ExportLinkTTL:   getDuration("EXPORT_LINK_TTL", 15*time.Minute),
+
+		WebhookTimeout:      getDuration("WEBHOOK_TIMEOUT", 10*time.Second),
+		WebhookAllowPrivate: getBool("WEBHOOK_ALLOW_PRIVATE", false),
+
+		MFAIssuer: getEnv("MFA_ISSUER", "Expense Tracker"),
+
+		MailDriver:   getEnv("MAIL_DRIVER", "log"),
+		MailFrom:     getEnv("MAIL_FROM", "no-reply@expense-tracker.local"),
+		MailLogFile:  getEnv("MAIL_LOG_FILE", ""),
+		SMTPHost:     getEnv("SMTP_HOST", ""),
+		SMTPPort:     getEnv("SMTP_PORT", "587"),
+		SMTPUser:     getEnv("SMTP_USER", ""),
+		SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+	}
+
+	c.JWTIssuer = getEnv("JWT_ISSUER", c.AppBaseURL)
+
+	if c.AccountPurgeInterval <= 0 {
+		log.Printf("[WARN] ACCOUNT_PURGE_INTERVAL harus > 0, pakai default %s", time.Hour)
+		c.AccountPurgeInterval = time.Hour
 	}
 
-	if c.JWTSecret == "supersecretultra" {
-		log.Println("[WARM] JWT_SECRET menggunakan default, sebaiknya ganti ddi .env")
+	if c.RecurringInterval <= 0 {
+		log.Printf("[WARN] RECURRING_INTERVAL harus > 0, pakai default %s", time.Minute)
+		c.RecurringInterval = time.Minute
+	}
 
+	if c.AttachmentMaxBytes <= 0 {
+		log.Printf("[WARN] ATTACHMENT_MAX_BYTES harus > 0, pakai default %d", 10<<20)
+		c.AttachmentMaxBytes = 10 << 20
+	}
+	if c.AttachmentQuotaBytes < 0 {
+		log.Println("[WARN] ATTACHMENT_QUOTA_BYTES tidak boleh negatif, pakai 0 (tanpa batas)")
+		c.AttachmentQuotaBytes = 0
+	}
+	if c.AttachmentMaxPerExpense <= 0 {
+		log.Printf("[WARN] ATTACHMENT_MAX_PER_EXPENSE harus > 0, pakai default %d", 10)
+		c.AttachmentMaxPerExpense = 10
+	}
+
+	if !money.ValidCurrency(c.DefaultCurrency) {
+		log.Printf("[WARN] DEFAULT_CURRENCY tidak valid (%q), pakai %q", c.DefaultCurrency, "IDR")
+		c.DefaultCurrency = "IDR"
+	}
+
+	if _, err := calendar.LoadLocation(c.DefaultTimezone); err != nil {
+		log.Printf("[WARN] DEFAULT_TIMEZONE tidak valid (%q), pakai %q", c.DefaultTimezone, "Asia/Jakarta")
+		c.DefaultTimezone = "Asia/Jakarta"
+	}
+	if locale, err := calendar.NormalizeLocale(c.DefaultLocale); err != nil {
+		log.Printf("[WARN] DEFAULT_LOCALE tidak valid (%q), pakai %q", c.DefaultLocale, "id-ID")
+		c.DefaultLocale = "id-ID"
+	} else {
+		c.DefaultLocale = locale
 	}
+
+	switch c.UnverifiedLogin {
+	case UnverifiedLoginAllow, UnverifiedLoginRestricted, UnverifiedLoginDeny:
+	default:
+		log.Printf("[WARN] UNVERIFIED_LOGIN tidak valid (%q), pakai %q", c.UnverifiedLogin, UnverifiedLoginRestricted)
+		c.UnverifiedLogin = UnverifiedLoginRestricted
+	}
+
+	c.OIDCProviders = loadOIDCProviders(c.AppBaseURL)
+
 	return c
 
 }
@@ -36,3 +252,89 @@ func getEnv(Key, def string) string {
 	}
 	return def
 }
+
+// getDuration baca env format time.ParseDuration (contoh: "15m", "720h")
+// getList nilai dipisah koma, yang kosong dibuang
+func getList(key string) []string {
+	var list []string
+	for _, v := range strings.Split(os.Getenv(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+func getDuration(key string, def time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		log.Printf("[WARN] %s tidak valid (%q), pakai default %s", key, v, def)
+		return def
+	}
+	return d
+}
+
+// getInt baca env angka, fallback ke default kalau kosong / tidak valid
+func getInt(key string, def int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		log.Printf("[WARN] %s tidak valid (%q), pakai default %d", key, v, def)
+		return def
+	}
+	return n
+}
+
+// getBool baca env true/false (1/0, t/f juga boleh), fallback ke default kalau kosong / tidak valid
+func getBool(key string, def bool) bool {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		log.Printf("[WARN] %s tidak valid (%q), pakai default %t", key, v, def)
+		return def
+	}
+	return b
+}
+
+// loadOIDCProviders baca OIDC_PROVIDERS lalu OIDC_<NAMA>_ISSUER, _CLIENT_ID, dst
+func loadOIDCProviders(appBaseURL string) []OIDCProvider {
+	var providers []OIDCProvider
+	for _, name := range strings.Split(getEnv("OIDC_PROVIDERS", ""), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+
+		p := OIDCProvider{
+			Name:         name,
+			Issuer:       getEnv(prefix+"ISSUER", ""),
+			ClientID:     getEnv(prefix+"CLIENT_ID", ""),
+			ClientSecret: getEnv(prefix+"CLIENT_SECRET", ""),
+			RedirectURL:  getEnv(prefix+"REDIRECT_URL", appBaseURL+"/auth/oidc/"+name+"/callback"),
+			AuthURL:      getEnv(prefix+"AUTH_URL", ""),
+			TokenURL:     getEnv(prefix+"TOKEN_URL", ""),
+			JWKSURL:      getEnv(prefix+"JWKS_URL", ""),
+		}
+		if scopes := getEnv(prefix+"SCOPES", ""); scopes != "" {
+			p.Scopes = strings.Fields(strings.ReplaceAll(scopes, ",", " "))
+		}
+
+		if p.Issuer == "" || p.ClientID == "" {
+			log.Printf("[WARN] OIDC provider %q dilewati: %sISSUER dan %sCLIENT_ID wajib diisi", name, prefix, prefix)
+			continue
+		}
+		providers = append(providers, p)
+	}
+	return providers
+}
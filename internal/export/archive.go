@@ -0,0 +1,432 @@
+// Package export bikin arsip data pribadi user (zip berisi JSON + CSV + manifest)
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rifqi535/expense-tracker-api/internal/money"
+	"github.com/rifqi535/expense-tracker-api/internal/repository"
+)
+
+// FormatVersion naik kalau struktur file di dalam zip berubah
+const FormatVersion = 12
+
+// Manifest isi manifest.json di dalam zip
+type Manifest struct {
+	FormatVersion int       `json:"format_version"`
+	GeneratedAt   time.Time `json:"generated_at"`
+	UserID        uuid.UUID `json:"user_id"`
+	Files         []File    `json:"files"`
+}
+
+// File satu file di dalam zip
+type File struct {
+	Name    string `json:"name"`
+	Records int    `json:"records"`
+	Bytes   int    `json:"bytes"`
+	SHA256  string `json:"sha256"`
+}
+
+// dataset satu jenis data, ditulis sebagai <name>.json dan (kalau ada header) <name>.csv
+type dataset struct {
+	name    string
+	records interface{}
+	count   int
+	header  []string
+	rows    [][]string
+}
+
+// Builder ambil data lewat repo yang sama dengan API, jadi isinya konsisten dengan yang user lihat
+type Builder struct {
+	Users         *repository.UserRepo
+	Categories    *repository.CategoryRepo
+	Expenses      *repository.ExpenseRepo
+	Recurring     *repository.RecurringExpenseRepo
+	Attachments   *repository.AttachmentRepo
+	Budgets       *repository.BudgetRepo
+	Allocations   *repository.AllocationRepo
+	Notifications *repository.NotificationRepo
+}
+
+func NewBuilder(users *repository.UserRepo, categories *repository.CategoryRepo, expenses *repository.ExpenseRepo, recurring *repository.RecurringExpenseRepo, attachments *repository.AttachmentRepo, budgets *repository.BudgetRepo, allocations *repository.AllocationRepo, notifications *repository.NotificationRepo) *Builder {
+	return &Builder{Users: users, Categories: categories, Expenses: expenses, Recurring: recurring, Attachments: attachments, Budgets: budgets, Allocations: allocations, Notifications: notifications}
+}
+
+// Write tulis zip export milik userID ke w
+func (b *Builder) Write(ctx context.Context, userID uuid.UUID, w io.Writer) (*Manifest, error) {
+	sets, err := b.collect(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &Manifest{
+		FormatVersion: FormatVersion,
+		GeneratedAt:   time.Now().UTC(),
+		UserID:        userID,
+	}
+
+	zw := zip.NewWriter(w)
+	for _, s := range sets {
+		body, err := json.MarshalIndent(s.records, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		f, err := writeFile(zw, s.name+".json", body, s.count)
+		if err != nil {
+			return nil, err
+		}
+		manifest.Files = append(manifest.Files, f)
+
+		if s.header == nil {
+			continue
+		}
+		var buf bytes.Buffer
+		cw := csv.NewWriter(&buf)
+		if err := cw.Write(s.header); err != nil {
+			return nil, err
+		}
+		if err := cw.WriteAll(s.rows); err != nil {
+			return nil, err
+		}
+		f, err = writeFile(zw, s.name+".csv", buf.Bytes(), s.count)
+		if err != nil {
+			return nil, err
+		}
+		manifest.Files = append(manifest.Files, f)
+	}
+
+	// manifest terakhir supaya checksum semua file sudah ada
+	body, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := writeFile(zw, "manifest.json", body, len(manifest.Files)); err != nil {
+		return nil, err
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+func (b *Builder) collect(ctx context.Context, userID uuid.UUID) ([]dataset, error) {
+	user, err := b.Users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	categories, err := b.Categories.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	// expense yang sudah dihapus (soft delete) juga data milik user
+	expenses, err := b.Expenses.ListAll(ctx, userID, true)
+	if err != nil {
+		return nil, err
+	}
+	recurring, err := b.Recurring.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	attachments, err := b.Attachments.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	budgets, err := b.Budgets.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	allocations, err := b.Allocations.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	notifications, err := b.Notifications.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	profile := profileRecord{
+		ID:              user.ID,
+		Name:            user.Name,
+		Email:           user.Email,
+		Role:            user.Role,
+		Currency:        user.Currency,
+		Timezone:        user.Timezone,
+		Locale:          user.Locale,
+		EmailVerifiedAt: user.EmailVerifiedAt,
+		MFAEnabled:      user.TOTPEnabledAt != nil,
+		CreatedAt:       user.CreatedAt,
+		UpdatedAt:       user.UpdatedAt,
+	}
+
+	categoryTitles := make(map[uuid.UUID]string, len(categories))
+	categoryRecords := make([]categoryRecord, 0, len(categories))
+	categoryRows := make([][]string, 0, len(categories))
+	for _, c := range categories {
+		categoryTitles[c.ID] = c.Title
+		categoryRecords = append(categoryRecords, categoryRecord{
+			ID:         c.ID,
+			Title:      c.Title,
+			ParentID:   c.ParentID,
+			Color:      c.Color,
+			Icon:       c.Icon,
+			SortOrder:  c.SortOrder,
+			ArchivedAt: c.ArchivedAt,
+			Rollover:   c.Rollover,
+			CreatedAt:  c.CreatedAt,
+			UpdatedAt:  c.UpdatedAt,
+		})
+		parentID, color, icon := "", "", ""
+		if c.ParentID != nil {
+			parentID = c.ParentID.String()
+		}
+		if c.Color != nil {
+			color = *c.Color
+		}
+		if c.Icon != nil {
+			icon = *c.Icon
+		}
+		categoryRows = append(categoryRows, []string{
+			c.ID.String(),
+			c.Title,
+			parentID,
+			color,
+			icon,
+			strconv.Itoa(c.SortOrder),
+			formatTime(c.ArchivedAt),
+			c.Rollover,
+			formatTime(&c.CreatedAt),
+			formatTime(&c.UpdatedAt),
+		})
+	}
+
+	expenseRecords := make([]expenseRecord, 0, len(expenses))
+	expenseRows := make([][]string, 0, len(expenses))
+	for _, e := range expenses {
+		rec := expenseRecord{
+			ID:            e.ID,
+			Title:         e.Title,
+			Description:   e.Description,
+			Amount:        e.Amount,
+			Currency:      e.Currency,
+			SpentAt:       e.SpentAt.UTC(),
+			SpentTZ:       e.SpentTZ,
+			RecurringID:   e.RecurringID,
+			CategoryID:    e.CategoryID,
+			CategoryTitle: categoryTitles[e.CategoryID],
+			Tags:          e.Tags,
+			CreatedAt:     e.CreatedAt,
+			UpdatedAt:     e.UpdatedAt,
+		}
+		if e.DeletedAt.Valid {
+			rec.DeletedAt = &e.DeletedAt.Time
+		}
+		expenseRecords = append(expenseRecords, rec)
+
+		description := ""
+		if e.Description != nil {
+			description = *e.Description
+		}
+		spentTZ := ""
+		if e.SpentTZ != nil {
+			spentTZ = *e.SpentTZ
+		}
+		expenseRows = append(expenseRows, []string{
+			e.ID.String(),
+			e.Title,
+			description,
+			e.Amount.String(),
+			e.Currency,
+			formatTime(&e.SpentAt),
+			spentTZ,
+			e.CategoryID.String(),
+			rec.CategoryTitle,
+			strings.Join(e.Tags, ","),
+			formatTime(&e.CreatedAt),
+			formatTime(&e.UpdatedAt),
+			formatTime(rec.DeletedAt),
+		})
+	}
+
+	budgetRecords := make([]budgetRecord, 0, len(budgets))
+	budgetRows := make([][]string, 0, len(budgets))
+	for _, bg := range budgets {
+		rec := budgetRecord{
+			ID:         bg.ID,
+			CategoryID: bg.CategoryID,
+			Amount:     bg.Amount,
+			Currency:   bg.Currency,
+			StartMonth: bg.StartMonth.Format("2006-01"),
+			CreatedAt:  bg.CreatedAt,
+			UpdatedAt:  bg.UpdatedAt,
+		}
+		categoryID, endMonth := "", ""
+		if bg.CategoryID != nil {
+			categoryID = bg.CategoryID.String()
+			rec.CategoryTitle = categoryTitles[*bg.CategoryID]
+		}
+		if bg.EndMonth != nil {
+			endMonth = bg.EndMonth.Format("2006-01")
+			rec.EndMonth = &endMonth
+		}
+		budgetRecords = append(budgetRecords, rec)
+		budgetRows = append(budgetRows, []string{
+			bg.ID.String(),
+			categoryID,
+			rec.CategoryTitle,
+			bg.Amount.String(),
+			bg.Currency,
+			rec.StartMonth,
+			endMonth,
+			formatTime(&bg.CreatedAt),
+			formatTime(&bg.UpdatedAt),
+		})
+	}
+
+	allocationRecords := make([]allocationRecord, 0, len(allocations))
+	for _, a := range allocations {
+		allocationRecords = append(allocationRecords, allocationRecord{
+			ID:            a.ID,
+			CategoryID:    a.CategoryID,
+			CategoryTitle: categoryTitles[a.CategoryID],
+			Month:         a.Month.Format("2006-01"),
+			Amount:        a.Amount,
+			Currency:      a.Currency,
+			TransferID:    a.TransferID,
+			Note:          a.Note,
+			CreatedAt:     a.CreatedAt,
+		})
+	}
+
+	return []dataset{
+		{name: "profile", records: profile, count: 1},
+		{
+			name:    "categories",
+			records: categoryRecords,
+			count:   len(categoryRecords),
+			header:  []string{"id", "title", "parent_id", "color", "icon", "sort_order", "archived_at", "rollover", "created_at", "updated_at"},
+			rows:    categoryRows,
+		},
+		{
+			name:    "expenses",
+			records: expenseRecords,
+			count:   len(expenseRecords),
+			header:  []string{"id", "title", "description", "amount", "currency", "spent_at", "spent_tz", "category_id", "category_title", "tags", "created_at", "updated_at", "deleted_at"},
+			rows:    expenseRows,
+		},
+		// jadwal berulang cuma JSON, strukturnya tidak cocok untuk CSV
+		{name: "recurring_expenses", records: recurring, count: len(recurring)},
+		// metadata lampiran saja, file-nya diunduh lewat API
+		{name: "attachments", records: attachments, count: len(attachments)},
+		{
+			name:    "budgets",
+			records: budgetRecords,
+			count:   len(budgetRecords),
+			header:  []string{"id", "category_id", "category_title", "amount", "currency", "start_month", "end_month", "created_at", "updated_at"},
+			rows:    budgetRows,
+		},
+		// ledger alokasi envelope cuma JSON, sama seperti jadwal berulang
+		{name: "budget_allocations", records: allocationRecords, count: len(allocationRecords)},
+		// inbox notifikasi, data tiap jenis beda-beda jadi cuma JSON
+		{name: "notifications", records: notifications, count: len(notifications)},
+	}, nil
+}
+
+func writeFile(zw *zip.Writer, name string, body []byte, records int) (File, error) {
+	w, err := zw.Create(name)
+	if err != nil {
+		return File{}, err
+	}
+	if _, err := w.Write(body); err != nil {
+		return File{}, err
+	}
+
+	sum := sha256.Sum256(body)
+	return File{Name: name, Records: records, Bytes: len(body), SHA256: hex.EncodeToString(sum[:])}, nil
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+type profileRecord struct {
+	ID              uuid.UUID  `json:"id"`
+	Name            string     `json:"name"`
+	Email           string     `json:"email"`
+	Role            string     `json:"role"`
+	Currency        string     `json:"currency"`
+	Timezone        string     `json:"timezone"`
+	Locale          string     `json:"locale"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	MFAEnabled      bool       `json:"mfa_enabled"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+type categoryRecord struct {
+	ID         uuid.UUID  `json:"id"`
+	Title      string     `json:"title"`
+	ParentID   *uuid.UUID `json:"parent_id"`
+	Color      *string    `json:"color"`
+	Icon       *string    `json:"icon"`
+	SortOrder  int        `json:"sort_order"`
+	ArchivedAt *time.Time `json:"archived_at"`
+	Rollover   string     `json:"rollover"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+type expenseRecord struct {
+	ID            uuid.UUID   `json:"id"`
+	Title         string      `json:"title"`
+	Description   *string     `json:"description"`
+	Amount        money.Money `json:"amount"`
+	Currency      string      `json:"currency"`
+	SpentAt       time.Time   `json:"spent_at"`
+	SpentTZ       *string     `json:"spent_tz"`
+	RecurringID   *uuid.UUID  `json:"recurring_id"`
+	CategoryID    uuid.UUID   `json:"category_id"`
+	CategoryTitle string      `json:"category_title"`
+	Tags          []string    `json:"tags"`
+	CreatedAt     time.Time   `json:"created_at"`
+	UpdatedAt     time.Time   `json:"updated_at"`
+	DeletedAt     *time.Time  `json:"deleted_at"`
+}
+
+type budgetRecord struct {
+	ID            uuid.UUID   `json:"id"`
+	CategoryID    *uuid.UUID  `json:"category_id"` // null = budget total
+	CategoryTitle string      `json:"category_title"`
+	Amount        money.Money `json:"amount"`
+	Currency      string      `json:"currency"`
+	StartMonth    string      `json:"start_month"`
+	EndMonth      *string     `json:"end_month"`
+	CreatedAt     time.Time   `json:"created_at"`
+	UpdatedAt     time.Time   `json:"updated_at"`
+}
+
+type allocationRecord struct {
+	ID            uuid.UUID   `json:"id"`
+	CategoryID    uuid.UUID   `json:"category_id"`
+	CategoryTitle string      `json:"category_title"`
+	Month         string      `json:"month"`
+	Amount        money.Money `json:"amount"`
+	Currency      string      `json:"currency"`
+	TransferID    *uuid.UUID  `json:"transfer_id"`
+	Note          *string     `json:"note"`
+	CreatedAt     time.Time   `json:"created_at"`
+}
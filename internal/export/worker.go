@@ -0,0 +1,158 @@
+package export
+
+import (
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/rifqi535/expense-tracker-api/internal/models"
+	"github.com/rifqi535/expense-tracker-api/internal/repository"
+)
+
+const (
+	pollInterval = 30 * time.Second
+	// job running lebih lama dari ini dianggap worker-nya mati
+	staleAfter = 15 * time.Minute
+)
+
+// Worker proses antrian data_exports di background & hapus file yang sudah expired
+type Worker struct {
+	repo      *repository.DataExportRepo
+	builder   *Builder
+	dir       string
+	retention time.Duration
+	wake      chan struct{}
+}
+
+func NewWorker(repo *repository.DataExportRepo, builder *Builder, dir string, retention time.Duration) *Worker {
+	return &Worker{
+		repo:      repo,
+		builder:   builder,
+		dir:       dir,
+		retention: retention,
+		wake:      make(chan struct{}, 1),
+	}
+}
+
+// Notify bangunkan worker setelah ada job baru (tidak nge-block)
+func (w *Worker) Notify() {
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run loop utama, berhenti kalau ctx selesai
+func (w *Worker) Run(ctx context.Context) {
+	if err := os.MkdirAll(w.dir, 0o700); err != nil {
+		log.Println("❌ gagal bikin folder export:", err)
+	}
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.repo.RequeueStale(ctx, staleAfter); err != nil {
+			log.Println("❌ gagal requeue export:", err)
+		}
+		w.drain(ctx)
+		w.cleanup(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-w.wake:
+		}
+	}
+}
+
+// drain kerjakan semua job pending yang ada
+func (w *Worker) drain(ctx context.Context) {
+	for ctx.Err() == nil {
+		job, err := w.repo.ClaimNext(ctx)
+		if err != nil {
+			log.Println("❌ gagal ambil job export:", err)
+			return
+		}
+		if job == nil {
+			return
+		}
+
+		path, size, err := w.build(ctx, job)
+		if err != nil {
+			log.Printf("❌ export %s gagal: %v", job.ID, err)
+			if err := w.repo.MarkFailed(ctx, job.ID, "export failed, please try again"); err != nil {
+				log.Println("❌ gagal update status export:", err)
+			}
+			continue
+		}
+
+		if err := w.repo.MarkReady(ctx, job.ID, path, size, time.Now().Add(w.retention)); err != nil {
+			log.Println("❌ gagal update status export:", err)
+			_ = os.Remove(path)
+		}
+	}
+}
+
+// build tulis zip ke file sementara lalu rename, jadi file yang ada di disk selalu utuh
+func (w *Worker) build(ctx context.Context, job *models.DataExport) (string, int64, error) {
+	path := filepath.Join(w.dir, job.ID.String()+".zip")
+
+	tmp, err := os.CreateTemp(w.dir, job.ID.String()+"-*.tmp")
+	if err != nil {
+		return "", 0, err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := w.builder.Write(ctx, job.UserID, tmp); err != nil {
+		tmp.Close()
+		return "", 0, err
+	}
+	info, err := tmp.Stat()
+	if err != nil {
+		tmp.Close()
+		return "", 0, err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", 0, err
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", 0, err
+	}
+	return path, info.Size(), nil
+}
+
+// cleanup hapus file export yang sudah lewat masa simpan (termasuk file yatim milik user yang sudah dihapus)
+func (w *Worker) cleanup(ctx context.Context) {
+	expired, err := w.repo.ListExpired(ctx)
+	if err != nil {
+		log.Println("❌ gagal cek export expired:", err)
+		return
+	}
+	for _, e := range expired {
+		if err := os.Remove(e.FilePath); err != nil && !os.IsNotExist(err) {
+			log.Println("❌ gagal hapus file export:", err)
+			continue
+		}
+		if err := w.repo.MarkExpired(ctx, e.ID); err != nil {
+			log.Println("❌ gagal update status export:", err)
+		}
+	}
+
+	entries, err := os.ReadDir(w.dir)
+	if err != nil {
+		return
+	}
+	cutoff := time.Now().Add(-w.retention)
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || entry.IsDir() || info.ModTime().After(cutoff) {
+			continue
+		}
+		_ = os.Remove(filepath.Join(w.dir, entry.Name()))
+	}
+}
@@ -0,0 +1,96 @@
+// Package fx konversi mata uang berdasarkan tabel exchange_rates
+package fx
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/rifqi535/expense-tracker-api/internal/money"
+	"github.com/rifqi535/expense-tracker-api/internal/repository"
+)
+
+// Converter cari kurs pada tanggal tertentu (langsung, kebalikan, atau lewat mata uang perantara).
+// Hasil di-cache, jadi buat satu Converter per request; tidak aman dipakai bareng antar goroutine.
+type Converter struct {
+	rates *repository.ExchangeRateRepo
+	cache map[string]money.Rate
+}
+
+func NewConverter(rates *repository.ExchangeRateRepo) *Converter {
+	return &Converter{rates: rates, cache: map[string]money.Rate{}}
+}
+
+// Rate kurs from → to yang berlaku pada tanggal on. ok=false kalau tidak ada data kurs.
+func (c *Converter) Rate(ctx context.Context, from, to string, on time.Time) (money.Rate, bool, error) {
+	if from == to {
+		return money.OneRate(), true, nil
+	}
+
+	key := from + to + on.Format("2006-01-02")
+	if r, ok := c.cache[key]; ok {
+		return r, !r.IsZero(), nil
+	}
+
+	r, err := c.lookup(ctx, from, to, on)
+	if err != nil {
+		return money.Rate{}, false, err
+	}
+	c.cache[key] = r
+	return r, !r.IsZero(), nil
+}
+
+// Convert amount from → to, dibulatkan ke digit desimal mata uang tujuan
+func (c *Converter) Convert(ctx context.Context, amount money.Money, from, to string, on time.Time) (money.Money, money.Rate, bool, error) {
+	r, ok, err := c.Rate(ctx, from, to, on)
+	if err != nil || !ok {
+		return money.Money{}, r, ok, err
+	}
+	converted, err := amount.Convert(r, money.Exponent(to))
+	if err != nil {
+		return money.Money{}, r, true, fmt.Errorf("convert %s %s to %s: %w", amount, from, to, err)
+	}
+	return converted, r, true, nil
+}
+
+func (c *Converter) lookup(ctx context.Context, from, to string, on time.Time) (money.Rate, error) {
+	rates, err := c.rates.LatestFor(ctx, []string{from, to}, on)
+	if err != nil {
+		return money.Rate{}, err
+	}
+
+	// kurs dari from & to ke mata uang lain (arah disamakan: X → P)
+	fromEdges := map[string]money.Rate{}
+	toEdges := map[string]money.Rate{}
+	for _, r := range rates {
+		switch {
+		case r.Base == from && r.Quote == to:
+			return r.Rate, nil
+		case r.Base == to && r.Quote == from:
+			return r.Rate.Inverse(), nil
+		case r.Base == from:
+			fromEdges[r.Quote] = r.Rate
+		case r.Quote == from:
+			fromEdges[r.Base] = r.Rate.Inverse()
+		case r.Base == to:
+			toEdges[r.Quote] = r.Rate
+		case r.Quote == to:
+			toEdges[r.Base] = r.Rate.Inverse()
+		}
+	}
+
+	// lewat perantara: from → P → to, urutan pivot dibuat tetap supaya hasilnya konsisten
+	pivots := make([]string, 0, len(fromEdges))
+	for p := range fromEdges {
+		if _, ok := toEdges[p]; ok {
+			pivots = append(pivots, p)
+		}
+	}
+	if len(pivots) == 0 {
+		return money.Rate{}, nil
+	}
+	sort.Strings(pivots)
+	p := pivots[0]
+	return fromEdges[p].Mul(toEdges[p].Inverse()), nil
+}
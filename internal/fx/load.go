@@ -0,0 +1,138 @@
+package fx
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rifqi535/expense-tracker-api/internal/models"
+	"github.com/rifqi535/expense-tracker-api/internal/money"
+)
+
+// RateInput satu baris kurs dari file / request admin
+type RateInput struct {
+	Date  string     `json:"date"` // YYYY-MM-DD
+	Base  string     `json:"base"`
+	Quote string     `json:"quote"`
+	Rate  money.Rate `json:"rate"`
+}
+
+// ToModel validasi input lalu ubah jadi models.ExchangeRate
+func (in RateInput) ToModel(source string) (models.ExchangeRate, error) {
+	base := money.NormalizeCurrency(in.Base)
+	quote := money.NormalizeCurrency(in.Quote)
+	if !money.ValidCurrency(base) || !money.ValidCurrency(quote) {
+		return models.ExchangeRate{}, fmt.Errorf("invalid currency pair %q/%q", in.Base, in.Quote)
+	}
+	if base == quote {
+		return models.ExchangeRate{}, fmt.Errorf("base and quote must differ (%s)", base)
+	}
+	if in.Rate.IsZero() {
+		return models.ExchangeRate{}, fmt.Errorf("rate is required for %s/%s", base, quote)
+	}
+	date, err := time.Parse("2006-01-02", strings.TrimSpace(in.Date))
+	if err != nil {
+		return models.ExchangeRate{}, fmt.Errorf("invalid date %q, use YYYY-MM-DD", in.Date)
+	}
+
+	return models.ExchangeRate{Base: base, Quote: quote, Rate: in.Rate, RateDate: date, Source: source}, nil
+}
+
+// ParseJSON baca array [{"date","base","quote","rate"}]
+func ParseJSON(r io.Reader, source string) ([]models.ExchangeRate, error) {
+	var inputs []RateInput
+	if err := json.NewDecoder(r).Decode(&inputs); err != nil {
+		return nil, fmt.Errorf("invalid json: %w", err)
+	}
+	return toModels(inputs, source)
+}
+
+// ParseCSV baca CSV dengan header date,base,quote,rate (urutan kolom bebas)
+func ParseCSV(r io.Reader, source string) ([]models.ExchangeRate, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("invalid csv: %w", err)
+	}
+	idx := map[string]int{}
+	for i, h := range header {
+		idx[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	for _, col := range []string{"date", "base", "quote", "rate"} {
+		if _, ok := idx[col]; !ok {
+			return nil, fmt.Errorf("csv is missing column %q", col)
+		}
+	}
+
+	var inputs []RateInput
+	for line := 2; ; line++ {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid csv: %w", err)
+		}
+
+		rate, err := money.ParseRate(rec[idx["rate"]])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		inputs = append(inputs, RateInput{
+			Date:  rec[idx["date"]],
+			Base:  rec[idx["base"]],
+			Quote: rec[idx["quote"]],
+			Rate:  rate,
+		})
+	}
+	return toModels(inputs, source)
+}
+
+// LoadFile baca file .csv atau .json
+func LoadFile(path string) ([]models.ExchangeRate, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	source := "file:" + filepath.Base(path)
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return ParseCSV(f, source)
+	case ".json":
+		return ParseJSON(f, source)
+	default:
+		return nil, fmt.Errorf("unsupported exchange rate file %q (use .csv or .json)", path)
+	}
+}
+
+// toModels validasi semua input; pasangan + tanggal yang dobel pakai baris terakhir
+// (satu INSERT ... ON CONFLICT tidak boleh mengenai baris yang sama dua kali)
+func toModels(inputs []RateInput, source string) ([]models.ExchangeRate, error) {
+	rates := make([]models.ExchangeRate, 0, len(inputs))
+	seen := make(map[string]int, len(inputs))
+	for i, in := range inputs {
+		m, err := in.ToModel(source)
+		if err != nil {
+			return nil, fmt.Errorf("rate #%d: %w", i+1, err)
+		}
+
+		key := m.Base + m.Quote + m.RateDate.Format("2006-01-02")
+		if j, ok := seen[key]; ok {
+			rates[j] = m
+			continue
+		}
+		seen[key] = len(rates)
+		rates = append(rates, m)
+	}
+	return rates, nil
+}
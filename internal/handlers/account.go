@@ -0,0 +1,391 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rifqi535/expense-tracker-api/internal/calendar"
+	"github.com/rifqi535/expense-tracker-api/internal/mailer"
+	"github.com/rifqi535/expense-tracker-api/internal/models"
+	"github.com/rifqi535/expense-tracker-api/internal/money"
+	"github.com/rifqi535/expense-tracker-api/internal/repository"
+	"gorm.io/gorm"
+)
+
+const (
+	purposeChangeEmail    = "change_email"
+	purposeRestoreAccount = "restore_account"
+)
+
+// 📌 UpdateProfile: ubah data profil (field yang tidak dikirim tidak berubah)
+func (h *AuthHandler) UpdateProfile(c *gin.Context) {
+	userIDVal, _ := c.Get("user_id")
+	uid, ok := userIDVal.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	var req struct {
+		Name      *string `json:"name"`
+		Currency  *string `json:"currency"` // mata uang dasar untuk laporan & konversi
+		Timezone  *string `json:"timezone"` // zona IANA, batas hari untuk filter & laporan
+		Locale    *string `json:"locale"`
+		WeekStart *string `json:"week_start"` // "monday", "sunday", ...
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+	if req.Name == nil && req.Currency == nil && req.Timezone == nil && req.Locale == nil && req.WeekStart == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "nothing to update"})
+		return
+	}
+
+	var update repository.ProfileUpdate
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "name is required"})
+			return
+		}
+		update.Name = &name
+	}
+	if req.Currency != nil {
+		currency := money.NormalizeCurrency(*req.Currency)
+		if !money.ValidCurrency(currency) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid currency"})
+			return
+		}
+		update.Currency = &currency
+	}
+	if req.Timezone != nil {
+		if _, err := calendar.LoadLocation(*req.Timezone); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		timezone := strings.TrimSpace(*req.Timezone)
+		update.Timezone = &timezone
+	}
+	if req.Locale != nil {
+		locale, err := calendar.NormalizeLocale(*req.Locale)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		update.Locale = &locale
+	}
+	if req.WeekStart != nil {
+		day, err := calendar.ParseWeekday(*req.WeekStart)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		weekStart := int(day)
+		update.WeekStart = &weekStart
+	}
+
+	okRepo, err := h.Users.UpdateProfile(c.Request.Context(), uid, update)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !okRepo {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Profile updated"})
+}
+
+// 📌 ChangePassword: ganti password (wajib password lama), sesi lain di-logout
+func (h *AuthHandler) ChangePassword(c *gin.Context) {
+	userIDVal, _ := c.Get("user_id")
+	uid, ok := userIDVal.(uuid.UUID)
+	sessionVal, _ := c.Get("session_id")
+	sid, okSession := sessionVal.(uuid.UUID)
+	if !ok || !okSession {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	var req struct {
+		CurrentPassword string `json:"current_password"`
+		NewPassword     string `json:"new_password"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.CurrentPassword == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "current_password and new_password are required"})
+		return
+	}
+	if len(req.NewPassword) < minPasswordLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("password must be at least %d characters", minPasswordLength)})
+		return
+	}
+
+	if _, ok := h.checkCurrentPassword(c, uid, req.CurrentPassword); !ok {
+		return
+	}
+
+	hashed, err := hashPassword(req.NewPassword)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to hash password"})
+		return
+	}
+
+	if _, err := h.Users.ChangePassword(c.Request.Context(), uid, hashed, sid); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password changed, other sessions have been logged out"})
+}
+
+// 📌 RequestEmailChange: email baru baru dipakai setelah dikonfirmasi dari link yang dikirim ke alamat baru
+func (h *AuthHandler) RequestEmailChange(c *gin.Context) {
+	userIDVal, _ := c.Get("user_id")
+	uid, ok := userIDVal.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	var req struct {
+		Email           string `json:"email"`
+		CurrentPassword string `json:"current_password"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.Email == "" || req.CurrentPassword == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "email and current_password are required"})
+		return
+	}
+	email := strings.TrimSpace(req.Email)
+	if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid email"})
+		return
+	}
+
+	user, ok := h.checkCurrentPassword(c, uid, req.CurrentPassword)
+	if !ok {
+		return
+	}
+	if strings.EqualFold(user.Email, email) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "new email is the same as the current one"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if _, err := h.Users.GetByEmail(ctx, email); err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": repository.ErrEmailTaken.Error()})
+		return
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if _, err := h.Users.SetPendingEmail(ctx, uid, email); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.sendEmailChangeConfirmation(ctx, user, email); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to send email"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Confirmation link sent to the new email address"})
+}
+
+// 📌 ConfirmEmailChange: dipanggil dari link di email baru (GET ?token=...) atau dari client (POST {"token": ...})
+func (h *AuthHandler) ConfirmEmailChange(c *gin.Context) {
+	p, uid, ok := h.signedLink(c, purposeChangeEmail)
+	if !ok {
+		return
+	}
+
+	okRepo, err := h.Users.ConfirmEmailChange(c.Request.Context(), uid, p.Email)
+	if err != nil {
+		if errors.Is(err, repository.ErrEmailTaken) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !okRepo {
+		// sudah dikonfirmasi, atau ada permintaan ganti email yang lebih baru
+		c.JSON(http.StatusBadRequest, gin.H{"error": errInvalidSignedToken.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "email changed"})
+}
+
+// 📌 DeleteAccount: akun dinonaktifkan dulu, dihapus permanen setelah masa tenggang
+func (h *AuthHandler) DeleteAccount(c *gin.Context) {
+	userIDVal, _ := c.Get("user_id")
+	uid, ok := userIDVal.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	var req struct {
+		CurrentPassword string `json:"current_password"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.CurrentPassword == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "current_password is required"})
+		return
+	}
+
+	user, ok := h.checkCurrentPassword(c, uid, req.CurrentPassword)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	if _, err := h.Users.RequestDeletion(ctx, uid); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	purgeAt := time.Now().Add(h.Cfg.AccountDeletionGrace)
+	if err := h.sendDeletionNotice(ctx, user, purgeAt); err != nil {
+		log.Println("❌ gagal kirim email hapus akun:", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Account scheduled for deletion",
+		"purge_at": purgeAt,
+	})
+}
+
+// 📌 RestoreAccount: batalkan hapus akun dari link di email selama masa tenggang
+func (h *AuthHandler) RestoreAccount(c *gin.Context) {
+	_, uid, ok := h.signedLink(c, purposeRestoreAccount)
+	if !ok {
+		return
+	}
+
+	okRepo, err := h.Users.CancelDeletion(c.Request.Context(), uid, h.Cfg.AccountDeletionGrace)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !okRepo {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errInvalidSignedToken.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "account restored, you can login again"})
+}
+
+// checkCurrentPassword ambil user & cocokkan password-nya. Kalau gagal, response sudah ditulis.
+func (h *AuthHandler) checkCurrentPassword(c *gin.Context, uid uuid.UUID, password string) (*models.User, bool) {
+	user, err := h.Users.GetByID(c.Request.Context(), uid)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	if !checkPasswordHash(password, user.PasswordHash) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "current password is incorrect"})
+		return nil, false
+	}
+	return user, true
+}
+
+// signedLink baca token dari query / body lalu verifikasi. Kalau gagal, response sudah ditulis.
+func (h *AuthHandler) signedLink(c *gin.Context, purpose string) (*signedPayload, uuid.UUID, bool) {
+	token := c.Query("token")
+	if token == "" {
+		var req struct {
+			Token string `json:"token"`
+		}
+		_ = c.ShouldBindJSON(&req)
+		token = req.Token
+	}
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "token is required"})
+		return nil, uuid.Nil, false
+	}
+
+	p, err := verifyPayload([]byte(h.Cfg.EmailVerificationSecret), token, purpose)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, uuid.Nil, false
+	}
+	uid, err := uuid.Parse(p.UserID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errInvalidSignedToken.Error()})
+		return nil, uuid.Nil, false
+	}
+	return p, uid, true
+}
+
+// sendEmailChangeConfirmation kirim link konfirmasi ke email baru + pemberitahuan ke email lama
+func (h *AuthHandler) sendEmailChangeConfirmation(ctx context.Context, user *models.User, newEmail string) error {
+	token, err := signPayload([]byte(h.Cfg.EmailVerificationSecret), signedPayload{
+		Purpose: purposeChangeEmail,
+		UserID:  user.ID.String(),
+		Email:   newEmail,
+		Exp:     time.Now().Add(h.Cfg.EmailVerificationTTL).Unix(),
+	})
+	if err != nil {
+		return err
+	}
+
+	link := h.Cfg.AppBaseURL + "/auth/email/confirm?token=" + url.QueryEscape(token)
+	if err := h.Mailer.Send(ctx, mailer.Message{
+		To:      newEmail,
+		Subject: "Confirm your new email address",
+		Body: fmt.Sprintf("Please confirm this address for your Expense Tracker account by opening this link within %s:\n%s\n",
+			h.Cfg.EmailVerificationTTL, link),
+	}); err != nil {
+		return err
+	}
+
+	// pemberitahuan ke alamat lama cukup di-log kalau gagal
+	if err := h.Mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Your email address is being changed",
+		Body: fmt.Sprintf("Someone requested to change the email of your Expense Tracker account to %s.\n\n"+
+			"If it wasn't you, change your password right away.", newEmail),
+	}); err != nil {
+		log.Println("❌ gagal kirim pemberitahuan ganti email:", err)
+	}
+	return nil
+}
+
+// sendDeletionNotice kirim email berisi link untuk membatalkan hapus akun
+func (h *AuthHandler) sendDeletionNotice(ctx context.Context, user *models.User, purgeAt time.Time) error {
+	token, err := signPayload([]byte(h.Cfg.EmailVerificationSecret), signedPayload{
+		Purpose: purposeRestoreAccount,
+		UserID:  user.ID.String(),
+		Email:   user.Email,
+		Exp:     purgeAt.Unix(),
+	})
+	if err != nil {
+		return err
+	}
+
+	link := h.Cfg.AppBaseURL + "/auth/account/restore?token=" + url.QueryEscape(token)
+	return h.Mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Your account will be deleted",
+		Body: fmt.Sprintf("Your Expense Tracker account has been deactivated and will be permanently deleted on %s, "+
+			"together with all of its categories and expenses.\n\n"+
+			"Changed your mind? Restore your account before then:\n%s\n", purgeAt.Format(time.RFC1123), link),
+	})
+}
@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rifqi535/expense-tracker-api/internal/models"
+	"github.com/rifqi535/expense-tracker-api/internal/repository"
+	"gorm.io/gorm"
+)
+
+type AdminHandler struct {
+	Users *repository.UserRepo
+	Repo  *repository.AdminRepo
+	// dipakai buat kirim email reset password
+	Auth *AuthHandler
+}
+
+func NewAdminHandler(users *repository.UserRepo, repo *repository.AdminRepo, auth *AuthHandler) *AdminHandler {
+	return &AdminHandler{Users: users, Repo: repo, Auth: auth}
+}
+
+// ListUsers: list & search user (?q=, ?role=, ?status=active|disabled)
+func (h *AdminHandler) ListUsers(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	offset := (page - 1) * limit
+
+	users, total, err := h.Users.Search(c, strings.TrimSpace(c.Query("q")), c.Query("role"), c.Query("status"), limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"page":  page,
+		"limit": limit,
+		"total": total,
+		"users": users,
+	})
+}
+
+// GetUser detail satu user
+func (h *AdminHandler) GetUser(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	user, err := h.Users.GetByID(c, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
+
+// DisableUser: akun tidak bisa login, semua sesi & API key langsung mati
+func (h *AdminHandler) DisableUser(c *gin.Context) {
+	h.setDisabled(c, true)
+}
+
+// EnableUser: aktifkan lagi akun yang di-disable
+func (h *AdminHandler) EnableUser(c *gin.Context) {
+	h.setDisabled(c, false)
+}
+
+func (h *AdminHandler) setDisabled(c *gin.Context, disabled bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	// jangan sampai admin mengunci dirinya sendiri
+	if disabled && isCurrentUser(c, id) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "you cannot disable your own account"})
+		return
+	}
+
+	okRepo, err := h.Users.SetDisabled(c, id, disabled)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !okRepo {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+
+	msg := "user enabled"
+	if disabled {
+		msg = "user disabled"
+	}
+	c.JSON(http.StatusOK, gin.H{"message": msg})
+}
+
+// SetRole ganti role user
+func (h *AdminHandler) SetRole(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	var req struct {
+		Role string `json:"role"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Role != models.RoleUser && req.Role != models.RoleAdmin {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "role must be user or admin"})
+		return
+	}
+	if req.Role != models.RoleAdmin && isCurrentUser(c, id) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "you cannot remove your own admin role"})
+		return
+	}
+
+	okRepo, err := h.Users.SetRole(c, id, req.Role)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !okRepo {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "role updated"})
+}
+
+// ForcePasswordReset: semua sesi dimatikan, user wajib reset password lewat link di email
+func (h *AdminHandler) ForcePasswordReset(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	okRepo, err := h.Users.RequirePasswordReset(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !okRepo {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+
+	user, err := h.Users.GetByID(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.Auth.sendPasswordReset(ctx, user, "An administrator has required you to choose a new password."); err != nil {
+		log.Println("❌ gagal kirim email reset password:", err)
+		c.JSON(http.StatusOK, gin.H{"message": "password reset required, but the email could not be sent"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "password reset required, email sent"})
+}
+
+// UnlockUser: buka kunci akun yang terkunci karena terlalu banyak gagal login
+func (h *AdminHandler) UnlockUser(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	okRepo, err := h.Users.Unlock(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !okRepo {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+
+	h.Auth.recordEvent(c, &id, "", models.LoginEventUnlock, "admin", "")
+	c.JSON(http.StatusOK, gin.H{"message": "user unlocked"})
+}
+
+// Stats statistik seluruh sistem
+func (h *AdminHandler) Stats(c *gin.Context) {
+	stats, err := h.Repo.Stats(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, stats)
+}
+
+func isCurrentUser(c *gin.Context, id uuid.UUID) bool {
+	userIDVal, _ := c.Get("user_id")
+	uid, ok := userIDVal.(uuid.UUID)
+	return ok && uid == id
+}
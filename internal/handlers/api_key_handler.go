@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rifqi535/expense-tracker-api/internal/middleware"
+	"github.com/rifqi535/expense-tracker-api/internal/models"
+	"github.com/rifqi535/expense-tracker-api/internal/repository"
+)
+
+type APIKeyHandler struct {
+	Repo *repository.APIKeyRepo
+}
+
+func NewAPIKeyHandler(repo *repository.APIKeyRepo) *APIKeyHandler {
+	return &APIKeyHandler{Repo: repo}
+}
+
+// List API keys milik user (tanpa key-nya, cuma prefix)
+func (h *APIKeyHandler) List(c *gin.Context) {
+	userIDVal, _ := c.Get("user_id")
+	uid, ok := userIDVal.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	keys, err := h.Repo.ListByUser(c, uid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, keys)
+}
+
+// Create API key baru, key mentah cuma ditampilkan sekali di response ini
+func (h *APIKeyHandler) Create(c *gin.Context) {
+	userIDVal, _ := c.Get("user_id")
+	uid, ok := userIDVal.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	var req struct {
+		Name          string   `json:"name"`
+		Scopes        []string `json:"scopes"`
+		ExpiresInDays int      `json:"expires_in_days"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name is required"})
+		return
+	}
+	if req.ExpiresInDays < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expires_in_days must be positive"})
+		return
+	}
+
+	scopes, err := normalizeScopes(req.Scopes)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	prefix, err := generateOpaqueToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate key"})
+		return
+	}
+	secret, err := generateOpaqueToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate key"})
+		return
+	}
+	// contoh: etk_AbCdEfGh_<secret>
+	prefix = middleware.APIKeyPrefix + prefix[:8]
+	rawKey := prefix + "_" + secret
+
+	key := &models.APIKey{
+		ID:        uuid.New(),
+		UserID:    uid,
+		Name:      req.Name,
+		Prefix:    prefix,
+		KeyHash:   middleware.HashAPIKey(rawKey),
+		Scopes:    scopes,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if req.ExpiresInDays > 0 {
+		exp := time.Now().AddDate(0, 0, req.ExpiresInDays)
+		key.ExpiresAt = &exp
+	}
+
+	if err := h.Repo.Create(c, key); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"api_key": key,
+		"key":     rawKey,
+		"message": "store this key now, it won't be shown again",
+	})
+}
+
+// Update nama API key
+func (h *APIKeyHandler) Update(c *gin.Context) {
+	userIDVal, _ := c.Get("user_id")
+	uid, ok := userIDVal.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid api key id"})
+		return
+	}
+
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Name) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name is required"})
+		return
+	}
+
+	okRepo, err := h.Repo.Rename(c, uid, id, strings.TrimSpace(req.Name))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !okRepo {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "API key updated"})
+}
+
+// Delete (revoke) API key
+func (h *APIKeyHandler) Delete(c *gin.Context) {
+	userIDVal, _ := c.Get("user_id")
+	uid, ok := userIDVal.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid api key id"})
+		return
+	}
+
+	okRepo, err := h.Repo.Revoke(c, uid, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !okRepo {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "API key revoked"})
+}
+
+// normalizeScopes validasi & buang duplikat
+func normalizeScopes(scopes []string) ([]string, error) {
+	out := []string{}
+	seen := map[string]bool{}
+	for _, s := range scopes {
+		s = strings.TrimSpace(s)
+		if seen[s] {
+			continue
+		}
+
+		valid := false
+		for _, v := range middleware.ValidScopes {
+			if s == v {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return nil, fmt.Errorf("unknown scope %q, allowed: %s", s, strings.Join(middleware.ValidScopes, ", "))
+		}
+
+		seen[s] = true
+		out = append(out, s)
+	}
+	return out, nil
+}
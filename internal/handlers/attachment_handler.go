@@ -0,0 +1,307 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rifqi535/expense-tracker-api/internal/blob"
+	"github.com/rifqi535/expense-tracker-api/internal/config"
+	"github.com/rifqi535/expense-tracker-api/internal/models"
+	"github.com/rifqi535/expense-tracker-api/internal/repository"
+)
+
+// tipe file yang boleh diupload, ditentukan dari isi file (bukan Content-Type / ekstensi dari client)
+var allowedAttachmentTypes = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"application/pdf": ".pdf",
+}
+
+var errUnsupportedAttachment = errors.New("only JPEG, PNG, GIF, WebP images and PDF files are allowed")
+
+// sniffAttachment tipe file dari isi data + ekstensi yang dipakai untuk nama file
+func sniffAttachment(data []byte) (string, string, error) {
+	contentType, _, _ := mime.ParseMediaType(http.DetectContentType(data))
+	ext, allowed := allowedAttachmentTypes[contentType]
+	if !allowed {
+		return "", "", errUnsupportedAttachment
+	}
+	return contentType, ext, nil
+}
+
+// ruang tambahan untuk header multipart di atas ukuran file
+const multipartOverhead = 1 << 20
+
+type AttachmentHandler struct {
+	Repo     *repository.AttachmentRepo
+	Expenses *repository.ExpenseRepo
+	Store    blob.BlobStore
+	Cfg      *config.Config
+}
+
+func NewAttachmentHandler(repo *repository.AttachmentRepo, expenses *repository.ExpenseRepo, store blob.BlobStore, cfg *config.Config) *AttachmentHandler {
+	return &AttachmentHandler{Repo: repo, Expenses: expenses, Store: store, Cfg: cfg}
+}
+
+// 📌 List: lampiran satu expense + pemakaian kuota user
+func (h *AttachmentHandler) List(c *gin.Context) {
+	uid, expenseID, ok := h.expense(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	list, err := h.Repo.ListByExpense(ctx, uid, expenseID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	used, err := h.Repo.Usage(ctx, uid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"attachments": list,
+		"used_bytes":  used,
+		"quota_bytes": h.Cfg.AttachmentQuotaBytes, // 0 = tanpa batas
+	})
+}
+
+// 📌 Upload: multipart/form-data, field "file"
+func (h *AttachmentHandler) Upload(c *gin.Context) {
+	uid, expenseID, ok := h.expense(c)
+	if !ok {
+		return
+	}
+
+	maxBytes := h.Cfg.AttachmentMaxBytes
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes+multipartOverhead)
+
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("file must not exceed %d bytes", maxBytes)})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "multipart field \"file\" is required"})
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxBytes+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if int64(len(data)) > maxBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("file must not exceed %d bytes", maxBytes)})
+		return
+	}
+	if len(data) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file is empty"})
+		return
+	}
+
+	// --- SNIFF CONTENT TYPE ---
+	contentType, ext, err := sniffAttachment(data)
+	if err != nil {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+		return
+	}
+
+	// cek kuota dulu supaya tidak upload percuma, dicek ulang (terkunci) waktu simpan metadata
+	ctx := c.Request.Context()
+	if quota := h.Cfg.AttachmentQuotaBytes; quota > 0 {
+		used, err := h.Repo.Usage(ctx, uid)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if repository.QuotaExceeded(used, int64(len(data)), quota) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": repository.ErrAttachmentQuota.Error()})
+			return
+		}
+	}
+
+	sum := sha256.Sum256(data)
+	a := &models.Attachment{
+		ID:          uuid.New(),
+		UserID:      uid,
+		ExpenseID:   expenseID,
+		FileName:    attachmentFileName(header.Filename, ext),
+		ContentType: contentType,
+		SizeBytes:   int64(len(data)),
+		SHA256:      hex.EncodeToString(sum[:]),
+	}
+	a.StorageKey = "attachments/" + uid.String() + "/" + a.ID.String()
+
+	if err := h.Store.Put(ctx, a.StorageKey, bytes.NewReader(data), a.SizeBytes, a.ContentType); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to store file"})
+		log.Println("❌ gagal simpan lampiran:", err)
+		return
+	}
+
+	if err := h.Repo.Create(ctx, a, h.Cfg.AttachmentQuotaBytes, h.Cfg.AttachmentMaxPerExpense); err != nil {
+		// metadata gagal disimpan, file-nya dibuang lagi
+		if errDel := h.Store.Delete(ctx, a.StorageKey); errDel != nil {
+			log.Println("❌ gagal hapus lampiran:", errDel)
+		}
+		switch {
+		case errors.Is(err, repository.ErrAttachmentQuota):
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+		case errors.Is(err, repository.ErrTooManyAttachments):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, a)
+}
+
+// 📌 Download: isi file, selalu sebagai attachment supaya tidak dirender inline di domain API
+func (h *AttachmentHandler) Download(c *gin.Context) {
+	uid, expenseID, ok := h.expense(c)
+	if !ok {
+		return
+	}
+	a, ok := h.load(c, uid, expenseID)
+	if !ok {
+		return
+	}
+
+	rc, err := h.Store.Get(c.Request.Context(), a.StorageKey)
+	if errors.Is(err, blob.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to read file"})
+		log.Println("❌ gagal baca lampiran:", err)
+		return
+	}
+	defer rc.Close()
+
+	c.DataFromReader(http.StatusOK, a.SizeBytes, a.ContentType, rc, map[string]string{
+		"Content-Disposition":    mime.FormatMediaType("attachment", map[string]string{"filename": a.FileName}),
+		"X-Content-Type-Options": "nosniff",
+		"Cache-Control":          "private, no-store",
+		"ETag":                   strconv.Quote(a.SHA256),
+	})
+}
+
+// 📌 Delete: hapus metadata lalu file-nya
+func (h *AttachmentHandler) Delete(c *gin.Context) {
+	uid, expenseID, ok := h.expense(c)
+	if !ok {
+		return
+	}
+	a, ok := h.load(c, uid, expenseID)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	okRepo, err := h.Repo.Delete(ctx, uid, expenseID, a.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !okRepo {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	// file yang gagal dihapus cuma jadi sampah di storage, metadata sudah hilang
+	if err := h.Store.Delete(ctx, a.StorageKey); err != nil {
+		log.Println("❌ gagal hapus lampiran:", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Attachment deleted"})
+}
+
+// expense cek user & expense :id miliknya (yang belum dihapus), response error sudah dikirim kalau false
+func (h *AttachmentHandler) expense(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	userIDVal, _ := c.Get("user_id")
+	uid, ok := userIDVal.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user id"})
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid expense id"})
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	e, err := h.Expenses.GetByID(c.Request.Context(), uid, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return uuid.Nil, uuid.Nil, false
+	}
+	if e == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return uuid.Nil, uuid.Nil, false
+	}
+	return uid, id, true
+}
+
+// load ambil lampiran :attachment_id milik expense
+func (h *AttachmentHandler) load(c *gin.Context, uid, expenseID uuid.UUID) (*models.Attachment, bool) {
+	id, err := uuid.Parse(c.Param("attachment_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid attachment id"})
+		return nil, false
+	}
+
+	a, err := h.Repo.GetByID(c.Request.Context(), uid, expenseID, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if a == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return nil, false
+	}
+	return a, true
+}
+
+// attachmentFileName nama file dari client tanpa path & karakter kontrol, ekstensi disesuaikan tipe aslinya
+func attachmentFileName(name, ext string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == '"' {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(strings.TrimSuffix(name, filepath.Ext(name)))
+	if name == "" || name == "." || name == "/" {
+		name = "attachment"
+	}
+	for utf8.RuneCountInString(name) > 100 {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	return name + ext
+}
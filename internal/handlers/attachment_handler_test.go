@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSniffAttachment(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		wantType string
+		wantExt  string
+	}{
+		{"png", []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), "image/png", ".png"},
+		{"jpeg", []byte("\xff\xd8\xff\xe0\x00\x10JFIF\x00"), "image/jpeg", ".jpg"},
+		{"gif", []byte("GIF89a\x01\x00\x01\x00"), "image/gif", ".gif"},
+		{"webp", []byte("RIFF\x24\x00\x00\x00WEBPVP8 "), "image/webp", ".webp"},
+		{"pdf", []byte("%PDF-1.7\n%\xe2\xe3\xcf\xd3\n"), "application/pdf", ".pdf"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotType, gotExt, err := sniffAttachment(tt.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if gotType != tt.wantType || gotExt != tt.wantExt {
+				t.Fatalf("got (%q, %q), want (%q, %q)", gotType, gotExt, tt.wantType, tt.wantExt)
+			}
+		})
+	}
+}
+
+func TestSniffAttachmentRejects(t *testing.T) {
+	// jenis file ditentukan dari isi, bukan dari nama / Content-Type yang dikirim client
+	tests := map[string][]byte{
+		"html":  []byte("<!DOCTYPE html><html><script>alert(1)</script></html>"),
+		"svg":   []byte(`<svg xmlns="http://www.w3.org/2000/svg"><script>alert(1)</script></svg>`),
+		"text":  []byte("just a receipt, honest"),
+		"exe":   []byte("MZ\x90\x00\x03\x00\x00\x00\x04\x00\x00\x00\xff\xff"),
+		"zip":   []byte("PK\x03\x04\x14\x00\x00\x00\x08\x00"),
+		"empty": {},
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, _, err := sniffAttachment(data); !errors.Is(err, errUnsupportedAttachment) {
+				t.Fatalf("err = %v, want errUnsupportedAttachment", err)
+			}
+		})
+	}
+}
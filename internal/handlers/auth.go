@@ -4,29 +4,90 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"github.com/rifqi535/expense-tracker-api/internal/middleware"
+	"github.com/rifqi535/expense-tracker-api/internal/calendar"
+	"github.com/rifqi535/expense-tracker-api/internal/config"
+	"github.com/rifqi535/expense-tracker-api/internal/mailer"
 	"github.com/rifqi535/expense-tracker-api/internal/models"
+	"github.com/rifqi535/expense-tracker-api/internal/money"
+	"github.com/rifqi535/expense-tracker-api/internal/oidc"
+	"github.com/rifqi535/expense-tracker-api/internal/repository"
+	"github.com/rifqi535/expense-tracker-api/internal/seed"
+	"github.com/rifqi535/expense-tracker-api/internal/token"
 	"gorm.io/gorm"
 
-	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
 )
 
-var jwtSecret = []byte("JWT_SECRET")
-
 type AuthHandler struct {
-	DB *gorm.DB
+	DB       *gorm.DB
+	Cfg      *config.Config
+	Users    *repository.UserRepo
+	Tokens   *repository.RefreshTokenRepo
+	Sessions *repository.SessionRepo
+	Resets   *repository.PasswordResetRepo
+	MFA      *repository.MFARepo
+	OIDC     *repository.OIDCRepo
+	Events   *repository.LoginEventRepo
+	Mailer   mailer.Mailer
+	JWT      *token.Service
+	// kategori default untuk user baru, nil = tidak ada
+	DefaultCategories seed.CategorySet
+
+	// provider OIDC berdasarkan nama di URL (/auth/oidc/:provider/...)
+	Providers map[string]*oidc.Provider
 }
 
-func NewAuthHandler(db *gorm.DB) *AuthHandler {
-	return &AuthHandler{DB: db}
+func NewAuthHandler(db *gorm.DB, cfg *config.Config, m mailer.Mailer, jwt *token.Service, categories seed.CategorySet) *AuthHandler {
+	providers := map[string]*oidc.Provider{}
+	for _, p := range cfg.OIDCProviders {
+		providers[p.Name] = oidc.NewProvider(oidc.Config{
+			Name:         p.Name,
+			Issuer:       p.Issuer,
+			ClientID:     p.ClientID,
+			ClientSecret: p.ClientSecret,
+			RedirectURL:  p.RedirectURL,
+			Scopes:       p.Scopes,
+			AuthURL:      p.AuthURL,
+			TokenURL:     p.TokenURL,
+			JWKSURL:      p.JWKSURL,
+		})
+	}
+
+	return &AuthHandler{
+		DB:       db,
+		Cfg:      cfg,
+		Users:    repository.NewUserRepo(db),
+		Tokens:   repository.NewRefreshTokenRepo(db),
+		Sessions: repository.NewSessionRepo(db),
+		Resets:   repository.NewPasswordResetRepo(db),
+		MFA:      repository.NewMFARepo(db),
+		OIDC:     repository.NewOIDCRepo(db),
+		Events:   repository.NewLoginEventRepo(db),
+		Mailer:   m,
+		JWT:      jwt,
+
+		DefaultCategories: categories,
+		Providers:         providers,
+	}
 }
 
+const minPasswordLength = 6
+
+var (
+	errEmailNotVerified      = errors.New("email not verified")
+	errAccountDisabled       = errors.New("account is disabled")
+	errPasswordResetRequired = errors.New("password reset required, check your email")
+	errAccountDeleted        = errors.New("account is scheduled for deletion, check your email to restore it")
+)
+
 // 🔑 Hash password
 func hashPassword(password string) (string, error) {
 	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
@@ -39,43 +100,79 @@ func checkPasswordHash(password, hash string) bool {
 	return err == nil
 }
 
-// 🔑 Generate JWT
-func GenerateToken(userID string) (string, error) {
-	claims := jwt.MapClaims{
-		"user_id": userID, // harus string!
-		"exp":     time.Now().Add(72 * time.Hour).Unix(),
-	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtSecret)
-}
-
 // 📌 Register
 func (h *AuthHandler) Register(c *gin.Context) {
 	var req struct {
 		Name     string `json:"name"`
 		Email    string `json:"email"`
 		Password string `json:"password"`
+		Currency string `json:"currency"` // opsional, default DEFAULT_CURRENCY
+		Timezone string `json:"timezone"` // opsional, default DEFAULT_TIMEZONE
+		Locale   string `json:"locale"`   // opsional, default DEFAULT_LOCALE
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	currency := h.Cfg.DefaultCurrency
+	if req.Currency != "" {
+		currency = money.NormalizeCurrency(req.Currency)
+		if !money.ValidCurrency(currency) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid currency"})
+			return
+		}
+	}
+
+	timezone := h.Cfg.DefaultTimezone
+	if req.Timezone != "" {
+		if _, err := calendar.LoadLocation(req.Timezone); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		timezone = strings.TrimSpace(req.Timezone)
+	}
+	locale := h.Cfg.DefaultLocale
+	if req.Locale != "" {
+		var err error
+		if locale, err = calendar.NormalizeLocale(req.Locale); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
 	hashed, _ := hashPassword(req.Password)
 	userID := uuid.New()
 
-	result := h.DB.WithContext(context.Background()).Exec(
-		`INSERT INTO users (id, name, email, password_hash, created_at, updated_at)
-	 VALUES (?, ?, ?, ?, ?, ?)`,
-		userID, req.Name, req.Email, hashed, time.Now(), time.Now(),
-	)
+	// user + kategori default dalam satu transaksi
+	err := h.DB.WithContext(context.Background()).Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec(
+			`INSERT INTO users (id, name, email, password_hash, currency, timezone, locale, week_start, verification_sent_at, created_at, updated_at)
+	 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			userID, req.Name, req.Email, hashed, currency, timezone, locale, int(calendar.DefaultWeekStart(locale)), time.Now(), time.Now(), time.Now(),
+		).Error
+		if err != nil {
+			return err
+		}
 
-	if result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": result.Error.Error()})
+		categories := h.DefaultCategories.Models(userID, locale, time.Now())
+		if len(categories) == 0 {
+			return nil
+		}
+		return tx.Create(&categories).Error
+	})
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"message": "user registered"})
+	// kirim link verifikasi, gagal kirim tidak menggagalkan register (bisa resend)
+	if err := h.sendVerificationEmail(c.Request.Context(), userID, req.Email); err != nil {
+		log.Println("❌ gagal kirim email verifikasi:", err)
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "user registered, please check your email to verify your account"})
 
 }
 
@@ -90,15 +187,22 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
+	// IP ini sudah terlalu sering gagal?
+	if !h.checkIPThrottle(c) {
+		return
+	}
+
 	// ambil user dari DB
 	var user models.User
 	err := h.DB.WithContext(c.Request.Context()).
-		Select("id", "password_hash").
+		Select("id", "email", "password_hash", "role", "disabled_at", "deletion_requested_at", "password_reset_required", "email_verified_at", "totp_enabled_at",
+			"failed_login_count", "last_failed_login_at", "locked_until").
 		Where("email = ?", req.Email).
 		First(&user).Error
 
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
+			h.recordEvent(c, nil, req.Email, models.LoginEventFailure, "password", "unknown email")
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid email/password"})
 			return
 		}
@@ -106,20 +210,351 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
+	// akun terkunci / masih backoff → password tidak dicek sama sekali
+	if !h.checkAccountLock(c, &user) {
+		return
+	}
+
 	// cek password
 	if !checkPasswordHash(req.Password, user.PasswordHash) {
+		h.loginFailed(c, &user, "password", "invalid password")
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid email/password"})
 		return
 	}
 
-	// generate token
-	token, err := middleware.GenerateToken(user.ID.String())
+	h.completeLogin(c, &user, "password")
+}
+
+// 📌 Refresh: tukar refresh token dengan pasangan token baru (rotasi)
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.RefreshToken == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "refresh_token is required"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	old, err := h.Tokens.GetByHash(ctx, hashToken(req.RefreshToken))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid refresh token"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// token yang sudah pernah dipakai / di-revoke dipakai lagi → anggap bocor, matikan seluruh sesi
+	if old.RevokedAt != nil {
+		if _, err := h.Sessions.Revoke(ctx, old.UserID, old.FamilyID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "refresh token reuse detected, please login again"})
+		return
+	}
+
+	if time.Now().After(old.ExpiresAt) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "refresh token expired"})
+		return
+	}
+
+	// status verifikasi bisa berubah sejak login, jadi claims dihitung ulang
+	user, err := h.Users.GetByID(ctx, old.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid refresh token"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	claims, err := h.accessClaims(user, old.FamilyID)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	refreshToken, next, err := h.newRefreshToken(old.UserID, old.FamilyID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate token"})
+		return
+	}
+
+	rotated, err := h.Tokens.Rotate(ctx, old, next)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !rotated {
+		// kalah balapan dengan request lain yang pakai token yang sama → reuse juga
+		if _, err := h.Sessions.Revoke(ctx, old.UserID, old.FamilyID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "refresh token reuse detected, please login again"})
+		return
+	}
+
+	accessToken, err := h.JWT.GenerateAccessToken(claims)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, h.tokenResponse(accessToken, refreshToken))
+}
+
+// 📌 Logout: revoke sesi yang sedang dipakai
+func (h *AuthHandler) Logout(c *gin.Context) {
+	userIDVal, _ := c.Get("user_id")
+	uid, ok := userIDVal.(uuid.UUID)
+	sessionVal, _ := c.Get("session_id")
+	sid, okSession := sessionVal.(uuid.UUID)
+	if !ok || !okSession {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid session"})
+		return
+	}
+
+	if _, err := h.Sessions.Revoke(c.Request.Context(), uid, sid); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "logged out"})
+}
+
+// 📌 LogoutAll: revoke semua sesi milik user
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	userIDVal, _ := c.Get("user_id")
+	uid, ok := userIDVal.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	if err := h.Sessions.RevokeAllForUser(c.Request.Context(), uid); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "logged out from all sessions"})
+}
+
+// 📌 ForgotPassword: kirim link reset password ke email user
+func (h *AuthHandler) ForgotPassword(c *gin.Context) {
+	var req struct {
+		Email string `json:"email"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.Email == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "email is required"})
+		return
+	}
+
+	// response selalu sama supaya tidak bocor email mana yang terdaftar
+	resp := gin.H{"message": "if the email is registered, a reset link has been sent"}
+
+	ctx := c.Request.Context()
+	var user models.User
+	err := h.DB.WithContext(ctx).
+		Select("id", "email").
+		Where("email = ?", req.Email).
+		First(&user).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusOK, resp)
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.sendPasswordReset(ctx, &user, "Someone requested a password reset for your account."); err != nil {
+		// jangan bocorin error mail ke client
+		log.Println("❌ gagal kirim email reset password:", err)
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// sendPasswordReset bikin token reset baru lalu kirim link-nya ke email user
+func (h *AuthHandler) sendPasswordReset(ctx context.Context, user *models.User, intro string) error {
+	raw, err := generateOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	_ = h.Resets.DeleteExpired(ctx, user.ID)
+	if err := h.Resets.Create(ctx, &models.PasswordResetToken{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		TokenHash: hashToken(raw),
+		ExpiresAt: time.Now().Add(h.Cfg.PasswordResetTTL),
+		CreatedAt: time.Now(),
+	}); err != nil {
+		return err
+	}
+
+	link := h.Cfg.AppBaseURL + "/reset-password?token=" + url.QueryEscape(raw)
+	return h.Mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("%s\n\n"+
+			"Use this link within %s to choose a new password:\n%s\n\n"+
+			"If it wasn't you, you can ignore this email.", intro, h.Cfg.PasswordResetTTL, link),
+	})
+}
+
+// 📌 ResetPassword: ganti password pakai token dari email
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	var req struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.Token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "token and password are required"})
+		return
+	}
+	if len(req.Password) < minPasswordLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("password must be at least %d characters", minPasswordLength)})
+		return
+	}
+
+	hashed, err := hashPassword(req.Password)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to hash password"})
+		return
+	}
+
+	ok, err := h.Resets.ResetPassword(c.Request.Context(), hashToken(req.Token), hashed)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid or expired reset token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "password has been reset, please login again"})
+}
+
+// completeLogin dipanggil setelah user terbukti pemilik akun (password / OIDC):
+// minta MFA kalau aktif, kalau tidak langsung kasih access + refresh token
+func (h *AuthHandler) completeLogin(c *gin.Context, user *models.User, method string) {
+	claims, err := h.accessClaims(user, uuid.New())
+	if err != nil {
+		// bukan salah tebak password, jadi tidak dihitung ke lockout
+		h.recordEvent(c, &user.ID, user.Email, models.LoginEventFailure, method, err.Error())
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	// MFA aktif → belum dapat token normal, cuma mfa_token untuk /auth/mfa/verify
+	if user.TOTPEnabledAt != nil {
+		mfaToken, err := h.JWT.GenerateMFAPendingToken(user.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate token"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"mfa_required": true,
+			"mfa_token":    mfaToken,
+			"expires_in":   int(h.JWT.MFAPendingTTL().Seconds()),
+		})
+		return
+	}
+
+	// generate access + refresh token (sesi login baru)
+	tokens, err := h.issueTokens(c, claims)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate token"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"token": token})
+	h.loginSucceeded(c, user, method)
+	c.JSON(http.StatusOK, tokens)
+}
+
+// accessClaims tentukan isi access token sesuai status akun, role & verifikasi email user
+func (h *AuthHandler) accessClaims(user *models.User, sessionID uuid.UUID) (token.Claims, error) {
+	claims := token.Claims{UserID: user.ID, SessionID: sessionID, Role: user.Role}
+
+	if user.DisabledAt != nil {
+		return claims, errAccountDisabled
+	}
+	if user.DeletionRequestedAt != nil {
+		return claims, errAccountDeleted
+	}
+	if user.PasswordResetRequired {
+		return claims, errPasswordResetRequired
+	}
+
+	if user.EmailVerifiedAt == nil {
+		switch h.Cfg.UnverifiedLogin {
+		case config.UnverifiedLoginDeny:
+			return claims, errEmailNotVerified
+		case config.UnverifiedLoginRestricted:
+			claims.Restricted = true
+		}
+	}
+	return claims, nil
+}
+
+// issueTokens mulai sesi baru (device, IP) + refresh token pertamanya, lalu bikin access token
+func (h *AuthHandler) issueTokens(c *gin.Context, claims token.Claims) (gin.H, error) {
+	refreshToken, rt, err := h.newRefreshToken(claims.UserID, claims.SessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	session := &models.Session{
+		ID:         claims.SessionID,
+		UserID:     claims.UserID,
+		UserAgent:  c.Request.UserAgent(),
+		IP:         c.ClientIP(),
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  rt.ExpiresAt,
+	}
+	if err := h.Sessions.Start(c.Request.Context(), session, rt); err != nil {
+		return nil, err
+	}
+
+	accessToken, err := h.JWT.GenerateAccessToken(claims)
+	if err != nil {
+		return nil, err
+	}
+	return h.tokenResponse(accessToken, refreshToken), nil
+}
+
+// newRefreshToken return token mentah (buat client) + row yang disimpan (hash saja)
+func (h *AuthHandler) newRefreshToken(userID, sessionID uuid.UUID) (string, *models.RefreshToken, error) {
+	raw, err := generateOpaqueToken()
+	if err != nil {
+		return "", nil, err
+	}
+
+	rt := &models.RefreshToken{
+		ID:        uuid.New(),
+		UserID:    userID,
+		FamilyID:  sessionID,
+		TokenHash: hashToken(raw),
+		ExpiresAt: time.Now().Add(h.Cfg.RefreshTokenTTL),
+		CreatedAt: time.Now(),
+	}
+	return raw, rt, nil
+}
+
+func (h *AuthHandler) tokenResponse(accessToken, refreshToken string) gin.H {
+	return gin.H{
+		"token":         accessToken,
+		"access_token":  accessToken,
+		"refresh_token": refreshToken,
+		"token_type":    "Bearer",
+		"expires_in":    int(h.JWT.AccessTTL().Seconds()),
+	}
 }
 
 // 📌 GetProfile
@@ -143,7 +578,7 @@ func (h *AuthHandler) GetProfile(c *gin.Context) {
 	// ambil data user dari DB
 	var user models.User
 	err = h.DB.WithContext(c.Request.Context()).
-		Select("name", "email").
+		Select("name", "email", "role", "currency", "timezone", "locale", "week_start", "email_verified_at", "pending_email", "totp_enabled_at", "created_at").
 		Where("id = ?", uid).
 		First(&user).Error
 
@@ -158,8 +593,17 @@ func (h *AuthHandler) GetProfile(c *gin.Context) {
 
 	// balikin profile
 	c.JSON(http.StatusOK, gin.H{
-		"id":    uid.String(),
-		"name":  user.Name,
-		"email": user.Email,
+		"id":                uid.String(),
+		"name":              user.Name,
+		"email":             user.Email,
+		"role":              user.Role,
+		"currency":          user.Currency,
+		"timezone":          user.Timezone,
+		"locale":            user.Locale,
+		"week_start":        strings.ToLower(time.Weekday(user.WeekStart).String()),
+		"email_verified_at": user.EmailVerifiedAt,
+		"pending_email":     user.PendingEmail,
+		"mfa_enabled":       user.TOTPEnabledAt != nil,
+		"created_at":        user.CreatedAt,
 	})
 }
@@ -0,0 +1,571 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rifqi535/expense-tracker-api/internal/budget"
+	"github.com/rifqi535/expense-tracker-api/internal/calendar"
+	"github.com/rifqi535/expense-tracker-api/internal/models"
+	"github.com/rifqi535/expense-tracker-api/internal/money"
+	"github.com/rifqi535/expense-tracker-api/internal/repository"
+	"gorm.io/gorm"
+)
+
+type BudgetHandler struct {
+	Repo        *repository.BudgetRepo
+	Allocations *repository.AllocationRepo
+	Users       *repository.UserRepo
+	Tracker     *budget.Tracker
+}
+
+func NewBudgetHandler(repo *repository.BudgetRepo, allocations *repository.AllocationRepo, users *repository.UserRepo, tracker *budget.Tracker) *BudgetHandler {
+	return &BudgetHandler{Repo: repo, Allocations: allocations, Users: users, Tracker: tracker}
+}
+
+// budgetResponse budget dengan periode dalam format YYYY-MM
+type budgetResponse struct {
+	models.Budget
+	StartMonth string  `json:"start_month"`
+	EndMonth   *string `json:"end_month"` // null = berlaku seterusnya
+}
+
+func toBudgetResponse(b models.Budget) budgetResponse {
+	resp := budgetResponse{Budget: b, StartMonth: b.StartMonth.Format("2006-01")}
+	if b.EndMonth != nil {
+		end := b.EndMonth.Format("2006-01")
+		resp.EndMonth = &end
+	}
+	return resp
+}
+
+// 📌 List: semua budget milik user, termasuk periode yang sudah lewat
+func (h *BudgetHandler) List(c *gin.Context) {
+	userIDVal, _ := c.Get("user_id")
+	uid, ok := userIDVal.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	list, err := h.Repo.ListByUser(c.Request.Context(), uid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp := make([]budgetResponse, 0, len(list))
+	for _, b := range list {
+		resp = append(resp, toBudgetResponse(b))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// 📌 Create: category_id kosong = budget total semua pengeluaran.
+// start_month default bulan ini, end_month kosong = berlaku seterusnya, currency default mata uang user.
+func (h *BudgetHandler) Create(c *gin.Context) {
+	userIDVal, _ := c.Get("user_id")
+	uid, ok := userIDVal.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	var req struct {
+		CategoryID string      `json:"category_id"`
+		Amount     money.Money `json:"amount"`
+		Currency   string      `json:"currency"`
+		StartMonth string      `json:"start_month"` // YYYY-MM
+		EndMonth   string      `json:"end_month"`   // YYYY-MM, inklusif
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	prefs, err := h.Users.GetPreferences(ctx, uid)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	now := time.Now()
+	b := &models.Budget{ID: uuid.New(), UserID: uid, Amount: req.Amount, CreatedAt: now, UpdatedAt: now}
+	if req.CategoryID != "" {
+		categoryID, err := uuid.Parse(req.CategoryID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid category id"})
+			return
+		}
+		b.CategoryID = &categoryID
+	}
+
+	b.Currency = money.NormalizeCurrency(req.Currency)
+	if b.Currency == "" {
+		b.Currency = prefs.Currency
+	}
+	if err := validateAmount(b.Amount, b.Currency); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// bulan ini menurut zona waktu user
+	loc, err := calendar.LoadLocation(prefs.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	y, m, _ := now.In(loc).Date()
+	b.StartMonth = time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)
+	if !setBudgetPeriod(c, b, &req.StartMonth, &req.EndMonth) {
+		return
+	}
+
+	if err := h.Repo.Create(ctx, b); err != nil {
+		if status, ok := budgetErrorStatus(err); ok {
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, toBudgetResponse(*b))
+}
+
+// 📌 Update: field yang tidak dikirim tidak berubah, end_month "" = berlaku seterusnya.
+// effective_from (YYYY-MM): limit baru cuma berlaku mulai bulan itu, periode sebelumnya tetap pakai limit lama.
+func (h *BudgetHandler) Update(c *gin.Context) {
+	userIDVal, _ := c.Get("user_id")
+	uid, ok := userIDVal.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user id"})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid budget id"})
+		return
+	}
+
+	var req struct {
+		Amount        *money.Money `json:"amount"`
+		Currency      *string      `json:"currency"`
+		StartMonth    *string      `json:"start_month"`
+		EndMonth      *string      `json:"end_month"`
+		EffectiveFrom string       `json:"effective_from"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	b, err := h.Repo.GetByID(ctx, uid, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if b == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+
+	if req.Amount != nil {
+		b.Amount = *req.Amount
+	}
+	if req.Currency != nil {
+		b.Currency = money.NormalizeCurrency(*req.Currency)
+	}
+	if err := validateAmount(b.Amount, b.Currency); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.EffectiveFrom != "" {
+		if req.StartMonth != nil || req.EndMonth != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "use either effective_from or start_month/end_month"})
+			return
+		}
+		from, err := parseMonth(req.EffectiveFrom)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid effective_from, use YYYY-MM"})
+			return
+		}
+		next, err := h.Repo.Split(ctx, uid, id, from, b.Amount, b.Currency)
+		if err != nil {
+			if status, ok := budgetErrorStatus(err); ok {
+				c.JSON(status, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if next == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		c.JSON(http.StatusOK, toBudgetResponse(*next))
+		return
+	}
+
+	if !setBudgetPeriod(c, b, req.StartMonth, req.EndMonth) {
+		return
+	}
+	okRepo, err := h.Repo.Update(ctx, b)
+	if err != nil {
+		if status, ok := budgetErrorStatus(err); ok {
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !okRepo {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	c.JSON(http.StatusOK, toBudgetResponse(*b))
+}
+
+// 📌 Delete: hapus satu periode budget
+func (h *BudgetHandler) Delete(c *gin.Context) {
+	userIDVal, _ := c.Get("user_id")
+	uid, ok := userIDVal.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user id"})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid budget id"})
+		return
+	}
+
+	okRepo, err := h.Repo.Delete(c.Request.Context(), uid, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !okRepo {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Budget deleted"})
+}
+
+// 📌 Status: limit, terpakai, sisa, persen & proyeksi akhir bulan tiap budget
+// ?month=YYYY-MM (default bulan ini di zona waktu user)
+func (h *BudgetHandler) Status(c *gin.Context) {
+	userIDVal, _ := c.Get("user_id")
+	uid, ok := userIDVal.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	report, err := h.Tracker.Month(c.Request.Context(), uid, c.Query("month"), time.Now())
+	if errors.Is(err, budget.ErrInvalidMonth) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// allocationResponse baris ledger dengan bulan dalam format YYYY-MM
+type allocationResponse struct {
+	models.BudgetAllocation
+	Month string `json:"month"`
+}
+
+func toAllocationResponses(list []models.BudgetAllocation) []allocationResponse {
+	resp := make([]allocationResponse, 0, len(list))
+	for _, a := range list {
+		resp = append(resp, allocationResponse{BudgetAllocation: a, Month: a.Month.Format("2006-01")})
+	}
+	return resp
+}
+
+// 📌 Envelopes: saldo envelope (dibawa dari bulan lalu + budget + alokasi - terpakai)
+// ?month=YYYY-MM (default bulan ini di zona waktu user)
+func (h *BudgetHandler) Envelopes(c *gin.Context) {
+	userIDVal, _ := c.Get("user_id")
+	uid, ok := userIDVal.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	report, err := h.Tracker.Envelopes(c.Request.Context(), uid, c.Query("month"), time.Now())
+	if errors.Is(err, budget.ErrInvalidMonth) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// 📌 ListAllocations: ledger alokasi satu bulan ?month=YYYY-MM (default bulan ini)
+func (h *BudgetHandler) ListAllocations(c *gin.Context) {
+	userIDVal, _ := c.Get("user_id")
+	uid, ok := userIDVal.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	month, ok := h.allocationMonth(c, uid, c.Query("month"))
+	if !ok {
+		return
+	}
+	list, err := h.Allocations.ListByMonth(ctx, uid, month)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, toAllocationResponses(list))
+}
+
+// 📌 Allocate: tambah (amount positif) / kurangi (negatif) isi satu envelope di satu bulan
+func (h *BudgetHandler) Allocate(c *gin.Context) {
+	userIDVal, _ := c.Get("user_id")
+	uid, ok := userIDVal.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	var req struct {
+		CategoryID string      `json:"category_id"`
+		Month      string      `json:"month"` // YYYY-MM, default bulan ini
+		Amount     money.Money `json:"amount"`
+		Note       *string     `json:"note"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	categoryID, err := uuid.Parse(req.CategoryID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid category id"})
+		return
+	}
+
+	month, ok := h.allocationMonth(c, uid, req.Month)
+	if !ok {
+		return
+	}
+	currency, ok := h.baseCurrency(c, uid)
+	if !ok {
+		return
+	}
+	if req.Amount.IsZero() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "amount must not be 0"})
+		return
+	}
+	if err := req.Amount.CheckCurrency(currency); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	a := &models.BudgetAllocation{
+		ID:         uuid.New(),
+		UserID:     uid,
+		CategoryID: categoryID,
+		Month:      month,
+		Amount:     req.Amount,
+		Currency:   currency,
+		Note:       req.Note,
+		CreatedAt:  time.Now(),
+	}
+	if err := h.Allocations.Create(c.Request.Context(), a); err != nil {
+		if status, ok := budgetErrorStatus(err); ok {
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, toAllocationResponses([]models.BudgetAllocation{*a})[0])
+}
+
+// 📌 Transfer: pindahkan uang dari satu envelope ke envelope lain di bulan yang sama
+func (h *BudgetHandler) Transfer(c *gin.Context) {
+	userIDVal, _ := c.Get("user_id")
+	uid, ok := userIDVal.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	var req struct {
+		FromCategoryID string      `json:"from_category_id"`
+		ToCategoryID   string      `json:"to_category_id"`
+		Month          string      `json:"month"` // YYYY-MM, default bulan ini
+		Amount         money.Money `json:"amount"`
+		Note           *string     `json:"note"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	from, err := uuid.Parse(req.FromCategoryID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from_category_id"})
+		return
+	}
+	to, err := uuid.Parse(req.ToCategoryID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to_category_id"})
+		return
+	}
+
+	month, ok := h.allocationMonth(c, uid, req.Month)
+	if !ok {
+		return
+	}
+	currency, ok := h.baseCurrency(c, uid)
+	if !ok {
+		return
+	}
+	if err := validateAmount(req.Amount, currency); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	legs, err := h.Allocations.Transfer(c.Request.Context(), uid, from, to, month, req.Amount, currency, req.Note)
+	if err != nil {
+		if status, ok := budgetErrorStatus(err); ok {
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, toAllocationResponses(legs))
+}
+
+// 📌 DeleteAllocation: hapus satu baris ledger (transfer → dua sisinya)
+func (h *BudgetHandler) DeleteAllocation(c *gin.Context) {
+	userIDVal, _ := c.Get("user_id")
+	uid, ok := userIDVal.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user id"})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid allocation id"})
+		return
+	}
+
+	okRepo, err := h.Allocations.Delete(c.Request.Context(), uid, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !okRepo {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Allocation deleted"})
+}
+
+// allocationMonth bulan dari "YYYY-MM", "" = bulan ini di zona waktu user
+func (h *BudgetHandler) allocationMonth(c *gin.Context, uid uuid.UUID, s string) (time.Time, bool) {
+	if s != "" {
+		month, err := parseMonth(s)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid month, use YYYY-MM"})
+			return time.Time{}, false
+		}
+		return month, true
+	}
+
+	prefs, err := h.Users.GetPreferences(c.Request.Context(), uid)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return time.Time{}, false
+	}
+	loc := time.UTC
+	if prefs != nil {
+		if l, err := calendar.LoadLocation(prefs.Timezone); err == nil {
+			loc = l
+		}
+	}
+	y, m, _ := time.Now().In(loc).Date()
+	return time.Date(y, m, 1, 0, 0, 0, 0, time.UTC), true
+}
+
+// baseCurrency mata uang dasar user, alokasi selalu dicatat dalam mata uang ini
+func (h *BudgetHandler) baseCurrency(c *gin.Context, uid uuid.UUID) (string, bool) {
+	prefs, err := h.Users.GetPreferences(c.Request.Context(), uid)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return "", false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return "", false
+	}
+	return prefs.Currency, true
+}
+
+// setBudgetPeriod isi start / end dari request (nil = tidak diubah, end "" = seterusnya)
+func setBudgetPeriod(c *gin.Context, b *models.Budget, start, end *string) bool {
+	if start != nil && *start != "" {
+		month, err := parseMonth(*start)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid start_month, use YYYY-MM"})
+			return false
+		}
+		b.StartMonth = month
+	}
+	if end != nil {
+		b.EndMonth = nil
+		if *end != "" {
+			month, err := parseMonth(*end)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid end_month, use YYYY-MM"})
+				return false
+			}
+			b.EndMonth = &month
+		}
+	}
+	if b.EndMonth != nil && b.EndMonth.Before(b.StartMonth) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "end_month must not be before start_month"})
+		return false
+	}
+	return true
+}
+
+// parseMonth "YYYY-MM" → tanggal 1 bulan itu (UTC, disimpan sebagai DATE)
+func parseMonth(s string) (time.Time, error) {
+	return time.Parse("2006-01", s)
+}
+
+func budgetErrorStatus(err error) (int, bool) {
+	switch {
+	case errors.Is(err, repository.ErrCategoryNotFound):
+		return http.StatusBadRequest, true
+	case errors.Is(err, repository.ErrBudgetPeriod), errors.Is(err, repository.ErrSameEnvelope):
+		return http.StatusBadRequest, true
+	case errors.Is(err, repository.ErrBudgetOverlap):
+		return http.StatusConflict, true
+	}
+	return 0, false
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -17,7 +18,16 @@ func NewCategoryHandler(repo *repository.CategoryRepo) *CategoryHandler {
 	return &CategoryHandler{Repo: repo}
 }
 
-// List categories for logged-in user
+// categoryNode satu kategori di tampilan tree, jumlah expense termasuk subkategori
+type categoryNode struct {
+	models.Category
+	ExpenseCount      int64           `json:"expense_count"`
+	TotalExpenseCount int64           `json:"total_expense_count"` // termasuk semua subkategori
+	Children          []*categoryNode `json:"children"`
+}
+
+// List categories for logged-in user, ?view=tree untuk bentuk bertingkat.
+// Kategori yang diarsipkan hanya muncul dengan ?include_archived=true
 func (h *CategoryHandler) List(c *gin.Context) {
 	userIDVal, _ := c.Get("user_id")
 	uid, ok := userIDVal.(uuid.UUID)
@@ -26,18 +36,40 @@ func (h *CategoryHandler) List(c *gin.Context) {
 		return
 	}
 
-	categories, err := h.Repo.ListByUser(c, uid)
+	list := h.Repo.ListActive
+	if c.Query("include_archived") == "true" {
+		list = h.Repo.ListByUser
+	}
+	categories, err := list(c, uid)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, categories)
+
+	switch c.DefaultQuery("view", "flat") {
+	case "flat":
+		c.JSON(http.StatusOK, categories)
+	case "tree":
+		counts, err := h.Repo.ExpenseCounts(c, uid)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, buildCategoryTree(categories, counts))
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "view must be flat or tree"})
+	}
 }
 
-// Create new category
+// Create new category, parent_id opsional untuk subkategori
 func (h *CategoryHandler) Create(c *gin.Context) {
 	var req struct {
-		Title string `json:"title"`
+		Title     string `json:"title"`
+		ParentID  string `json:"parent_id"`
+		Color     string `json:"color"` // opsional, #RRGGBB
+		Icon      string `json:"icon"`  // opsional, key ikon di client
+		SortOrder int    `json:"sort_order"`
+		Rollover  string `json:"rollover"` // opsional, aturan envelope, default none
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -51,19 +83,59 @@ func (h *CategoryHandler) Create(c *gin.Context) {
 		return
 	}
 
+	color, err := repository.NormalizeColor(req.Color)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !repository.ValidIcon(req.Icon) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": repository.ErrInvalidIcon.Error()})
+		return
+	}
+	if req.Rollover == "" {
+		req.Rollover = models.RolloverNone
+	}
+	if !repository.ValidRollover(req.Rollover) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": repository.ErrInvalidRollover.Error()})
+		return
+	}
+
 	category := &models.Category{
-		ID:     uuid.New(),
-		Title:  req.Title,
-		UserID: uid,
+		ID:        uuid.New(),
+		Title:     req.Title,
+		UserID:    uid,
+		SortOrder: req.SortOrder,
+		Rollover:  req.Rollover,
 	}
+	if color != "" {
+		category.Color = &color
+	}
+	if req.Icon != "" {
+		category.Icon = &req.Icon
+	}
+	if req.ParentID != "" {
+		parentID, err := uuid.Parse(req.ParentID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid parent id"})
+			return
+		}
+		category.ParentID = &parentID
+	}
+
 	if err := h.Repo.Create(c, category); err != nil {
+		if status, ok := categoryErrorStatus(err); ok {
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusCreated, category)
 }
 
-// Update category
+// Update category, field yang tidak dikirim tidak berubah.
+// parent_id: id kategori tujuan, "" = jadikan kategori utama. color / icon: "" = hapus.
+// archived: true / false, berlaku juga ke subkategori. rollover: none / surplus / deficit / full
 func (h *CategoryHandler) Update(c *gin.Context) {
 	userIDVal, _ := c.Get("user_id")
 	uid, ok := userIDVal.(uuid.UUID)
@@ -80,15 +152,54 @@ func (h *CategoryHandler) Update(c *gin.Context) {
 	}
 
 	var req struct {
-		Title string `json:"title"`
+		Title     *string `json:"title"`
+		ParentID  *string `json:"parent_id"`
+		Color     *string `json:"color"`
+		Icon      *string `json:"icon"`
+		SortOrder *int    `json:"sort_order"`
+		Archived  *bool   `json:"archived"`
+		Rollover  *string `json:"rollover"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	okRepo, err := h.Repo.Update(c, uid, id, req.Title)
+	update := repository.CategoryUpdate{Title: req.Title, Icon: req.Icon, SortOrder: req.SortOrder, Archived: req.Archived, Rollover: req.Rollover}
+	if req.Color != nil {
+		color, err := repository.NormalizeColor(*req.Color)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		update.Color = &color
+	}
+	if req.Icon != nil && !repository.ValidIcon(*req.Icon) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": repository.ErrInvalidIcon.Error()})
+		return
+	}
+	if req.Rollover != nil && !repository.ValidRollover(*req.Rollover) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": repository.ErrInvalidRollover.Error()})
+		return
+	}
+	if req.ParentID != nil {
+		update.SetParent = true
+		if *req.ParentID != "" {
+			parentID, err := uuid.Parse(*req.ParentID)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid parent id"})
+				return
+			}
+			update.ParentID = &parentID
+		}
+	}
+
+	okRepo, err := h.Repo.Update(c, uid, id, update)
 	if err != nil {
+		if status, ok := categoryErrorStatus(err); ok {
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -99,7 +210,8 @@ func (h *CategoryHandler) Update(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Category updated"})
 }
 
-// Delete category
+// Delete category, ?reassign_to=<id> untuk memindahkan expense & jadwal berulangnya dulu.
+// Subkategori naik satu tingkat.
 func (h *CategoryHandler) Delete(c *gin.Context) {
 	userIDVal, _ := c.Get("user_id")
 	uid, ok := userIDVal.(uuid.UUID)
@@ -115,14 +227,136 @@ func (h *CategoryHandler) Delete(c *gin.Context) {
 		return
 	}
 
-	okRepo, err := h.Repo.Delete(c, uid, id)
+	var reassignTo *uuid.UUID
+	if s := c.Query("reassign_to"); s != "" {
+		target, err := uuid.Parse(s)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid reassign_to category id"})
+			return
+		}
+		reassignTo = &target
+	}
+
+	removal, err := h.Repo.Delete(c.Request.Context(), uid, id, reassignTo)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		categoryRemoveError(c, err)
 		return
 	}
-	if !okRepo {
+	if removal == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Category deleted", "result": removal})
+}
+
+// 📌 Merge: gabungkan kategori :id ke {"into": "<id>"}, lalu :id dihapus
+func (h *CategoryHandler) Merge(c *gin.Context) {
+	userIDVal, _ := c.Get("user_id")
+	uid, ok := userIDVal.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid category id"})
+		return
+	}
+
+	var req struct {
+		Into string `json:"into" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	into, err := uuid.Parse(req.Into)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid into category id"})
+		return
+	}
+
+	removal, err := h.Repo.Merge(c.Request.Context(), uid, id, into)
+	if err != nil {
+		categoryRemoveError(c, err)
+		return
+	}
+	if removal == nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "Category deleted"})
+	c.JSON(http.StatusOK, gin.H{"message": "Category merged", "result": removal})
+}
+
+// categoryRemoveError response untuk error hapus / merge kategori
+func categoryRemoveError(c *gin.Context, err error) {
+	var inUse *repository.CategoryInUseError
+	switch {
+	case errors.As(err, &inUse):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":                   inUse.Error(),
+			"expense_count":           inUse.Expenses,
+			"deleted_expense_count":   inUse.DeletedExpenses,
+			"recurring_expense_count": inUse.RecurringExpenses,
+			"budget_count":            inUse.Budgets,
+			"allocation_count":        inUse.Allocations,
+		})
+	case errors.Is(err, repository.ErrTargetNotFound), errors.Is(err, repository.ErrTargetIsSelf):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, repository.ErrMergeIntoDescendant), errors.Is(err, repository.ErrSubcategoryConflict),
+		errors.Is(err, repository.ErrBudgetConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		if status, ok := categoryErrorStatus(err); ok {
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// categoryErrorStatus status HTTP untuk error validasi parent dari repo
+func categoryErrorStatus(err error) (int, bool) {
+	switch {
+	case errors.Is(err, repository.ErrParentNotFound):
+		return http.StatusBadRequest, true
+	case errors.Is(err, repository.ErrCategoryCycle), errors.Is(err, repository.ErrCategoryTooDeep):
+		return http.StatusConflict, true
+	}
+	return 0, false
+}
+
+// buildCategoryTree susun list datar (sudah urut sort_order & judul) jadi tree, kategori yang parent-nya
+// tidak ketemu ditaruh di level atas supaya tidak hilang
+func buildCategoryTree(categories []models.Category, counts map[uuid.UUID]int64) []*categoryNode {
+	nodes := make(map[uuid.UUID]*categoryNode, len(categories))
+	for _, cat := range categories {
+		nodes[cat.ID] = &categoryNode{Category: cat, ExpenseCount: counts[cat.ID], Children: []*categoryNode{}}
+	}
+
+	roots := []*categoryNode{}
+	for _, cat := range categories {
+		n := nodes[cat.ID]
+		if cat.ParentID != nil {
+			if parent, ok := nodes[*cat.ParentID]; ok {
+				parent.Children = append(parent.Children, n)
+				continue
+			}
+		}
+		roots = append(roots, n)
+	}
+
+	var total func(n *categoryNode) int64
+	total = func(n *categoryNode) int64 {
+		n.TotalExpenseCount = n.ExpenseCount
+		for _, child := range n.Children {
+			n.TotalExpenseCount += total(child)
+		}
+		return n.TotalExpenseCount
+	}
+	for _, n := range roots {
+		total(n)
+	}
+	return roots
 }
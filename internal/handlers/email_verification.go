@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rifqi535/expense-tracker-api/internal/mailer"
+	"gorm.io/gorm"
+)
+
+const purposeVerifyEmail = "verify_email"
+
+// 📌 VerifyEmail: dipanggil dari link di email (GET ?token=...) atau dari client (POST {"token": ...})
+func (h *AuthHandler) VerifyEmail(c *gin.Context) {
+	p, uid, ok := h.signedLink(c, purposeVerifyEmail)
+	if !ok {
+		return
+	}
+
+	// email di link harus masih sama, link lama tidak berlaku setelah ganti email
+	okRepo, err := h.Users.MarkEmailVerified(c.Request.Context(), uid, p.Email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !okRepo {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errInvalidSignedToken.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "email verified"})
+}
+
+// 📌 ResendVerification: kirim ulang link verifikasi (ada cooldown)
+func (h *AuthHandler) ResendVerification(c *gin.Context) {
+	var req struct {
+		Email string `json:"email"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.Email == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "email is required"})
+		return
+	}
+
+	resp := gin.H{"message": "if the email is registered and not verified yet, a new link has been sent"}
+
+	ctx := c.Request.Context()
+	user, err := h.Users.GetByEmail(ctx, req.Email)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusOK, resp)
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if user.EmailVerifiedAt != nil {
+		c.JSON(http.StatusOK, resp)
+		return
+	}
+
+	claimed, err := h.Users.ClaimVerificationResend(ctx, user.ID, h.Cfg.EmailVerificationCooldown)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !claimed {
+		retryAfter := h.Cfg.EmailVerificationCooldown
+		if user.VerificationSentAt != nil {
+			retryAfter = time.Until(user.VerificationSentAt.Add(h.Cfg.EmailVerificationCooldown))
+		}
+		seconds := int(math.Ceil(retryAfter.Seconds()))
+		if seconds < 1 {
+			seconds = 1
+		}
+		c.Header("Retry-After", fmt.Sprint(seconds))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "please wait before requesting another email", "retry_after": seconds})
+		return
+	}
+
+	if err := h.sendVerificationEmail(ctx, user.ID, user.Email); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to send email"})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// sendVerificationEmail bikin link bertanda tangan lalu kirim lewat Mailer
+func (h *AuthHandler) sendVerificationEmail(ctx context.Context, userID uuid.UUID, email string) error {
+	token, err := signPayload([]byte(h.Cfg.EmailVerificationSecret), signedPayload{
+		Purpose: purposeVerifyEmail,
+		UserID:  userID.String(),
+		Email:   email,
+		Exp:     time.Now().Add(h.Cfg.EmailVerificationTTL).Unix(),
+	})
+	if err != nil {
+		return err
+	}
+
+	link := h.Cfg.AppBaseURL + "/auth/verify-email?token=" + url.QueryEscape(token)
+	return h.Mailer.Send(ctx, mailer.Message{
+		To:      email,
+		Subject: "Verify your email",
+		Body: fmt.Sprintf("Welcome to Expense Tracker!\n\n"+
+			"Please confirm your email address by opening this link within %s:\n%s\n", h.Cfg.EmailVerificationTTL, link),
+	})
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rifqi535/expense-tracker-api/internal/fx"
+	"github.com/rifqi535/expense-tracker-api/internal/models"
+	"github.com/rifqi535/expense-tracker-api/internal/money"
+	"github.com/rifqi535/expense-tracker-api/internal/repository"
+)
+
+// batas ukuran body import kurs
+const maxRatesUpload = 5 << 20
+
+type ExchangeRateHandler struct {
+	Repo *repository.ExchangeRateRepo
+}
+
+func NewExchangeRateHandler(repo *repository.ExchangeRateRepo) *ExchangeRateHandler {
+	return &ExchangeRateHandler{Repo: repo}
+}
+
+// 📌 List: kurs tersimpan (?base=, ?quote=, ?from=, ?to= YYYY-MM-DD)
+func (h *ExchangeRateHandler) List(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 500 {
+		limit = 50
+	}
+	offset := (page - 1) * limit
+
+	var from, to *time.Time
+	if s := c.Query("from"); s != "" {
+		t, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from date, use YYYY-MM-DD"})
+			return
+		}
+		from = &t
+	}
+	if s := c.Query("to"); s != "" {
+		t, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to date, use YYYY-MM-DD"})
+			return
+		}
+		to = &t
+	}
+
+	base := money.NormalizeCurrency(c.Query("base"))
+	quote := money.NormalizeCurrency(c.Query("quote"))
+	rates, total, err := h.Repo.List(c.Request.Context(), base, quote, from, to, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"page":  page,
+		"limit": limit,
+		"total": total,
+		"rates": rates,
+	})
+}
+
+// 📌 Upsert: import kurs, body JSON array atau CSV (Content-Type: text/csv)
+func (h *ExchangeRateHandler) Upsert(c *gin.Context) {
+	body := http.MaxBytesReader(c.Writer, c.Request.Body, maxRatesUpload)
+
+	var (
+		rates []models.ExchangeRate
+		err   error
+	)
+	if strings.HasPrefix(c.ContentType(), "text/csv") {
+		rates, err = fx.ParseCSV(body, "admin")
+	} else {
+		rates, err = fx.ParseJSON(body, "admin")
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(rates) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "no rates given"})
+		return
+	}
+
+	n, err := h.Repo.Upsert(c.Request.Context(), rates)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Exchange rates saved", "count": n})
+}
+
+// 📌 Delete: hapus satu kurs
+func (h *ExchangeRateHandler) Delete(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid exchange rate id"})
+		return
+	}
+
+	okRepo, err := h.Repo.Delete(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !okRepo {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Exchange rate deleted"})
+}
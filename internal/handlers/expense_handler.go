@@ -1,22 +1,41 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/rifqi535/expense-tracker-api/internal/calendar"
+	"github.com/rifqi535/expense-tracker-api/internal/fx"
 	"github.com/rifqi535/expense-tracker-api/internal/models"
+	"github.com/rifqi535/expense-tracker-api/internal/money"
 	"github.com/rifqi535/expense-tracker-api/internal/repository"
+	"gorm.io/gorm"
 )
 
 type ExpenseHandler struct {
-	Repo *repository.ExpenseRepo
+	Repo  *repository.ExpenseRepo
+	Users *repository.UserRepo
+	Rates *repository.ExchangeRateRepo
+	// fallback kalau mata uang user tidak ketemu
+	Currency string
 }
 
-func NewExpenseHandler(repo *repository.ExpenseRepo) *ExpenseHandler {
-	return &ExpenseHandler{Repo: repo}
+func NewExpenseHandler(repo *repository.ExpenseRepo, users *repository.UserRepo, rates *repository.ExchangeRateRepo, currency string) *ExpenseHandler {
+	return &ExpenseHandler{Repo: repo, Users: users, Rates: rates, Currency: currency}
+}
+
+// expenseResponse expense + nilainya dalam mata uang dasar user
+type expenseResponse struct {
+	models.Expense
+	ConvertedAmount *money.Money `json:"converted_amount"` // null kalau kurs tidak tersedia
+	BaseCurrency    string       `json:"base_currency"`
+	ExchangeRate    *money.Rate  `json:"exchange_rate"`
 }
 
 func (h *ExpenseHandler) List(c *gin.Context) {
@@ -49,39 +68,90 @@ func (h *ExpenseHandler) List(c *gin.Context) {
 	}
 
 	// --- FILTERS ---
-	var (
-		categoryID *uuid.UUID
-		startDate  *time.Time
-		endDate    *time.Time
-	)
+	var filter repository.ExpenseFilter
 
+	// category_id ikut subkategorinya, include_subcategories=false untuk kategori itu saja
 	if cid := c.Query("category_id"); cid != "" {
 		if parsed, err := uuid.Parse(cid); err == nil {
-			categoryID = &parsed
+			filter.CategoryID = &parsed
+			filter.ExactCategory = c.Query("include_subcategories") == "false"
 		}
 	}
+
+	// ?tags=a,b&tag_match=any|all
+	if t := c.Query("tags"); t != "" {
+		tags, err := repository.NormalizeTags(strings.Split(t, ","))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		filter.Tags = tags
+	}
+	switch c.DefaultQuery("tag_match", "any") {
+	case "any":
+	case "all":
+		filter.MatchAllTags = true
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "tag_match must be any or all"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	prefs, err := h.prefs(ctx, uid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// tanggal = satu hari penuh di zona waktu user
 	if s := c.Query("start_date"); s != "" {
-		if t, err := time.Parse("2006-01-02", s); err == nil {
-			startDate = &t
+		if t, err := prefs.Calendar.ParseDate(s); err == nil {
+			filter.StartDate = &t
 		}
 	}
 	if e := c.Query("end_date"); e != "" {
-		if t, err := time.Parse("2006-01-02", e); err == nil {
-			endDate = &t
+		if t, err := prefs.Calendar.ParseDate(e); err == nil {
+			// end_date ikut dihitung satu hari penuh (di zona waktu user)
+			next := prefs.Calendar.Next(t, calendar.Day)
+			filter.EndDate = &next
 		}
 	}
 
+	// --- MATA UANG TUJUAN ---
+	base, ok := convertTo(c, prefs.Currency)
+	if !ok {
+		return
+	}
+
 	// --- QUERY KE REPO ---
-	expenses, err := h.Repo.List(c, uid, categoryID, startDate, endDate, limit, offset, sortBy, order)
+	expenses, err := h.Repo.List(c, uid, filter, limit, offset, sortBy, order)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	// kurs diambil per tanggal transaksi (di zona transaksinya)
+	conv := fx.NewConverter(h.Rates)
+	resp := make([]expenseResponse, 0, len(expenses))
+	for _, e := range expenses {
+		e.SpentAt = e.SpentAt.In(spentLocation(e.SpentTZ, prefs.Calendar.Location))
+		item := expenseResponse{Expense: e, BaseCurrency: base}
+		converted, rate, found, err := conv.Convert(ctx, e.Amount, e.Currency, base, e.SpentAt)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if found {
+			item.ConvertedAmount = &converted
+			item.ExchangeRate = &rate
+		}
+		resp = append(resp, item)
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"page":     page,
 		"limit":    limit,
-		"expenses": expenses,
+		"expenses": resp,
 	})
 }
 
@@ -95,29 +165,64 @@ func (h *ExpenseHandler) Create(c *gin.Context) {
 	}
 
 	var req struct {
-		Title       string  `json:"title"`
-		Amount      float64 `json:"amount"`
-		CategoryID  string  `json:"category_id"`
-		Description string  `json:"description"`
+		Title       string      `json:"title"`
+		Amount      money.Money `json:"amount"`
+		Currency    string      `json:"currency"` // opsional, default mata uang user
+		SpentAt     string      `json:"spent_at"` // opsional, default sekarang
+		Timezone    string      `json:"timezone"` // opsional, zona IANA mis. "Asia/Jakarta", default zona user
+		CategoryID  string      `json:"category_id"`
+		Description string      `json:"description"`
+		Tags        []string    `json:"tags"` // nama tag, yang belum ada otomatis dibuat
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	prefs, err := h.prefs(c.Request.Context(), uid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	currency := money.NormalizeCurrency(req.Currency)
+	if currency == "" {
+		currency = prefs.Currency
+	}
+	if err := validateAmount(req.Amount, currency); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	spentAt, spentTZ, err := parseSpentAt(req.SpentAt, req.Timezone, prefs.Calendar.Location)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	categoryID, err := uuid.Parse(req.CategoryID)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid category id"})
 		return
 	}
 
+	tags, err := repository.NormalizeTags(req.Tags)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	exp := &models.Expense{
 		ID:          uuid.New(),
 		Title:       req.Title,
 		Amount:      req.Amount,
+		Currency:    currency,
+		SpentAt:     spentAt,
+		SpentTZ:     spentTZ,
 		CategoryID:  categoryID,
 		UserID:      uid,
 		Description: &req.Description,
+		Tags:        tags,
 	}
 	if err := h.Repo.Create(c, exp); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -144,31 +249,79 @@ func (h *ExpenseHandler) Update(c *gin.Context) {
 	}
 
 	var req struct {
-		Title       string  `json:"title"`
-		Description string  `json:"description"`
-		Amount      float64 `json:"amount"`
-		CategoryID  string  `json:"category_id"`
+		Title       string      `json:"title"`
+		Description string      `json:"description"`
+		Amount      money.Money `json:"amount"`
+		Currency    string      `json:"currency"` // opsional, default tetap mata uang lama
+		SpentAt     string      `json:"spent_at"` // opsional, default tetap tanggal lama
+		Timezone    string      `json:"timezone"` // hanya dipakai kalau spent_at dikirim
+		CategoryID  string      `json:"category_id"`
+		Tags        []string    `json:"tags"` // opsional, tidak dikirim = tag tetap, [] = hapus semua tag
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	existing, err := h.Repo.GetByID(c.Request.Context(), uid, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if existing == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+
+	currency := money.NormalizeCurrency(req.Currency)
+	if currency == "" {
+		currency = existing.Currency
+	}
+	if err := validateAmount(req.Amount, currency); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	spentAt, spentTZ := existing.SpentAt, existing.SpentTZ
+	if req.SpentAt != "" {
+		prefs, err := h.prefs(c.Request.Context(), uid)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if spentAt, spentTZ, err = parseSpentAt(req.SpentAt, req.Timezone, prefs.Calendar.Location); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	} else if req.Timezone != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "timezone requires spent_at"})
+		return
+	}
+
 	categoryID, err := uuid.Parse(req.CategoryID)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid category id"})
 		return
 	}
 
-	okRepo, err := h.Repo.Update(
-		c,
-		uid,
-		id,
-		req.Title,
-		req.Description,
-		req.Amount,
-		categoryID,
-	)
+	var tags []string
+	if req.Tags != nil {
+		if tags, err = repository.NormalizeTags(req.Tags); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	okRepo, err := h.Repo.Update(c, uid, id, &models.Expense{
+		Title:       req.Title,
+		Description: &req.Description,
+		Amount:      req.Amount,
+		Currency:    currency,
+		SpentAt:     spentAt,
+		SpentTZ:     spentTZ,
+		CategoryID:  categoryID,
+		Tags:        tags,
+	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -209,3 +362,93 @@ func (h *ExpenseHandler) Delete(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "Expense deleted"})
 }
+
+// layout spent_at tanpa offset, dibaca di zona timezone (atau zona user)
+var spentAtLayouts = []string{"2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02"}
+
+// parseSpentAt baca spent_at (RFC3339 atau waktu lokal) + timezone IANA opsional.
+// Kosong = sekarang. Waktu dengan offset tetap dihormati, timezone cuma menentukan tampilan.
+func parseSpentAt(value, tz string, userLoc *time.Location) (time.Time, *string, error) {
+	loc := userLoc
+	var spentTZ *string
+	if tz = strings.TrimSpace(tz); tz != "" {
+		l, err := calendar.LoadLocation(tz)
+		if err != nil {
+			return time.Time{}, nil, err
+		}
+		loc = l
+		spentTZ = &tz
+	}
+
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Now().In(loc), spentTZ, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.In(loc), spentTZ, nil
+	}
+	for _, layout := range spentAtLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, spentTZ, nil
+		}
+	}
+	return time.Time{}, nil, errors.New("invalid spent_at, use RFC3339 or YYYY-MM-DD[THH:MM[:SS]]")
+}
+
+// spentLocation zona tampilan spent_at, zona user kalau kosong / tidak dikenal
+func spentLocation(tz *string, userLoc *time.Location) *time.Location {
+	if tz == nil {
+		return userLoc
+	}
+	if loc, err := calendar.LoadLocation(*tz); err == nil {
+		return loc
+	}
+	return userLoc
+}
+
+// expensePrefs preferensi user yang dipakai expense: mata uang dasar + kalender
+type expensePrefs struct {
+	Currency string
+	Calendar calendar.Calendar
+}
+
+// prefs ambil preferensi user, fallback ke default server kalau user tidak ketemu
+func (h *ExpenseHandler) prefs(ctx context.Context, uid uuid.UUID) (*expensePrefs, error) {
+	p, err := h.Users.GetPreferences(ctx, uid)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &expensePrefs{Currency: h.Currency, Calendar: calendar.New(time.UTC, time.Monday)}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	loc, err := calendar.LoadLocation(p.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	return &expensePrefs{Currency: p.Currency, Calendar: calendar.New(loc, time.Weekday(p.WeekStart))}, nil
+}
+
+// convertTo mata uang tujuan dari ?convert_to=, default mata uang dasar user
+func convertTo(c *gin.Context, def string) (string, bool) {
+	base := money.NormalizeCurrency(c.Query("convert_to"))
+	if base == "" {
+		return def, true
+	}
+	if !money.ValidCurrency(base) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid convert_to currency"})
+		return "", false
+	}
+	return base, true
+}
+
+// validateAmount mata uang harus valid, amount positif dan digit desimalnya sesuai mata uang
+func validateAmount(amount money.Money, currency string) error {
+	if !money.ValidCurrency(currency) {
+		return errors.New("invalid currency")
+	}
+	if !amount.IsPositive() {
+		return errors.New("amount must be greater than 0")
+	}
+	return amount.CheckCurrency(currency)
+}
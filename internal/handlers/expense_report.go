@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rifqi535/expense-tracker-api/internal/calendar"
+	"github.com/rifqi535/expense-tracker-api/internal/fx"
+	"github.com/rifqi535/expense-tracker-api/internal/money"
+	"github.com/rifqi535/expense-tracker-api/internal/repository"
+)
+
+// maxReportBuckets batas jumlah bucket per request (mis. ±1 tahun harian)
+const maxReportBuckets = 366
+
+// jumlah bucket default kalau start_date tidak dikirim
+var defaultReportBuckets = map[string]int{calendar.Day: 30, calendar.Week: 12, calendar.Month: 12}
+
+type currencyTotal struct {
+	Currency string      `json:"currency"`
+	Count    int64       `json:"count"`
+	Total    money.Money `json:"total"`
+}
+
+// reportBucket satu periode laporan, start & end tanggal lokal (inklusif)
+type reportBucket struct {
+	Start          string          `json:"start"`
+	End            string          `json:"end"`
+	Count          int64           `json:"count"`
+	Totals         []currencyTotal `json:"totals"`
+	ConvertedTotal *money.Money    `json:"converted_total"` // null kalau ada kurs yang tidak tersedia
+	MissingRates   bool            `json:"missing_rates"`
+
+	converted money.Money
+	byCur     map[string]int
+}
+
+func newBucket(start, end string) *reportBucket {
+	return &reportBucket{Start: start, End: end, Totals: []currencyTotal{}, byCur: map[string]int{}}
+}
+
+func (b *reportBucket) add(currency string, count int64, total money.Money) {
+	b.Count += count
+	i, ok := b.byCur[currency]
+	if !ok {
+		i = len(b.Totals)
+		b.byCur[currency] = i
+		b.Totals = append(b.Totals, currencyTotal{Currency: currency})
+	}
+	b.Totals[i].Count += count
+	b.Totals[i].Total = b.Totals[i].Total.Add(total)
+}
+
+func (b *reportBucket) finish() {
+	if !b.MissingRates {
+		total := b.converted
+		b.ConvertedTotal = &total
+	}
+}
+
+// 📌 Report: total expense per hari / minggu / bulan di zona waktu user
+// ?group_by=day|week|month&start_date=&end_date=&category_id=&include_subcategories=&convert_to=
+func (h *ExpenseHandler) Report(c *gin.Context) {
+	userIDVal, _ := c.Get("user_id")
+	uid, ok := userIDVal.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	unit := strings.ToLower(c.DefaultQuery("group_by", calendar.Day))
+	if !calendar.ValidUnit(unit) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "group_by must be day, week or month"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	prefs, err := h.prefs(ctx, uid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	cal := prefs.Calendar
+
+	// week_start di query menimpa preferensi user (mis. untuk tampilan sementara)
+	if ws := c.Query("week_start"); ws != "" {
+		day, err := calendar.ParseWeekday(ws)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		cal.WeekStart = day
+	}
+
+	base, ok := convertTo(c, prefs.Currency)
+	if !ok {
+		return
+	}
+
+	var filter repository.ExpenseFilter
+	if cid := c.Query("category_id"); cid != "" {
+		parsed, err := uuid.Parse(cid)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid category id"})
+			return
+		}
+		filter.CategoryID = &parsed
+		filter.ExactCategory = c.Query("include_subcategories") == "false"
+	}
+
+	// --- RENTANG TANGGAL (lokal, end_date inklusif) ---
+	lastDay := cal.StartOfDay(time.Now())
+	if s := c.Query("end_date"); s != "" {
+		if lastDay, err = cal.ParseDate(s); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid end_date, use YYYY-MM-DD"})
+			return
+		}
+	}
+	to := cal.Next(lastDay, calendar.Day)
+
+	var from time.Time
+	if s := c.Query("start_date"); s != "" {
+		if from, err = cal.ParseDate(s); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid start_date, use YYYY-MM-DD"})
+			return
+		}
+	} else {
+		from = cal.Truncate(lastDay, unit)
+		for i := 1; i < defaultReportBuckets[unit]; i++ {
+			from = cal.Truncate(from.AddDate(0, 0, -1), unit)
+		}
+	}
+	if !from.Before(to) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "start_date must not be after end_date"})
+		return
+	}
+
+	// --- BUCKET KOSONG UNTUK SELURUH RENTANG ---
+	var buckets []*reportBucket
+	index := map[string]*reportBucket{}
+	for start := cal.Truncate(from, unit); start.Before(to); start = cal.Next(start, unit) {
+		if len(buckets) == maxReportBuckets {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "date range is too large for this group_by"})
+			return
+		}
+		end := cal.Next(start, unit).AddDate(0, 0, -1)
+		b := newBucket(start.Format("2006-01-02"), end.Format("2006-01-02"))
+		buckets = append(buckets, b)
+		index[b.Start] = b
+	}
+
+	filter.StartDate, filter.EndDate = &from, &to
+	rows, err := h.Repo.DailyTotals(ctx, uid, cal.Location.String(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// konversi per hari supaya kurs sesuai tanggal transaksi
+	conv := fx.NewConverter(h.Rates)
+	summary := newBucket(from.Format("2006-01-02"), lastDay.Format("2006-01-02"))
+	for _, row := range rows {
+		day, err := cal.ParseDate(row.Day)
+		if err != nil {
+			continue
+		}
+		b := index[cal.Truncate(day, unit).Format("2006-01-02")]
+		if b == nil {
+			continue
+		}
+
+		converted, _, found, err := conv.Convert(ctx, row.Total, row.Currency, base, day)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		for _, target := range []*reportBucket{b, summary} {
+			target.add(row.Currency, row.Count, row.Total)
+			if found {
+				target.converted = target.converted.Add(converted)
+			} else {
+				target.MissingRates = true
+			}
+		}
+	}
+	for _, b := range buckets {
+		b.finish()
+	}
+	summary.finish()
+
+	c.JSON(http.StatusOK, gin.H{
+		"group_by":      unit,
+		"timezone":      cal.Location.String(),
+		"week_start":    strings.ToLower(cal.WeekStart.String()),
+		"base_currency": base,
+		"start_date":    summary.Start,
+		"end_date":      summary.End,
+		"buckets":       buckets,
+		"total":         summary,
+	})
+}
@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rifqi535/expense-tracker-api/internal/config"
+	"github.com/rifqi535/expense-tracker-api/internal/export"
+	"github.com/rifqi535/expense-tracker-api/internal/models"
+	"github.com/rifqi535/expense-tracker-api/internal/repository"
+)
+
+const purposeDownloadExport = "download_export"
+
+type ExportHandler struct {
+	Repo   *repository.DataExportRepo
+	Worker *export.Worker
+	Cfg    *config.Config
+}
+
+func NewExportHandler(repo *repository.DataExportRepo, worker *export.Worker, cfg *config.Config) *ExportHandler {
+	return &ExportHandler{Repo: repo, Worker: worker, Cfg: cfg}
+}
+
+// exportResponse status export + link download (kalau sudah siap)
+type exportResponse struct {
+	models.DataExport
+	DownloadURL       string     `json:"download_url,omitempty"`
+	DownloadExpiresAt *time.Time `json:"download_url_expires_at,omitempty"`
+}
+
+// 📌 Create: minta export baru, diproses di background
+func (h *ExportHandler) Create(c *gin.Context) {
+	userIDVal, _ := c.Get("user_id")
+	uid, ok := userIDVal.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	active, err := h.Repo.HasActive(ctx, uid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if active {
+		c.JSON(http.StatusConflict, gin.H{"error": "an export is already in progress"})
+		return
+	}
+
+	e := &models.DataExport{
+		ID:        uuid.New(),
+		UserID:    uid,
+		Status:    models.ExportPending,
+		CreatedAt: time.Now(),
+	}
+	if err := h.Repo.Create(ctx, e); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	h.Worker.Notify()
+
+	c.JSON(http.StatusAccepted, exportResponse{DataExport: *e})
+}
+
+// 📌 List: riwayat export milik user
+func (h *ExportHandler) List(c *gin.Context) {
+	userIDVal, _ := c.Get("user_id")
+	uid, ok := userIDVal.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	exports, err := h.Repo.ListByUser(c.Request.Context(), uid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp := make([]exportResponse, 0, len(exports))
+	for _, e := range exports {
+		r, err := h.response(e)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		resp = append(resp, r)
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// 📌 Get: status satu export, download_url baru dibuat tiap kali dipanggil
+func (h *ExportHandler) Get(c *gin.Context) {
+	userIDVal, _ := c.Get("user_id")
+	uid, ok := userIDVal.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid export id"})
+		return
+	}
+
+	e, err := h.Repo.GetByID(c.Request.Context(), uid, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if e == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+
+	resp, err := h.response(*e)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// 📌 Download: dipanggil dari signed URL, tidak butuh Authorization header
+func (h *ExportHandler) Download(c *gin.Context) {
+	p, err := verifyPayload([]byte(h.Cfg.EmailVerificationSecret), c.Query("token"), purposeDownloadExport)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	uid, errUser := uuid.Parse(p.UserID)
+	id, errID := uuid.Parse(p.Ref)
+	if errUser != nil || errID != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": errInvalidSignedToken.Error()})
+		return
+	}
+
+	e, err := h.Repo.GetByID(c.Request.Context(), uid, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if e == nil || e.Status != models.ExportReady {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	if _, err := os.Stat(e.FilePath); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.FileAttachment(e.FilePath, "expense-tracker-export-"+e.CreatedAt.Format("20060102")+".zip")
+}
+
+func (h *ExportHandler) response(e models.DataExport) (exportResponse, error) {
+	resp := exportResponse{DataExport: e}
+	if e.Status != models.ExportReady || e.ExpiresAt == nil {
+		return resp, nil
+	}
+
+	// link tidak boleh hidup lebih lama dari file-nya
+	exp := time.Now().Add(h.Cfg.ExportLinkTTL)
+	if e.ExpiresAt.Before(exp) {
+		exp = *e.ExpiresAt
+	}
+
+	token, err := signPayload([]byte(h.Cfg.EmailVerificationSecret), signedPayload{
+		Purpose: purposeDownloadExport,
+		UserID:  e.UserID.String(),
+		Ref:     e.ID.String(),
+		Exp:     exp.Unix(),
+	})
+	if err != nil {
+		return resp, err
+	}
+
+	resp.DownloadURL = h.Cfg.AppBaseURL + "/exports/download?token=" + url.QueryEscape(token)
+	resp.DownloadExpiresAt = &exp
+	return resp, nil
+}
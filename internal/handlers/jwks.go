@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rifqi535/expense-tracker-api/internal/token"
+)
+
+type JWKSHandler struct {
+	JWT *token.Service
+}
+
+func NewJWKSHandler(jwt *token.Service) *JWKSHandler {
+	return &JWKSHandler{JWT: jwt}
+}
+
+// Get public keys untuk verifikasi JWT (dipakai service lain)
+func (h *JWKSHandler) Get(c *gin.Context) {
+	// boleh di-cache sebentar, rotasi kunci selalu didahului penambahan public key baru
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.JWT.JWKS())
+}
@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rifqi535/expense-tracker-api/internal/mailer"
+	"github.com/rifqi535/expense-tracker-api/internal/models"
+)
+
+const (
+	purposeUnlockAccount = "unlock_account"
+	unlockLinkTTL        = 24 * time.Hour
+)
+
+// checkIPThrottle tolak request kalau IP ini sudah terlalu banyak gagal login.
+// Return false kalau response 429 sudah ditulis.
+func (h *AuthHandler) checkIPThrottle(c *gin.Context) bool {
+	if h.Cfg.LoginIPMaxFailures <= 0 {
+		return true
+	}
+
+	ctx := c.Request.Context()
+	since := time.Now().Add(-h.Cfg.LoginIPWindow)
+
+	count, last, err := h.Events.CountFailuresByIP(ctx, c.ClientIP(), since)
+	if err != nil {
+		// jangan sampai login mati total gara-gara query ini gagal
+		log.Println("❌ gagal cek login per IP:", err)
+		return true
+	}
+	if count < int64(h.Cfg.LoginIPMaxFailures) {
+		return true
+	}
+
+	tooManyAttempts(c, time.Until(last.Add(h.Cfg.LoginIPWindow)))
+	return false
+}
+
+// checkAccountLock tolak login kalau akun sedang dikunci atau masih dalam jeda backoff.
+// Return false kalau response sudah ditulis.
+func (h *AuthHandler) checkAccountLock(c *gin.Context, user *models.User) bool {
+	now := time.Now()
+
+	if user.LockedUntil != nil && now.Before(*user.LockedUntil) {
+		seconds := retrySeconds(user.LockedUntil.Sub(now))
+		c.Header("Retry-After", strconv.Itoa(seconds))
+		c.JSON(http.StatusLocked, gin.H{
+			"error":       "account is temporarily locked, check your email to unlock it",
+			"retry_after": seconds,
+		})
+		return false
+	}
+	if user.LockedUntil != nil {
+		// kunci sudah habis: counter mulai dari nol lagi, kalau tidak satu kali gagal langsung dikunci ulang
+		if err := h.Users.ResetFailedLogins(c.Request.Context(), user.ID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return false
+		}
+		user.FailedLoginCount = 0
+		user.LockedUntil = nil
+	}
+
+	if wait := h.backoff(user.FailedLoginCount); wait > 0 && user.LastFailedLoginAt != nil {
+		if next := user.LastFailedLoginAt.Add(wait); now.Before(next) {
+			tooManyAttempts(c, next.Sub(now))
+			return false
+		}
+	}
+	return true
+}
+
+// backoff jeda minimal sebelum percobaan berikutnya: 1s, 2s, 4s, ... (maks LoginBackoffMax)
+func (h *AuthHandler) backoff(failed int) time.Duration {
+	over := failed - h.Cfg.LoginBackoffAfter
+	if over < 0 {
+		return 0
+	}
+	if over > 30 {
+		return h.Cfg.LoginBackoffMax
+	}
+
+	wait := time.Duration(math.Pow(2, float64(over))) * time.Second
+	if wait > h.Cfg.LoginBackoffMax {
+		return h.Cfg.LoginBackoffMax
+	}
+	return wait
+}
+
+// loginFailed catat gagal login, kunci akun (dan kirim link unlock) kalau sudah mencapai batas
+func (h *AuthHandler) loginFailed(c *gin.Context, user *models.User, method, reason string) {
+	ctx := c.Request.Context()
+	h.recordEvent(c, &user.ID, user.Email, models.LoginEventFailure, method, reason)
+
+	count, lockedUntil, err := h.Users.RegisterFailedLogin(ctx, user.ID, h.Cfg.LoginLockAfter, h.Cfg.LoginLockDuration)
+	if err != nil {
+		log.Println("❌ gagal catat login gagal:", err)
+		return
+	}
+
+	// checkAccountLock sudah menolak akun yang masih terkunci, jadi di sini artinya baru saja terkunci
+	if h.Cfg.LoginLockAfter > 0 && count >= h.Cfg.LoginLockAfter && lockedUntil != nil {
+		h.recordEvent(c, &user.ID, user.Email, models.LoginEventLockout, method, fmt.Sprintf("%d failed attempts", count))
+		if err := h.sendUnlockEmail(ctx, user, *lockedUntil); err != nil {
+			log.Println("❌ gagal kirim email unlock:", err)
+		}
+	}
+}
+
+// loginSucceeded reset counter gagal & catat event sukses
+func (h *AuthHandler) loginSucceeded(c *gin.Context, user *models.User, method string) {
+	if err := h.Users.ResetFailedLogins(c.Request.Context(), user.ID); err != nil {
+		log.Println("❌ gagal reset counter login:", err)
+	}
+	h.recordEvent(c, &user.ID, user.Email, models.LoginEventSuccess, method, "")
+}
+
+// recordEvent simpan ke login_events, gagal simpan cukup di-log
+func (h *AuthHandler) recordEvent(c *gin.Context, userID *uuid.UUID, email, event, method, reason string) {
+	err := h.Events.Create(c.Request.Context(), &models.LoginEvent{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Email:     email,
+		Event:     event,
+		Method:    method,
+		Reason:    reason,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		log.Println("❌ gagal simpan login event:", err)
+	}
+}
+
+// sendUnlockEmail kirim link bertanda tangan untuk membuka kunci akun lebih cepat
+func (h *AuthHandler) sendUnlockEmail(ctx context.Context, user *models.User, lockedUntil time.Time) error {
+	token, err := signPayload([]byte(h.Cfg.EmailVerificationSecret), signedPayload{
+		Purpose: purposeUnlockAccount,
+		UserID:  user.ID.String(),
+		Email:   user.Email,
+		Exp:     time.Now().Add(unlockLinkTTL).Unix(),
+	})
+	if err != nil {
+		return err
+	}
+
+	link := h.Cfg.AppBaseURL + "/auth/unlock?token=" + url.QueryEscape(token)
+	return h.Mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Your account has been locked",
+		Body: fmt.Sprintf("We locked your account after too many failed sign-in attempts.\n\n"+
+			"It will unlock automatically at %s. If it was you, you can unlock it now:\n%s\n\n"+
+			"If it wasn't you, consider resetting your password.", lockedUntil.Format(time.RFC1123), link),
+	})
+}
+
+// 📌 Unlock: buka kunci akun dari link di email
+func (h *AuthHandler) Unlock(c *gin.Context) {
+	p, uid, ok := h.signedLink(c, purposeUnlockAccount)
+	if !ok {
+		return
+	}
+
+	okRepo, err := h.Users.Unlock(c.Request.Context(), uid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !okRepo {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errInvalidSignedToken.Error()})
+		return
+	}
+
+	h.recordEvent(c, &uid, p.Email, models.LoginEventUnlock, "email", "")
+	c.JSON(http.StatusOK, gin.H{"message": "account unlocked"})
+}
+
+// 📌 ListLoginEvents: riwayat login user yang sedang login
+func (h *AuthHandler) ListLoginEvents(c *gin.Context) {
+	userIDVal, _ := c.Get("user_id")
+	uid, ok := userIDVal.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	events, err := h.Events.ListByUser(c.Request.Context(), uid, limit, (page-1)*limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"page":   page,
+		"limit":  limit,
+		"events": events,
+	})
+}
+
+func tooManyAttempts(c *gin.Context, wait time.Duration) {
+	seconds := retrySeconds(wait)
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":       "too many failed attempts, try again later",
+		"retry_after": seconds,
+	})
+}
+
+func retrySeconds(d time.Duration) int {
+	seconds := int(math.Ceil(d.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	return seconds
+}
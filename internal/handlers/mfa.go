@@ -0,0 +1,287 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rifqi535/expense-tracker-api/internal/models"
+	"github.com/rifqi535/expense-tracker-api/internal/totp"
+	"gorm.io/gorm"
+)
+
+const recoveryCodeCount = 10
+
+// 📌 EnrollMFA: bikin secret TOTP baru (belum aktif sampai dikonfirmasi)
+func (h *AuthHandler) EnrollMFA(c *gin.Context) {
+	userIDVal, _ := c.Get("user_id")
+	uid, ok := userIDVal.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	user, err := h.Users.GetByID(ctx, uid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if user.TOTPEnabledAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "mfa already enabled"})
+		return
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate secret"})
+		return
+	}
+	if _, err := h.MFA.SetPendingSecret(ctx, uid, secret); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"secret":      secret,
+		"otpauth_uri": totp.URI(h.Cfg.MFAIssuer, user.Email, secret),
+		"message":     "scan the uri with your authenticator app, then confirm with a code",
+	})
+}
+
+// 📌 ConfirmMFA: aktifkan MFA setelah user membuktikan authenticator-nya jalan
+func (h *AuthHandler) ConfirmMFA(c *gin.Context) {
+	userIDVal, _ := c.Get("user_id")
+	uid, ok := userIDVal.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	var req struct {
+		Code string `json:"code"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.Code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "code is required"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	user, err := h.Users.GetByID(ctx, uid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if user.TOTPEnabledAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "mfa already enabled"})
+		return
+	}
+	if user.TOTPSecret == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "call /auth/mfa/enroll first"})
+		return
+	}
+
+	step, valid := totp.Validate(*user.TOTPSecret, req.Code, time.Now())
+	if !valid {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid code"})
+		return
+	}
+
+	codes, rows, err := newRecoveryCodes(uid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate recovery codes"})
+		return
+	}
+
+	enabled, err := h.MFA.Enable(ctx, uid, step, rows)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !enabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "mfa already enabled"})
+		return
+	}
+
+	// recovery codes cuma ditampilkan sekali ini
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "mfa enabled",
+		"recovery_codes": codes,
+	})
+}
+
+// 📌 DisableMFA: butuh password + kode TOTP / recovery code
+func (h *AuthHandler) DisableMFA(c *gin.Context) {
+	userIDVal, _ := c.Get("user_id")
+	uid, ok := userIDVal.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	var req struct {
+		Password     string `json:"password"`
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	user, err := h.Users.GetByID(ctx, uid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if user.TOTPEnabledAt == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "mfa is not enabled"})
+		return
+	}
+	if !checkPasswordHash(req.Password, user.PasswordHash) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid password"})
+		return
+	}
+
+	ok, err = h.checkSecondFactor(ctx, user, req.Code, req.RecoveryCode)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid code"})
+		return
+	}
+
+	if err := h.MFA.Disable(ctx, uid); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "mfa disabled"})
+}
+
+// 📌 VerifyMFA: langkah kedua login, tukar mfa_token + kode dengan token normal
+func (h *AuthHandler) VerifyMFA(c *gin.Context) {
+	var req struct {
+		MFAToken     string `json:"mfa_token"`
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.MFAToken == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "mfa_token is required"})
+		return
+	}
+
+	if !h.checkIPThrottle(c) {
+		return
+	}
+
+	uid, err := h.JWT.ParseMFAPendingToken(req.MFAToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	user, err := h.Users.GetByID(ctx, uid)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid mfa token"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if user.TOTPEnabledAt == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "mfa is not enabled"})
+		return
+	}
+	// tebak kode TOTP juga kena lockout yang sama dengan password
+	if !h.checkAccountLock(c, user) {
+		return
+	}
+
+	ok, err := h.checkSecondFactor(ctx, user, req.Code, req.RecoveryCode)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !ok {
+		h.loginFailed(c, user, "mfa", "invalid code")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid code"})
+		return
+	}
+
+	claims, err := h.accessClaims(user, uuid.New())
+	if err != nil {
+		h.recordEvent(c, &user.ID, user.Email, models.LoginEventFailure, "mfa", err.Error())
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	tokens, err := h.issueTokens(c, claims)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate token"})
+		return
+	}
+
+	h.loginSucceeded(c, user, "mfa")
+	c.JSON(http.StatusOK, tokens)
+}
+
+// checkSecondFactor cek kode TOTP (sekali pakai per time-step) atau recovery code
+func (h *AuthHandler) checkSecondFactor(ctx context.Context, user *models.User, code, recoveryCode string) (bool, error) {
+	if code != "" {
+		if user.TOTPSecret == nil {
+			return false, nil
+		}
+		step, valid := totp.Validate(*user.TOTPSecret, code, time.Now())
+		if !valid {
+			return false, nil
+		}
+		return h.MFA.ClaimStep(ctx, user.ID, step)
+	}
+
+	if recoveryCode != "" {
+		return h.MFA.UseRecoveryCode(ctx, user.ID, hashToken(normalizeRecoveryCode(recoveryCode)))
+	}
+
+	return false, nil
+}
+
+// newRecoveryCodes return kode mentah (buat user) + row yang disimpan (hash saja)
+func newRecoveryCodes(userID uuid.UUID) ([]string, []models.MFARecoveryCode, error) {
+	enc := base32.StdEncoding.WithPadding(base32.NoPadding)
+
+	codes := make([]string, 0, recoveryCodeCount)
+	rows := make([]models.MFARecoveryCode, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		b := make([]byte, 7)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		raw := strings.ToLower(enc.EncodeToString(b))[:10]
+		code := raw[:5] + "-" + raw[5:]
+
+		codes = append(codes, code)
+		rows = append(rows, models.MFARecoveryCode{
+			ID:        uuid.New(),
+			UserID:    userID,
+			CodeHash:  hashToken(raw),
+			CreatedAt: time.Now(),
+		})
+	}
+	return codes, rows, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rifqi535/expense-tracker-api/internal/notify"
+	"github.com/rifqi535/expense-tracker-api/internal/repository"
+)
+
+type NotificationHandler struct {
+	Repo *repository.NotificationRepo
+}
+
+func NewNotificationHandler(repo *repository.NotificationRepo) *NotificationHandler {
+	return &NotificationHandler{Repo: repo}
+}
+
+// 📌 List inbox notifikasi, ?unread=true cuma yang belum dibaca
+func (h *NotificationHandler) List(c *gin.Context) {
+	userIDVal, _ := c.Get("user_id")
+	uid, ok := userIDVal.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	unreadOnly := c.Query("unread") == "true"
+
+	list, err := h.Repo.List(c.Request.Context(), uid, unreadOnly, limit, (page-1)*limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	unread, err := h.Repo.UnreadCount(c.Request.Context(), uid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":         list,
+		"unread_count": unread,
+		"page":         page,
+		"limit":        limit,
+	})
+}
+
+// 📌 Read / Unread tandai satu notifikasi
+func (h *NotificationHandler) Read(c *gin.Context)   { h.mark(c, true) }
+func (h *NotificationHandler) Unread(c *gin.Context) { h.mark(c, false) }
+
+func (h *NotificationHandler) mark(c *gin.Context, read bool) {
+	userIDVal, _ := c.Get("user_id")
+	uid, ok := userIDVal.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user id"})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid notification id"})
+		return
+	}
+
+	okRepo, err := h.Repo.MarkRead(c.Request.Context(), uid, id, read)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !okRepo {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	if read {
+		c.JSON(http.StatusOK, gin.H{"message": "Notification marked as read"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Notification marked as unread"})
+}
+
+// 📌 ReadAll tandai semua notifikasi sudah dibaca
+func (h *NotificationHandler) ReadAll(c *gin.Context) {
+	userIDVal, _ := c.Get("user_id")
+	uid, ok := userIDVal.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	updated, err := h.Repo.MarkAllRead(c.Request.Context(), uid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Notifications marked as read", "updated": updated})
+}
+
+func (h *NotificationHandler) Delete(c *gin.Context) {
+	userIDVal, _ := c.Get("user_id")
+	uid, ok := userIDVal.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user id"})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid notification id"})
+		return
+	}
+
+	okRepo, err := h.Repo.Delete(c.Request.Context(), uid, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !okRepo {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Notification deleted"})
+}
+
+// 📌 GetSettings channel notifikasi user (in-app selalu aktif)
+func (h *NotificationHandler) GetSettings(c *gin.Context) {
+	userIDVal, _ := c.Get("user_id")
+	uid, ok := userIDVal.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	s, err := h.Repo.GetSettings(c.Request.Context(), uid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, s)
+}
+
+// 📌 UpdateSettings ubah channel email / webhook.
+// webhook_url "" = matikan webhook, secret dibuat otomatis waktu webhook pertama kali diisi
+// atau kalau rotate_secret=true.
+func (h *NotificationHandler) UpdateSettings(c *gin.Context) {
+	userIDVal, _ := c.Get("user_id")
+	uid, ok := userIDVal.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	var req struct {
+		EmailEnabled *bool   `json:"email_enabled"`
+		WebhookURL   *string `json:"webhook_url"`
+		RotateSecret bool    `json:"rotate_secret"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	s, err := h.Repo.GetSettings(c.Request.Context(), uid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.EmailEnabled != nil {
+		s.EmailEnabled = *req.EmailEnabled
+	}
+	if req.WebhookURL != nil {
+		url := strings.TrimSpace(*req.WebhookURL)
+		if url == "" {
+			s.WebhookURL = nil
+			s.WebhookSecret = nil
+		} else {
+			if err := notify.ValidateWebhookURL(url); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			s.WebhookURL = &url
+		}
+	}
+	if s.WebhookURL != nil && (s.WebhookSecret == nil || req.RotateSecret) {
+		secret, err := generateOpaqueToken()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate webhook secret"})
+			return
+		}
+		s.WebhookSecret = &secret
+	}
+
+	if err := h.Repo.SaveSettings(c.Request.Context(), s); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, s)
+}
@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rifqi535/expense-tracker-api/internal/calendar"
+	"github.com/rifqi535/expense-tracker-api/internal/models"
+	"github.com/rifqi535/expense-tracker-api/internal/oidc"
+	"gorm.io/gorm"
+)
+
+// login OIDC harus selesai dalam waktu ini sejak /start
+const oidcStateTTL = 10 * time.Minute
+
+// 📌 OIDCStart: mulai login lewat provider (authorization code + PKCE)
+func (h *AuthHandler) OIDCStart(c *gin.Context) {
+	provider, ok := h.Providers[c.Param("provider")]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "unknown provider"})
+		return
+	}
+
+	state, err := oidc.GenerateVerifier()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate state"})
+		return
+	}
+	nonce, err := oidc.GenerateVerifier()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate nonce"})
+		return
+	}
+	verifier, err := oidc.GenerateVerifier()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate verifier"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	_ = h.OIDC.DeleteExpiredStates(ctx)
+	if err := h.OIDC.CreateState(ctx, &models.OIDCState{
+		ID:           uuid.New(),
+		StateHash:    hashToken(state),
+		Provider:     provider.Name(),
+		CodeVerifier: verifier,
+		Nonce:        nonce,
+		ExpiresAt:    time.Now().Add(oidcStateTTL),
+		CreatedAt:    time.Now(),
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	authURL, err := provider.AuthCodeURL(ctx, state, nonce, oidc.ChallengeS256(verifier))
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+
+	// browser bisa langsung di-redirect, client API cukup ambil URL-nya
+	if c.Query("redirect") == "true" {
+		c.Redirect(http.StatusFound, authURL)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"authorization_url": authURL})
+}
+
+// 📌 OIDCCallback: provider redirect ke sini dengan ?code=...&state=...
+func (h *AuthHandler) OIDCCallback(c *gin.Context) {
+	provider, ok := h.Providers[c.Param("provider")]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "unknown provider"})
+		return
+	}
+
+	if e := c.Query("error"); e != "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "provider error: " + e, "description": c.Query("error_description")})
+		return
+	}
+
+	code, state := c.Query("code"), c.Query("state")
+	if code == "" || state == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "code and state are required"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	st, err := h.OIDC.ConsumeState(ctx, provider.Name(), hashToken(state))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if st == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid or expired state"})
+		return
+	}
+
+	idt, err := provider.Exchange(ctx, code, st.CodeVerifier, st.Nonce)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.oidcUser(c, provider.Name(), idt)
+	if err != nil {
+		return
+	}
+	if !h.checkAccountLock(c, user) {
+		return
+	}
+
+	h.completeLogin(c, user, "oidc:"+provider.Name())
+}
+
+// oidcUser cari user yang ter-link, link ke akun yang emailnya sudah terverifikasi (di provider & di sini),
+// atau bikin user baru.
+// Kalau gagal, response error sudah ditulis ke c.
+func (h *AuthHandler) oidcUser(c *gin.Context, provider string, idt *oidc.IDToken) (*models.User, error) {
+	ctx := c.Request.Context()
+
+	user, err := h.OIDC.FindUserByIdentity(ctx, provider, idt.Subject)
+	if err == nil {
+		return user, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, err
+	}
+
+	// belum ter-link: cuma boleh lewat email yang sudah diverifikasi provider
+	if idt.Email == "" || !idt.EmailVerified {
+		err := errors.New("provider did not return a verified email")
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return nil, err
+	}
+
+	email := idt.Email
+	identity := &models.UserIdentity{
+		ID:        uuid.New(),
+		Provider:  provider,
+		Subject:   idt.Subject,
+		Email:     &email,
+		CreatedAt: time.Now(),
+	}
+
+	existing, err := h.Users.GetByEmail(ctx, email)
+	switch {
+	case err == nil:
+		// akun lokal yang emailnya belum diverifikasi bisa saja didaftarkan orang lain dengan email korban
+		// (password-nya dia yang tahu), jadi tidak di-link otomatis: verifikasi email / login password dulu
+		if existing.EmailVerifiedAt == nil {
+			err := errors.New("email is registered but not verified, verify it or sign in with password first")
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return nil, err
+		}
+		identity.UserID = existing.ID
+		if err := h.OIDC.CreateIdentity(ctx, identity); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return nil, err
+		}
+		return existing, nil
+
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		// user baru tanpa password (bisa diset lewat reset password)
+		randomPassword, err := generateOpaqueToken()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create user"})
+			return nil, err
+		}
+		hashed, err := hashPassword(randomPassword)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create user"})
+			return nil, err
+		}
+
+		name := strings.TrimSpace(idt.Name)
+		if name == "" {
+			name, _, _ = strings.Cut(email, "@")
+		}
+
+		now := time.Now()
+		newUser := &models.User{
+			ID:              uuid.New(),
+			Name:            name,
+			Email:           email,
+			PasswordHash:    hashed,
+			Currency:        h.Cfg.DefaultCurrency,
+			Timezone:        h.Cfg.DefaultTimezone,
+			Locale:          h.Cfg.DefaultLocale,
+			WeekStart:       int(calendar.DefaultWeekStart(h.Cfg.DefaultLocale)),
+			EmailVerifiedAt: &now,
+			CreatedAt:       now,
+			UpdatedAt:       now,
+		}
+		identity.UserID = newUser.ID
+		categories := h.DefaultCategories.Models(newUser.ID, newUser.Locale, now)
+		if err := h.OIDC.CreateUserWithIdentity(ctx, newUser, identity, categories); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return nil, err
+		}
+		return newUser, nil
+
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, err
+	}
+}
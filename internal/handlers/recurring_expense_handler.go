@@ -0,0 +1,518 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rifqi535/expense-tracker-api/internal/calendar"
+	"github.com/rifqi535/expense-tracker-api/internal/models"
+	"github.com/rifqi535/expense-tracker-api/internal/money"
+	"github.com/rifqi535/expense-tracker-api/internal/recurring"
+	"github.com/rifqi535/expense-tracker-api/internal/repository"
+	"github.com/rifqi535/expense-tracker-api/internal/rrule"
+	"gorm.io/gorm"
+)
+
+// maxOccurrencePreview batas jumlah kejadian di GET /occurrences
+const maxOccurrencePreview = 100
+
+// status kejadian di preview
+const (
+	occurrenceScheduled  = "scheduled"
+	occurrenceCreated    = "created"
+	occurrenceSkipped    = "skipped"
+	occurrenceOverridden = "overridden"
+)
+
+type RecurringExpenseHandler struct {
+	Repo      *repository.RecurringExpenseRepo
+	Users     *repository.UserRepo
+	Scheduler *recurring.Scheduler
+}
+
+func NewRecurringExpenseHandler(repo *repository.RecurringExpenseRepo, users *repository.UserRepo, scheduler *recurring.Scheduler) *RecurringExpenseHandler {
+	return &RecurringExpenseHandler{Repo: repo, Users: users, Scheduler: scheduler}
+}
+
+// recurringRequest body create / update. Jadwal pakai freq/interval/until/count atau string rrule.
+type recurringRequest struct {
+	Title       string      `json:"title"`
+	Description *string     `json:"description"`
+	Amount      money.Money `json:"amount"`
+	Currency    string      `json:"currency"` // opsional, default mata uang user
+	CategoryID  string      `json:"category_id"`
+	Freq        string      `json:"freq"`
+	Interval    int         `json:"interval"`
+	StartsAt    string      `json:"starts_at"` // opsional, default sekarang
+	Timezone    string      `json:"timezone"`  // opsional, default zona user
+	Until       string      `json:"until"`     // YYYY-MM-DD, inklusif
+	Count       int         `json:"count"`
+	RRule       string      `json:"rrule"` // mis. "FREQ=MONTHLY;COUNT=12"
+}
+
+type recurringResponse struct {
+	models.RecurringExpense
+	RRule string `json:"rrule"`
+}
+
+type occurrenceResponse struct {
+	Date      string                     `json:"date"`
+	At        time.Time                  `json:"at"`
+	Status    string                     `json:"status"`
+	ExpenseID *uuid.UUID                 `json:"expense_id,omitempty"`
+	Exception *models.RecurringException `json:"exception,omitempty"`
+}
+
+// 📌 List: semua jadwal milik user
+func (h *RecurringExpenseHandler) List(c *gin.Context) {
+	userIDVal, _ := c.Get("user_id")
+	uid, ok := userIDVal.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	list, err := h.Repo.ListByUser(c.Request.Context(), uid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp := make([]recurringResponse, 0, len(list))
+	for _, re := range list {
+		resp = append(resp, toRecurringResponse(re))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// 📌 Get: detail satu jadwal
+func (h *RecurringExpenseHandler) Get(c *gin.Context) {
+	re, ok := h.load(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, toRecurringResponse(*re))
+}
+
+// 📌 Create: jadwal baru; starts_at di masa lalu ikut dibuatkan expense-nya (catch-up)
+func (h *RecurringExpenseHandler) Create(c *gin.Context) {
+	userIDVal, _ := c.Get("user_id")
+	uid, ok := userIDVal.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	var req recurringRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	now := time.Now()
+	re := &models.RecurringExpense{ID: uuid.New(), UserID: uid, CreatedAt: now, UpdatedAt: now}
+	rule, err := h.apply(c, re, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	re.NextIndex = 0
+	re.NextRunAt = nextRun(rule, 0)
+
+	if err := h.Repo.Create(c.Request.Context(), re); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	h.Scheduler.Notify()
+
+	c.JSON(http.StatusCreated, toRecurringResponse(*re))
+}
+
+// 📌 Update: ganti template / jadwal. Kalau jadwal berubah, mulai lagi dari kejadian setelah sekarang.
+func (h *RecurringExpenseHandler) Update(c *gin.Context) {
+	re, ok := h.load(c)
+	if !ok {
+		return
+	}
+
+	var req recurringRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	old := *re
+	rule, err := h.apply(c, re, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if re.Freq != old.Freq || re.Interval != old.Interval || !re.StartsAt.Equal(old.StartsAt) || re.Timezone != old.Timezone {
+		re.NextIndex = rule.FirstAfter(time.Now())
+	}
+	re.NextRunAt = nextRun(rule, re.NextIndex)
+
+	okRepo, err := h.Repo.Update(c.Request.Context(), re.UserID, re.ID, re)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !okRepo {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	h.Scheduler.Notify()
+
+	c.JSON(http.StatusOK, toRecurringResponse(*re))
+}
+
+// 📌 Delete: hapus jadwal, expense yang sudah dibuat tidak ikut terhapus
+func (h *RecurringExpenseHandler) Delete(c *gin.Context) {
+	userIDVal, _ := c.Get("user_id")
+	uid, ok := userIDVal.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user id"})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid recurring expense id"})
+		return
+	}
+
+	okRepo, err := h.Repo.Delete(c.Request.Context(), uid, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !okRepo {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Recurring expense deleted"})
+}
+
+// 📌 Occurrences: daftar kejadian ?from=&to= (default 90 hari ke depan dari hari ini)
+func (h *RecurringExpenseHandler) Occurrences(c *gin.Context) {
+	re, ok := h.load(c)
+	if !ok {
+		return
+	}
+	rule, err := re.Rule()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	cal := calendar.New(rule.Start.Location(), time.Monday)
+
+	from := cal.StartOfDay(time.Now())
+	if s := c.Query("from"); s != "" {
+		if from, err = cal.ParseDate(s); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from date, use YYYY-MM-DD"})
+			return
+		}
+	}
+	to := from.AddDate(0, 0, 90)
+	if s := c.Query("to"); s != "" {
+		if to, err = cal.ParseDate(s); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to date, use YYYY-MM-DD"})
+			return
+		}
+	}
+	end := cal.Next(to, calendar.Day)
+
+	ctx := c.Request.Context()
+	exceptions, err := h.Repo.Exceptions(ctx, re.ID, from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	byDate := make(map[string]models.RecurringException, len(exceptions))
+	for _, e := range exceptions {
+		byDate[e.OccurrenceDate.Format("2006-01-02")] = e
+	}
+	created, err := h.Repo.Materialized(ctx, re.ID, from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp := []occurrenceResponse{}
+	for n := rule.FirstAfter(from.Add(-time.Nanosecond)); !rule.Done(n) && len(resp) < maxOccurrencePreview; n++ {
+		at := rule.At(n)
+		if !at.Before(end) {
+			break
+		}
+
+		date := at.Format("2006-01-02")
+		item := occurrenceResponse{Date: date, At: at, Status: occurrenceScheduled}
+		if exc, ok := byDate[date]; ok {
+			item.Exception = &exc
+			item.Status = occurrenceOverridden
+			if exc.Action == models.RecurringSkip {
+				item.Status = occurrenceSkipped
+			}
+		}
+		if id, ok := created[date]; ok {
+			item.ExpenseID = &id
+			item.Status = occurrenceCreated
+		}
+		resp = append(resp, item)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"rrule": rule.String(), "occurrences": resp})
+}
+
+// 📌 SkipOccurrence: lewati satu kejadian (expense-nya dihapus kalau sudah terlanjur dibuat)
+func (h *RecurringExpenseHandler) SkipOccurrence(c *gin.Context) {
+	re, date, ok := h.loadOccurrence(c)
+	if !ok {
+		return
+	}
+
+	touched, err := h.Repo.SaveException(c.Request.Context(), re.UserID, &models.RecurringException{
+		RecurringID:    re.ID,
+		OccurrenceDate: date,
+		Action:         models.RecurringSkip,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Occurrence skipped", "expense_deleted": touched})
+}
+
+// 📌 UpdateOccurrence: ubah isi satu kejadian tanpa mengubah jadwalnya
+func (h *RecurringExpenseHandler) UpdateOccurrence(c *gin.Context) {
+	re, date, ok := h.loadOccurrence(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Title       *string      `json:"title"`
+		Description *string      `json:"description"`
+		Amount      *money.Money `json:"amount"`
+		CategoryID  *string      `json:"category_id"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Title == nil && req.Description == nil && req.Amount == nil && req.CategoryID == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "nothing to update"})
+		return
+	}
+
+	exc := &models.RecurringException{
+		RecurringID:    re.ID,
+		OccurrenceDate: date,
+		Action:         models.RecurringOverride,
+		Description:    req.Description,
+	}
+	if req.Title != nil {
+		title := strings.TrimSpace(*req.Title)
+		if title == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "title is required"})
+			return
+		}
+		exc.Title = &title
+	}
+	if req.Amount != nil {
+		if err := validateAmount(*req.Amount, re.Currency); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		exc.Amount = req.Amount
+	}
+	if req.CategoryID != nil {
+		categoryID, err := uuid.Parse(*req.CategoryID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid category id"})
+			return
+		}
+		exc.CategoryID = &categoryID
+	}
+
+	touched, err := h.Repo.SaveException(c.Request.Context(), re.UserID, exc)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Occurrence updated", "expense_updated": touched})
+}
+
+// 📌 ResetOccurrence: hapus skip / override; expense yang sudah dibuat / dihapus tidak berubah
+func (h *RecurringExpenseHandler) ResetOccurrence(c *gin.Context) {
+	re, date, ok := h.loadOccurrence(c)
+	if !ok {
+		return
+	}
+
+	okRepo, err := h.Repo.DeleteException(c.Request.Context(), re.ID, date)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !okRepo {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Occurrence reset"})
+}
+
+// apply validasi request lalu isi template + jadwal ke re
+func (h *RecurringExpenseHandler) apply(c *gin.Context, re *models.RecurringExpense, req *recurringRequest) (rrule.Rule, error) {
+	prefs, err := h.Users.GetPreferences(c.Request.Context(), re.UserID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return rrule.Rule{}, errors.New("user not found")
+	}
+	if err != nil {
+		return rrule.Rule{}, err
+	}
+
+	title := strings.TrimSpace(req.Title)
+	if title == "" {
+		return rrule.Rule{}, errors.New("title is required")
+	}
+	currency := money.NormalizeCurrency(req.Currency)
+	if currency == "" {
+		currency = prefs.Currency
+	}
+	if err := validateAmount(req.Amount, currency); err != nil {
+		return rrule.Rule{}, err
+	}
+	categoryID, err := uuid.Parse(req.CategoryID)
+	if err != nil {
+		return rrule.Rule{}, errors.New("invalid category id")
+	}
+
+	// --- JADWAL ---
+	freq, interval, count := strings.ToLower(strings.TrimSpace(req.Freq)), req.Interval, req.Count
+	var until *time.Time
+	if req.Until != "" {
+		t, err := time.Parse("2006-01-02", req.Until)
+		if err != nil {
+			return rrule.Rule{}, errors.New("invalid until, use YYYY-MM-DD")
+		}
+		until = &t
+	}
+	if req.RRule != "" {
+		if freq != "" || interval != 0 || count != 0 || until != nil {
+			return rrule.Rule{}, errors.New("use either rrule or freq/interval/until/count")
+		}
+		spec, err := rrule.Parse(req.RRule)
+		if err != nil {
+			return rrule.Rule{}, err
+		}
+		freq, interval, count, until = spec.Freq, spec.Interval, spec.Count, spec.Until
+	}
+	if interval == 0 {
+		interval = 1
+	}
+
+	timezone := strings.TrimSpace(req.Timezone)
+	if timezone == "" {
+		timezone = prefs.Timezone
+	}
+	startsAt, _, err := parseSpentAt(req.StartsAt, timezone, time.UTC)
+	if err != nil {
+		return rrule.Rule{}, err
+	}
+
+	re.Title = title
+	re.Description = req.Description
+	re.Amount = req.Amount
+	re.Currency = currency
+	re.CategoryID = categoryID
+	re.Freq = freq
+	re.Interval = interval
+	re.StartsAt = startsAt
+	re.Timezone = timezone
+	re.UntilDate = until
+	re.MaxCount = nil
+	if count > 0 {
+		re.MaxCount = &count
+	} else if count < 0 {
+		return rrule.Rule{}, rrule.ErrInvalidCount
+	}
+
+	return re.Rule()
+}
+
+// load jadwal dari :id milik user, response error sudah ditulis kalau gagal
+func (h *RecurringExpenseHandler) load(c *gin.Context) (*models.RecurringExpense, bool) {
+	userIDVal, _ := c.Get("user_id")
+	uid, ok := userIDVal.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user id"})
+		return nil, false
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid recurring expense id"})
+		return nil, false
+	}
+
+	re, err := h.Repo.GetByID(c.Request.Context(), uid, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if re == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return nil, false
+	}
+	return re, true
+}
+
+// loadOccurrence jadwal + tanggal :date yang memang salah satu kejadiannya
+func (h *RecurringExpenseHandler) loadOccurrence(c *gin.Context) (*models.RecurringExpense, time.Time, bool) {
+	re, ok := h.load(c)
+	if !ok {
+		return nil, time.Time{}, false
+	}
+	date, err := time.Parse("2006-01-02", c.Param("date"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid occurrence date, use YYYY-MM-DD"})
+		return nil, time.Time{}, false
+	}
+
+	rule, err := re.Rule()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, time.Time{}, false
+	}
+	if _, ok := rule.IndexOf(date); !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no occurrence on this date"})
+		return nil, time.Time{}, false
+	}
+	return re, date, true
+}
+
+func toRecurringResponse(re models.RecurringExpense) recurringResponse {
+	resp := recurringResponse{RecurringExpense: re}
+	if rule, err := re.Rule(); err == nil {
+		resp.RRule = rule.String()
+		resp.StartsAt = rule.Start
+	}
+	return resp
+}
+
+// nextRun waktu kejadian ke-n, nil kalau jadwal sudah selesai
+func nextRun(rule rrule.Rule, n int) *time.Time {
+	if rule.Done(n) {
+		return nil
+	}
+	t := rule.At(n)
+	return &t
+}
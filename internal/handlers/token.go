@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// 🔑 Generate token opaque (random) untuk dikirim ke client
+func generateOpaqueToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// 🔑 Hash token opaque, yang disimpan di DB cuma hash-nya
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+var errInvalidSignedToken = errors.New("invalid or expired link")
+
+// signedPayload isi link yang ditandatangani (verifikasi email, dll)
+type signedPayload struct {
+	Purpose string `json:"p"`
+	UserID  string `json:"uid"`
+	Email   string `json:"email,omitempty"`
+	Ref     string `json:"ref,omitempty"` // id resource lain (mis. export)
+	Exp     int64  `json:"exp"`
+}
+
+// 🔑 signPayload: base64url(json) + "." + base64url(hmac-sha256)
+func signPayload(secret []byte, p signedPayload) (string, error) {
+	body, err := json.Marshal(p)
+	if err != nil {
+		return "", err
+	}
+	enc := base64.RawURLEncoding.EncodeToString(body)
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(enc))
+	return enc + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// 🔑 verifyPayload cek signature, purpose dan expiry
+func verifyPayload(secret []byte, token, purpose string) (*signedPayload, error) {
+	enc, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, errInvalidSignedToken
+	}
+
+	got, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil {
+		return nil, errInvalidSignedToken
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(enc))
+	if !hmac.Equal(got, mac.Sum(nil)) {
+		return nil, errInvalidSignedToken
+	}
+
+	body, err := base64.RawURLEncoding.DecodeString(enc)
+	if err != nil {
+		return nil, errInvalidSignedToken
+	}
+	var p signedPayload
+	if err := json.Unmarshal(body, &p); err != nil {
+		return nil, errInvalidSignedToken
+	}
+	if p.Purpose != purpose || time.Now().Unix() > p.Exp {
+		return nil, errInvalidSignedToken
+	}
+	return &p, nil
+}
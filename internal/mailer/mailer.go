@@ -0,0 +1,36 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/rifqi535/expense-tracker-api/internal/config"
+)
+
+// Message email plain text sederhana
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer pengirim email, implementasinya bisa SMTP atau log/file (buat lokal & test)
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// New pilih implementasi Mailer sesuai MAIL_DRIVER
+func New(cfg *config.Config) (Mailer, error) {
+	switch strings.ToLower(cfg.MailDriver) {
+	case "smtp":
+		if cfg.SMTPHost == "" {
+			return nil, fmt.Errorf("SMTP_HOST is required for MAIL_DRIVER=smtp")
+		}
+		return NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUser, cfg.SMTPPassword, cfg.MailFrom), nil
+	case "", "log":
+		return NewLogMailer(cfg.MailLogFile), nil
+	default:
+		return nil, fmt.Errorf("unknown MAIL_DRIVER %q", cfg.MailDriver)
+	}
+}
@@ -0,0 +1,50 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+type SMTPMailer struct {
+	host     string
+	port     string
+	user     string
+	password string
+	from     string
+}
+
+func NewSMTPMailer(host, port, user, password, from string) *SMTPMailer {
+	return &SMTPMailer{host: host, port: port, user: user, password: password, from: from}
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	var auth smtp.Auth
+	if m.user != "" {
+		auth = smtp.PlainAuth("", m.user, m.password, m.host)
+	}
+
+	headers := []string{
+		"From: " + m.from,
+		"To: " + msg.To,
+		"Subject: " + msg.Subject,
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+	}
+	body := strings.Join(headers, "\r\n") + "\r\n\r\n" + msg.Body
+
+	// net/smtp belum support context, minimal cek dulu sebelum kirim
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	addr := net.JoinHostPort(m.host, m.port)
+	if err := smtp.SendMail(addr, auth, m.from, []string{msg.To}, []byte(body)); err != nil {
+		return fmt.Errorf("smtp send: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rifqi535/expense-tracker-api/internal/models"
+	"gorm.io/gorm"
+)
+
+// APIKeyPrefix awalan semua personal API key, biar gampang dibedakan dari JWT
+const APIKeyPrefix = "etk_"
+
+// scope yang bisa dipasang di API key, key tanpa scope = akses penuh seperti JWT
+const (
+	ScopeRead            = "read"
+	ScopeExpensesWrite   = "expenses:write"
+	ScopeCategoriesWrite = "categories:write"
+	ScopeBudgetsWrite    = "budgets:write"
+)
+
+// ValidScopes daftar scope yang dikenal
+var ValidScopes = []string{ScopeRead, ScopeExpensesWrite, ScopeCategoriesWrite, ScopeBudgetsWrite}
+
+var apiKeyStore APIKeyStore
+
+// APIKeyStore dipakai AuthMiddleware buat autentikasi lewat API key
+type APIKeyStore interface {
+	FindActiveByHash(ctx context.Context, hash string) (*models.APIKey, error)
+	TouchLastUsed(ctx context.Context, id uuid.UUID) error
+}
+
+// InitAPIKeyStore dipanggil dari main.go supaya AuthMiddleware bisa terima API key
+func InitAPIKeyStore(store APIKeyStore) {
+	apiKeyStore = store
+}
+
+// HashAPIKey yang disimpan di DB cuma hash-nya
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// apiKeyFromRequest ambil API key dari header X-API-Key atau Authorization: Bearer etk_...
+func apiKeyFromRequest(c *gin.Context) string {
+	if k := c.GetHeader("X-API-Key"); k != "" {
+		return k
+	}
+	if tok := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "); strings.HasPrefix(tok, APIKeyPrefix) {
+		return tok
+	}
+	return ""
+}
+
+// authenticateAPIKey cari key aktif lalu catat last_used_at
+func authenticateAPIKey(ctx context.Context, key string) (*models.APIKey, error) {
+	if apiKeyStore == nil {
+		return nil, errors.New("api keys are not enabled")
+	}
+
+	k, err := apiKeyStore.FindActiveByHash(ctx, HashAPIKey(key))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("invalid or expired api key")
+		}
+		return nil, err
+	}
+
+	if err := apiKeyStore.TouchLastUsed(ctx, k.ID); err != nil {
+		// gagal catat last_used tidak perlu menggagalkan request
+		fmt.Println("❌ api key touch error:", err)
+	}
+	return k, nil
+}
+
+// RequireScope: request lewat API key harus punya scope ini (JWT selalu lolos)
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		val, isAPIKey := c.Get("api_key_scopes")
+		if !isAPIKey {
+			c.Next()
+			return
+		}
+
+		scopes, _ := val.([]string)
+		if len(scopes) == 0 {
+			c.Next()
+			return
+		}
+		for _, s := range scopes {
+			if s == scope {
+				c.Next()
+				return
+			}
+		}
+
+		c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("api key is missing scope %q", scope)})
+		c.Abort()
+	}
+}
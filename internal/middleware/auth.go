@@ -2,87 +2,84 @@
 package middleware
 
 import (
-	"errors"
+	"context"
 	"fmt"
 	"net/http"
 	"strings"
-	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
+	"github.com/rifqi535/expense-tracker-api/internal/token"
 )
 
-var jwtSecret []byte
+var (
+	tokens       *token.Service
+	sessionStore SessionStore
+)
 
-// InitJWT harus dipanggil dari main.go setelah .env diload
-func InitJWT(secret string) {
-	if secret == "" {
-		panic("JWT_SECRET is empty, set di .env atau environment")
-	}
-	jwtSecret = []byte(secret)
-	fmt.Println("✅ JWT_SECRET loaded")
+// SessionStore dipakai AuthMiddleware buat cek apakah sesi masih aktif (sekalian catat last seen)
+type SessionStore interface {
+	Touch(ctx context.Context, sessionID uuid.UUID) (bool, error)
 }
 
-// GenerateToken bikin JWT dari userID
-func GenerateToken(userID string) (string, error) {
-	if len(jwtSecret) == 0 {
-		return "", errors.New("JWT_SECRET is not set")
-	}
-
-	claims := jwt.MapClaims{
-		"user_id": fmt.Sprintf("%s", userID),
-		"exp":     time.Now().Add(72 * time.Hour).Unix(), // 3 hari
-	}
-
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtSecret)
+// InitJWT harus dipanggil dari main.go setelah token service dibuat
+func InitJWT(svc *token.Service) {
+	tokens = svc
+	fmt.Println("✅ JWT keys loaded")
 }
 
-// ParseToken untuk validasi & extract user_id dari token
-func ParseToken(tokenString string) (uuid.UUID, error) {
-	if len(jwtSecret) == 0 {
-		return uuid.Nil, errors.New("JWT_SECRET is not set")
-	}
-
-	// ✅ parse & validasi
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		// cek signing method
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return jwtSecret, nil
-	})
-
-	if err != nil {
-		// 🔎 kasih detail biar ketahuan error aslinya
-		return uuid.Nil, fmt.Errorf("token parse error: %w", err)
-	}
-	if !token.Valid {
-		return uuid.Nil, errors.New("token is not valid")
-	}
+// InitSessionStore dipanggil dari main.go supaya logout / revoke sesi langsung berlaku
+func InitSessionStore(store SessionStore) {
+	sessionStore = store
+}
 
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok {
-		return uuid.Nil, errors.New("invalid claims")
-	}
+type authOptions struct {
+	allowRestricted bool
+	allowAPIKey     bool
+}
 
-	userID, ok := claims["user_id"].(string)
-	if !ok {
-		return uuid.Nil, fmt.Errorf("invalid user id, got type %T, value: %#v", claims["user_id"], claims["user_id"])
-	}
+// AuthOption opsi tambahan untuk AuthMiddleware
+type AuthOption func(*authOptions)
 
-	uid, err := uuid.Parse(userID)
-	if err != nil {
-		return uuid.Nil, fmt.Errorf("invalid UUID format: %w", err)
-	}
+// AllowRestricted: route ini boleh diakses token restricted (email belum diverifikasi)
+func AllowRestricted() AuthOption {
+	return func(o *authOptions) { o.allowRestricted = true }
+}
 
-	return uid, nil
+// AllowAPIKey: route ini juga bisa diakses pakai personal API key (lihat RequireScope)
+func AllowAPIKey() AuthOption {
+	return func(o *authOptions) { o.allowAPIKey = true }
 }
 
 // AuthMiddleware untuk validasi JWT di header
-func AuthMiddleware() gin.HandlerFunc {
+func AuthMiddleware(opts ...AuthOption) gin.HandlerFunc {
+	var o authOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
 	return func(c *gin.Context) {
+		if key := apiKeyFromRequest(c); key != "" {
+			if !o.allowAPIKey {
+				c.JSON(http.StatusForbidden, gin.H{"error": "api keys are not allowed on this route"})
+				c.Abort()
+				return
+			}
+
+			k, err := authenticateAPIKey(c.Request.Context(), key)
+			if err != nil {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+				c.Abort()
+				return
+			}
+
+			c.Set("user_id", k.UserID)
+			c.Set("api_key_id", k.ID)
+			c.Set("api_key_scopes", k.Scopes)
+			c.Next()
+			return
+		}
+
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "authorization header required"})
@@ -98,7 +95,13 @@ func AuthMiddleware() gin.HandlerFunc {
 
 		tokenStr := strings.TrimPrefix(authHeader, "Bearer ")
 
-		userID, err := ParseToken(tokenStr)
+		if tokens == nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "token service is not initialized"})
+			c.Abort()
+			return
+		}
+
+		claims, err := tokens.ParseAccessToken(tokenStr)
 		if err != nil {
 			// 🔎 log biar keliatan di console error sebenarnya
 			fmt.Println("❌ JWT error:", err)
@@ -107,8 +110,47 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		// simpan user_id ke context
-		c.Set("user_id", userID)
+		if claims.Restricted && !o.allowRestricted {
+			c.JSON(http.StatusForbidden, gin.H{"error": "email not verified"})
+			c.Abort()
+			return
+		}
+
+		// cek sesi belum di-logout / di-revoke
+		if sessionStore != nil {
+			active, err := sessionStore.Touch(c.Request.Context(), claims.SessionID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				c.Abort()
+				return
+			}
+			if !active {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "session has been revoked"})
+				c.Abort()
+				return
+			}
+		}
+
+		// simpan user_id, session_id & role ke context
+		c.Set("user_id", claims.UserID)
+		c.Set("session_id", claims.SessionID)
+		c.Set("role", claims.Role)
 		c.Next()
 	}
 }
+
+// RequireRole dipasang setelah AuthMiddleware, tolak kalau role di token tidak termasuk roles
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := c.GetString("role")
+		for _, r := range roles {
+			if role == r {
+				c.Next()
+				return
+			}
+		}
+
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		c.Abort()
+	}
+}
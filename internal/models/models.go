@@ -4,34 +4,362 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/rifqi535/expense-tracker-api/internal/calendar"
+	"github.com/rifqi535/expense-tracker-api/internal/money"
+	"github.com/rifqi535/expense-tracker-api/internal/rrule"
 	"gorm.io/gorm"
 )
 
+// role user
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
 type User struct {
-	ID           uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primaryKey" json:"id"`
-	Name         string    `json:"name"`
-	Email        string    `json:"email"`
-	PasswordHash string    `json:"_"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"update_at"`
+	ID                    uuid.UUID  `gorm:"type:uuid;default:uuid_generate_v4();primaryKey" json:"id"`
+	Name                  string     `json:"name"`
+	Email                 string     `json:"email"`
+	PasswordHash          string     `json:"-"`
+	Role                  string     `json:"role"`
+	Currency              string     `json:"currency"`
+	Timezone              string     `json:"timezone"`
+	Locale                string     `json:"locale"`
+	WeekStart             int        `json:"week_start"` // 0 = Minggu, 1 = Senin
+	DisabledAt            *time.Time `json:"disabled_at"`
+	PasswordResetRequired bool       `json:"password_reset_required"`
+	FailedLoginCount      int        `json:"failed_login_count"`
+	LastFailedLoginAt     *time.Time `json:"-"`
+	LockedUntil           *time.Time `json:"locked_until"`
+	EmailVerifiedAt       *time.Time `json:"email_verified_at"`
+	VerificationSentAt    *time.Time `json:"-"`
+	TOTPSecret            *string    `json:"-"`
+	TOTPEnabledAt         *time.Time `json:"-"`
+	TOTPLastStep          *int64     `json:"-"`
+	PendingEmail          *string    `json:"pending_email,omitempty"`
+	DeletionRequestedAt   *time.Time `json:"deletion_requested_at,omitempty"`
+	CreatedAt             time.Time  `json:"created_at"`
+	UpdatedAt             time.Time  `json:"update_at"`
 }
 
 type Category struct {
-	ID        uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primaryKey" json:"id"`
-	Title     string    `json:"title"`
+	ID         uuid.UUID  `gorm:"type:uuid;default:uuid_generate_v4();primaryKey" json:"id"`
+	Title      string     `json:"title"`
+	UserID     uuid.UUID  `gorm:"type:uuid" json:"user_id"`
+	ParentID   *uuid.UUID `gorm:"type:uuid" json:"parent_id"` // nil = kategori utama
+	Color      *string    `json:"color"`                      // #RRGGBB
+	Icon       *string    `json:"icon"`                       // key ikon di client, mis. "utensils"
+	SortOrder  int        `json:"sort_order"`
+	ArchivedAt *time.Time `json:"archived_at"`                  // diisi = disembunyikan dari pilihan kategori
+	Rollover   string     `gorm:"default:none" json:"rollover"` // aturan envelope, lihat Rollover*
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"update_at"`
+}
+
+// Budget batas pengeluaran per bulan, CategoryID nil = budget total.
+// Berlaku dari StartMonth sampai EndMonth (tanggal 1, inklusif, nil = seterusnya).
+type Budget struct {
+	ID         uuid.UUID   `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID     uuid.UUID   `gorm:"type:uuid" json:"user_id"`
+	CategoryID *uuid.UUID  `gorm:"type:uuid" json:"category_id"`
+	Amount     money.Money `json:"amount"`
+	Currency   string      `json:"currency"`
+	StartMonth time.Time   `gorm:"type:date" json:"-"`
+	EndMonth   *time.Time  `gorm:"type:date" json:"-"`
+	CreatedAt  time.Time   `json:"created_at"`
+	UpdatedAt  time.Time   `json:"updated_at"`
+}
+
+// aturan rollover envelope kategori ke bulan berikutnya
+const (
+	RolloverNone    = "none"    // mulai dari nol tiap bulan
+	RolloverSurplus = "surplus" // sisa dibawa, kelebihan belanja tidak
+	RolloverDeficit = "deficit" // kelebihan belanja dibawa sebagai minus, sisa tidak
+	RolloverFull    = "full"    // sisa & kelebihan belanja dua-duanya dibawa
+)
+
+// BudgetAllocation satu baris ledger alokasi envelope di satu bulan.
+// Transfer antar envelope = dua baris (minus & plus) dengan TransferID yang sama.
+type BudgetAllocation struct {
+	ID         uuid.UUID   `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID     uuid.UUID   `gorm:"type:uuid" json:"user_id"`
+	CategoryID uuid.UUID   `gorm:"type:uuid" json:"category_id"`
+	Month      time.Time   `gorm:"type:date" json:"-"`
+	Amount     money.Money `json:"amount"` // negatif = uang keluar dari envelope
+	Currency   string      `json:"currency"`
+	TransferID *uuid.UUID  `gorm:"type:uuid" json:"transfer_id"`
+	Note       *string     `json:"note"`
+	CreatedAt  time.Time   `json:"created_at"`
+}
+
+// jenis notifikasi
+const (
+	NotificationBudgetAlert = "budget_alert"
+)
+
+// Notification satu item inbox in-app, ReadAt nil = belum dibaca
+type Notification struct {
+	ID        uuid.UUID              `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID    uuid.UUID              `gorm:"type:uuid" json:"user_id"`
+	Kind      string                 `json:"kind"`
+	Title     string                 `json:"title"`
+	Body      string                 `json:"body"`
+	Data      map[string]interface{} `gorm:"serializer:json" json:"data"`
+	ReadAt    *time.Time             `json:"read_at"`
+	CreatedAt time.Time              `json:"created_at"`
+}
+
+// NotificationSettings channel notifikasi selain in-app, WebhookURL nil = webhook mati
+type NotificationSettings struct {
+	UserID        uuid.UUID `gorm:"type:uuid;primaryKey" json:"-"`
+	EmailEnabled  bool      `json:"email_enabled"`
+	WebhookURL    *string   `json:"webhook_url"`
+	WebhookSecret *string   `json:"webhook_secret"` // kunci HMAC header X-Webhook-Signature
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// BudgetAlert penanda ambang batas budget yang sudah dikirim di satu bulan
+type BudgetAlert struct {
+	BudgetID  uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Month     time.Time `gorm:"type:date;primaryKey"`
+	Threshold int       `gorm:"primaryKey"`
+	CreatedAt time.Time
+}
+
+type Tag struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
 	UserID    uuid.UUID `gorm:"type:uuid" json:"user_id"`
+	Name      string    `json:"name"`
 	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"update_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type ExpenseTag struct {
+	ExpenseID uuid.UUID `gorm:"type:uuid;primaryKey"`
+	TagID     uuid.UUID `gorm:"type:uuid;primaryKey"`
+}
+
+// Attachment lampiran expense, isi file disimpan di BlobStore dengan key StorageKey
+type Attachment struct {
+	ID          uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID      uuid.UUID `gorm:"type:uuid" json:"user_id"`
+	ExpenseID   uuid.UUID `gorm:"type:uuid" json:"expense_id"`
+	FileName    string    `json:"file_name"`
+	ContentType string    `json:"content_type"`
+	SizeBytes   int64     `json:"size_bytes"`
+	SHA256      string    `gorm:"column:sha256" json:"sha256"`
+	StorageKey  string    `json:"-"`
+	CreatedAt   time.Time `json:"created_at"`
 }
 
 type Expense struct {
-	ID          uuid.UUID      `gorm:"type:uuid;default:uuid_generate_v4();primaryKey" json:"id"`
-	Title       string         `json:"title"`
-	Description *string        `json:"description,omitempty"`
-	Amount      float64        `json:"amount"`
-	CategoryID  uuid.UUID      `gorm:"type:uuid" json:"category_id"`
-	UserID      uuid.UUID      `gorm:"type:uuid" json:"user_id"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+	ID          uuid.UUID   `gorm:"type:uuid;default:uuid_generate_v4();primaryKey" json:"id"`
+	Title       string      `json:"title"`
+	Description *string     `json:"description,omitempty"`
+	Amount      money.Money `json:"amount"`
+	Currency    string      `json:"currency"`
+	SpentAt     time.Time   `json:"spent_at"`                        // waktu transaksi, bukan waktu input
+	SpentTZ     *string     `gorm:"column:spent_tz" json:"spent_tz"` // zona IANA, nil = zona user
+	CategoryID  uuid.UUID   `gorm:"type:uuid" json:"category_id"`
+	UserID      uuid.UUID   `gorm:"type:uuid" json:"user_id"`
+	// diisi kalau expense dibuat dari jadwal berulang
+	RecurringID    *uuid.UUID `gorm:"type:uuid" json:"recurring_id,omitempty"`
+	OccurrenceDate *time.Time `gorm:"type:date" json:"occurrence_date,omitempty"`
+	// nama tag, disimpan lewat expense_tags
+	Tags      []string       `gorm:"-" json:"tags"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+type RefreshToken struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID     uuid.UUID  `gorm:"type:uuid" json:"user_id"`
+	FamilyID   uuid.UUID  `gorm:"type:uuid" json:"family_id"`
+	TokenHash  string     `json:"-"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	ReplacedBy *uuid.UUID `gorm:"type:uuid" json:"replaced_by,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// Session satu login (device); refresh token dalam family yang sama punya FamilyID = Session.ID
+type Session struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID     uuid.UUID  `gorm:"type:uuid" json:"-"`
+	UserAgent  string     `json:"user_agent"`
+	IP         string     `json:"ip"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+type PasswordResetToken struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID    uuid.UUID  `gorm:"type:uuid" json:"user_id"`
+	TokenHash string     `json:"-"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type MFARecoveryCode struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID    uuid.UUID  `gorm:"type:uuid" json:"user_id"`
+	CodeHash  string     `json:"-"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type APIKey struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID     uuid.UUID  `gorm:"type:uuid" json:"user_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	KeyHash    string     `json:"-"`
+	Scopes     []string   `gorm:"serializer:json" json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+type UserIdentity struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID    uuid.UUID `gorm:"type:uuid" json:"user_id"`
+	Provider  string    `json:"provider"`
+	Subject   string    `json:"subject"`
+	Email     *string   `json:"email,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type OIDCState struct {
+	ID           uuid.UUID `gorm:"type:uuid;primaryKey"`
+	StateHash    string
+	Provider     string
+	CodeVerifier string
+	Nonce        string
+	ExpiresAt    time.Time
+	CreatedAt    time.Time
+}
+
+// TableName: naming default GORM jadi "o_id_c_states"
+func (OIDCState) TableName() string { return "oidc_states" }
+
+// jenis event di login_events
+const (
+	LoginEventSuccess = "login_success"
+	LoginEventFailure = "login_failure"
+	LoginEventLockout = "lockout"
+	LoginEventUnlock  = "unlock"
+)
+
+type LoginEvent struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID    *uuid.UUID `gorm:"type:uuid" json:"-"`
+	Email     string     `json:"-"`
+	Event     string     `json:"event"`
+	Method    string     `json:"method,omitempty"`
+	Reason    string     `json:"reason,omitempty"`
+	IP        string     `json:"ip"`
+	UserAgent string     `json:"user_agent"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// status data_exports
+const (
+	ExportPending = "pending"
+	ExportRunning = "running"
+	ExportReady   = "ready"
+	ExportFailed  = "failed"
+	ExportExpired = "expired"
+)
+
+type DataExport struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID      uuid.UUID  `gorm:"type:uuid" json:"-"`
+	Status      string     `json:"status"`
+	Error       *string    `json:"error,omitempty"`
+	FilePath    string     `json:"-"`
+	SizeBytes   int64      `json:"size_bytes,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+}
+
+// ExchangeRate kurs harian, 1 Base = Rate Quote
+type ExchangeRate struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	Base      string     `json:"base"`
+	Quote     string     `json:"quote"`
+	Rate      money.Rate `json:"rate"`
+	RateDate  time.Time  `gorm:"type:date" json:"date"`
+	Source    string     `json:"source,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// action pengecualian jadwal berulang
+const (
+	RecurringSkip     = "skip"
+	RecurringOverride = "override"
+)
+
+// RecurringExpense template expense + jadwal berulang
+type RecurringExpense struct {
+	ID          uuid.UUID   `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID      uuid.UUID   `gorm:"type:uuid" json:"-"`
+	CategoryID  uuid.UUID   `gorm:"type:uuid" json:"category_id"`
+	Title       string      `json:"title"`
+	Description *string     `json:"description,omitempty"`
+	Amount      money.Money `json:"amount"`
+	Currency    string      `json:"currency"`
+	Freq        string      `json:"freq"` // daily / weekly / monthly / yearly
+	Interval    int         `gorm:"column:interval_count" json:"interval"`
+	StartsAt    time.Time   `json:"starts_at"` // tanggal + jam kejadian pertama
+	Timezone    string      `json:"timezone"`
+	UntilDate   *time.Time  `gorm:"type:date" json:"until"`
+	MaxCount    *int        `json:"count"`
+	NextIndex   int         `json:"-"`
+	NextRunAt   *time.Time  `json:"next_run_at"` // nil = jadwal sudah selesai
+	LastRunAt   *time.Time  `json:"last_run_at"`
+	CreatedAt   time.Time   `json:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at"`
+}
+
+// RecurringException satu kejadian yang dilewati (skip) atau diubah (override)
+type RecurringException struct {
+	RecurringID    uuid.UUID    `gorm:"type:uuid;primaryKey" json:"-"`
+	OccurrenceDate time.Time    `gorm:"type:date;primaryKey" json:"date"`
+	Action         string       `json:"action"`
+	Title          *string      `json:"title,omitempty"`
+	Description    *string      `json:"description,omitempty"`
+	Amount         *money.Money `json:"amount,omitempty"`
+	CategoryID     *uuid.UUID   `gorm:"type:uuid" json:"category_id,omitempty"`
+	CreatedAt      time.Time    `json:"created_at"`
+	UpdatedAt      time.Time    `json:"updated_at"`
+}
+
+func (RecurringException) TableName() string { return "recurring_expense_exceptions" }
+
+// Rule jadwal dalam bentuk rrule.Rule, StartsAt dibaca di zona Timezone
+func (r *RecurringExpense) Rule() (rrule.Rule, error) {
+	loc, err := calendar.LoadLocation(r.Timezone)
+	if err != nil {
+		return rrule.Rule{}, err
+	}
+
+	rule := rrule.Rule{
+		Freq:     r.Freq,
+		Interval: r.Interval,
+		Start:    r.StartsAt.In(loc),
+		Until:    r.UntilDate,
+	}
+	if r.MaxCount != nil {
+		rule.Count = *r.MaxCount
+	}
+	return rule, rule.Validate()
 }
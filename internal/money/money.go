@@ -0,0 +1,310 @@
+// Package money nilai uang desimal yang pasti (tanpa float), disimpan sebagai integer berskala tetap
+package money
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Scale jumlah digit desimal yang disimpan; cukup untuk semua mata uang ISO 4217 (maks 4)
+const Scale = 4
+
+const unit = 10000 // 10^Scale
+
+var (
+	ErrInvalid    = errors.New("invalid amount")
+	ErrOverflow   = errors.New("amount is too large")
+	ErrTooPrecise = fmt.Errorf("amount has more than %d decimal places", Scale)
+)
+
+// Money nilai uang, zero value = 0
+type Money struct {
+	units int64 // nilai * 10^Scale
+}
+
+// New dari bagian bulat + pecahan dalam 10^-Scale, mis. New(12, 5000) = 12.5
+func New(whole, frac int64) Money {
+	return Money{units: whole*unit + frac}
+}
+
+// FromMinor dari satuan terkecil mata uang, mis. FromMinor(1250, 2) = 12.50
+func FromMinor(minor int64, exponent int) Money {
+	return Money{units: minor * pow10(Scale-exponent)}
+}
+
+// Parse string desimal seperti "12", "12.5", "-0.25". Tidak menerima notasi eksponen.
+func Parse(s string) (Money, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Money{}, ErrInvalid
+	}
+
+	neg := false
+	switch s[0] {
+	case '-':
+		neg = true
+		s = s[1:]
+	case '+':
+		s = s[1:]
+	}
+
+	whole, frac, hasDot := strings.Cut(s, ".")
+	if whole == "" && frac == "" {
+		return Money{}, ErrInvalid
+	}
+	if hasDot && frac == "" {
+		return Money{}, ErrInvalid
+	}
+	if !digitsOnly(whole) || !digitsOnly(frac) {
+		return Money{}, ErrInvalid
+	}
+
+	// nol di belakang koma tidak menambah presisi: "1.50000" tetap valid
+	frac = strings.TrimRight(frac, "0")
+	if len(frac) > Scale {
+		return Money{}, ErrTooPrecise
+	}
+
+	var w int64
+	if whole != "" {
+		v, err := strconv.ParseInt(whole, 10, 64)
+		if err != nil || v > math.MaxInt64/unit {
+			return Money{}, ErrOverflow
+		}
+		w = v
+	}
+
+	var f int64
+	if frac != "" {
+		v, _ := strconv.ParseInt(frac, 10, 64)
+		f = v * pow10(Scale-len(frac))
+	}
+
+	units := w*unit + f
+	if units < 0 {
+		return Money{}, ErrOverflow
+	}
+	if neg {
+		units = -units
+	}
+	return Money{units: units}, nil
+}
+
+// MustParse untuk konstanta di kode, panic kalau tidak valid
+func MustParse(s string) Money {
+	m, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+func (m Money) Add(o Money) Money { return Money{units: m.units + o.units} }
+func (m Money) Sub(o Money) Money { return Money{units: m.units - o.units} }
+func (m Money) Neg() Money        { return Money{units: -m.units} }
+
+// Mul kali bilangan bulat (mis. jumlah bulan), ErrOverflow kalau hasilnya di luar jangkauan Money
+func (m Money) Mul(n int64) (Money, error) {
+	if n == 0 || m.units == 0 {
+		return Money{}, nil
+	}
+	u := m.units * n
+	if u/n != m.units || (n == -1 && m.units == math.MinInt64) {
+		return Money{}, ErrOverflow
+	}
+	return Money{units: u}, nil
+}
+
+// ReachedPercent true kalau m sudah >= pct persen dari total (dihitung dengan big.Int, tidak bisa overflow)
+func (m Money) ReachedPercent(total Money, pct int64) bool {
+	lhs := new(big.Int).Mul(big.NewInt(m.units), big.NewInt(100))
+	rhs := new(big.Int).Mul(big.NewInt(total.units), big.NewInt(pct))
+	return lhs.Cmp(rhs) >= 0
+}
+
+// Cmp -1, 0, 1 seperti bytes.Compare
+func (m Money) Cmp(o Money) int {
+	switch {
+	case m.units < o.units:
+		return -1
+	case m.units > o.units:
+		return 1
+	}
+	return 0
+}
+
+func (m Money) IsZero() bool     { return m.units == 0 }
+func (m Money) IsPositive() bool { return m.units > 0 }
+func (m Money) IsNegative() bool { return m.units < 0 }
+
+// Float64 cuma untuk rasio / tampilan (mis. persen), jangan dipakai untuk hitung uang
+func (m Money) Float64() float64 { return float64(m.units) / unit }
+
+// Decimals jumlah digit desimal yang benar-benar dipakai (12.50 → 1)
+func (m Money) Decimals() int {
+	u := m.units
+	if u < 0 {
+		u = -u
+	}
+	d := Scale
+	for d > 0 && u%10 == 0 {
+		u /= 10
+		d--
+	}
+	return d
+}
+
+// Round bulatkan ke sejumlah digit desimal (half away from zero)
+func (m Money) Round(places int) Money {
+	if places >= Scale {
+		return m
+	}
+	if places < 0 {
+		places = 0
+	}
+	p := pow10(Scale - places)
+	q, r := m.units/p, m.units%p
+	if r*2 >= p {
+		q++
+	} else if r*2 <= -p {
+		q--
+	}
+	return Money{units: q * p}
+}
+
+// String minimal 2 digit desimal, digit nol di belakang dibuang ("12.50", "1.234", "5.00")
+func (m Money) String() string {
+	places := m.Decimals()
+	if places < 2 {
+		places = 2
+	}
+	return m.StringFixed(places)
+}
+
+// StringFixed tepat sejumlah digit desimal (dibulatkan kalau perlu)
+func (m Money) StringFixed(places int) string {
+	if places > Scale {
+		places = Scale
+	}
+	if places < 0 {
+		places = 0
+	}
+	r := m.Round(places)
+
+	u := r.units
+	sign := ""
+	if u < 0 {
+		sign = "-"
+		u = -u
+	}
+	whole := strconv.FormatInt(u/unit, 10)
+	if places == 0 {
+		return sign + whole
+	}
+	frac := fmt.Sprintf("%0*d", Scale, u%unit)[:places]
+	return sign + whole + "." + frac
+}
+
+// MarshalJSON ditulis sebagai JSON number dengan digit desimal yang pasti, mis. 12.50
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON terima number (12.5) maupun string ("12.50")
+func (m *Money) UnmarshalJSON(b []byte) error {
+	s := strings.TrimSpace(string(b))
+	if s == "null" {
+		return nil
+	}
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		s = s[1 : len(s)-1]
+	}
+
+	v, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
+
+// Value simpan ke kolom NUMERIC sebagai teks supaya tidak lewat float
+func (m Money) Value() (driver.Value, error) {
+	return m.StringFixed(Scale), nil
+}
+
+// Scan baca dari kolom NUMERIC (driver bisa kirim string, []byte, int atau float)
+func (m *Money) Scan(src interface{}) error {
+	var (
+		v   Money
+		err error
+	)
+
+	switch x := src.(type) {
+	case nil:
+		v = Money{}
+	case string:
+		v, err = parseRounded(x)
+	case []byte:
+		v, err = parseRounded(string(x))
+	case int64:
+		v = Money{units: x * unit}
+	case float64:
+		v, err = Parse(strconv.FormatFloat(x, 'f', Scale, 64))
+	default:
+		return fmt.Errorf("money: cannot scan %T", src)
+	}
+	if err != nil {
+		return fmt.Errorf("money: %w", err)
+	}
+
+	*m = v
+	return nil
+}
+
+// parseRounded seperti Parse, tapi hasil agregat (mis. AVG) yang digit desimalnya lebih dari Scale dibulatkan
+func parseRounded(s string) (Money, error) {
+	v, err := Parse(s)
+	if !errors.Is(err, ErrTooPrecise) {
+		return v, err
+	}
+
+	whole, frac, _ := strings.Cut(strings.TrimSpace(s), ".")
+	v, err = Parse(whole + "." + frac[:Scale])
+	if err != nil {
+		return v, err
+	}
+	if frac[Scale] >= '5' {
+		step := Money{units: 1}
+		if v.IsNegative() || strings.HasPrefix(whole, "-") {
+			step = step.Neg()
+		}
+		v = v.Add(step)
+	}
+	return v, nil
+}
+
+// GormDataType tipe kolom untuk gorm
+func (Money) GormDataType() string { return "numeric" }
+
+func digitsOnly(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func pow10(n int) int64 {
+	p := int64(1)
+	for i := 0; i < n; i++ {
+		p *= 10
+	}
+	return p
+}
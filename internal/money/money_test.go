@@ -0,0 +1,334 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string // StringFixed(Scale)
+		wantErr error
+	}{
+		{"12", "12.0000", nil},
+		{"12.5", "12.5000", nil},
+		{"-0.25", "-0.2500", nil},
+		{"+7.01", "7.0100", nil},
+		{" 3.1 ", "3.1000", nil},
+		{".5", "0.5000", nil},
+		{"-.5", "-0.5000", nil},
+		{"0.0001", "0.0001", nil},
+		{"-0", "0.0000", nil},
+		{"1.50000000", "1.5000", nil}, // nol di belakang koma tidak dihitung presisi
+		{"922337203685477", "922337203685477.0000", nil},
+		{"-922337203685477.5807", "-922337203685477.5807", nil},
+
+		{"", "", ErrInvalid},
+		{"-", "", ErrInvalid},
+		{".", "", ErrInvalid},
+		{"1.", "", ErrInvalid},
+		{"--1", "", ErrInvalid},
+		{"+-1", "", ErrInvalid},
+		{"1e3", "", ErrInvalid},
+		{"1,5", "", ErrInvalid},
+		{"1.2.3", "", ErrInvalid},
+		{"abc", "", ErrInvalid},
+		{"0x10", "", ErrInvalid},
+
+		{"0.00001", "", ErrTooPrecise},
+		{"-1.23456", "", ErrTooPrecise},
+
+		{"922337203685478", "", ErrOverflow},
+		{"922337203685477.9999", "", ErrOverflow},
+		{"99999999999999999999", "", ErrOverflow},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := Parse(tt.in)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Parse(%q) err = %v, want %v", tt.in, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.in, err)
+			}
+			if s := got.StringFixed(Scale); s != tt.want {
+				t.Fatalf("Parse(%q) = %s, want %s", tt.in, s, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRounded(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"1.5", "1.5000"},
+		{"1.00004", "1.0000"},
+		{"1.00005", "1.0001"},
+		{"1.99995", "2.0000"},
+		{"-1.00005", "-1.0001"},
+		{"-0.00005", "-0.0001"},
+		{"-0.00004", "0.0000"},
+		{"0.00005", "0.0001"},
+		{"12.3456789", "12.3457"},
+		{"-12.3456489", "-12.3456"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := parseRounded(tt.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s := got.StringFixed(Scale); s != tt.want {
+				t.Fatalf("parseRounded(%q) = %s, want %s", tt.in, s, tt.want)
+			}
+		})
+	}
+
+	if _, err := parseRounded("1.2x345"); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("err = %v, want ErrInvalid", err)
+	}
+}
+
+func TestRound(t *testing.T) {
+	tests := []struct {
+		in     string
+		places int
+		want   string
+	}{
+		{"1.005", 2, "1.01"},
+		{"1.0049", 2, "1.00"},
+		{"-1.005", 2, "-1.01"},
+		{"-1.0049", 2, "-1.00"},
+		{"2.5", 0, "3"},
+		{"-2.5", 0, "-3"},
+		{"0.4999", 0, "0"},
+		{"-0.5", 0, "-1"},
+		{"1.2345", 3, "1.235"},
+		{"1.2345", 4, "1.2345"},
+		{"1.2345", 9, "1.2345"},
+		{"19.99", -1, "20"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got := MustParse(tt.in).Round(tt.places)
+			if got.Cmp(MustParse(tt.want)) != 0 {
+				t.Fatalf("Round(%s, %d) = %s, want %s", tt.in, tt.places, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		in   Money
+		want string
+	}{
+		{Money{}, "0.00"},
+		{New(12, 5000), "12.50"},
+		{New(5, 0), "5.00"},
+		{MustParse("1.234"), "1.234"},
+		{MustParse("-0.0001"), "-0.0001"},
+		{FromMinor(1250, 2), "12.50"},
+		{FromMinor(1500, 0), "1500.00"},
+		{FromMinor(-1, 3), "-0.001"},
+	}
+	for _, tt := range tests {
+		if got := tt.in.String(); got != tt.want {
+			t.Errorf("String() = %q, want %q", got, tt.want)
+		}
+	}
+
+	if got := MustParse("1.2345").StringFixed(2); got != "1.23" {
+		t.Errorf("StringFixed(2) = %q", got)
+	}
+	if got := MustParse("-0.004").StringFixed(2); got != "0.00" {
+		t.Errorf("StringFixed(2) of -0.004 = %q", got)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{`12.5`, "12.50", false},
+		{`"12.50"`, "12.50", false},
+		{`-3`, "-3.00", false},
+		{`"-0.0001"`, "-0.0001", false},
+		{`0.1`, "0.10", false}, // number dibaca sebagai teks, tidak lewat float
+		{`1e2`, "", true},
+		{`"abc"`, "", true},
+		{`"1.23456"`, "", true},
+		{`true`, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			var v struct {
+				Amount Money `json:"amount"`
+			}
+			err := json.Unmarshal([]byte(`{"amount":`+tt.in+`}`), &v)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Unmarshal(%s) = %s, want error", tt.in, v.Amount)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := v.Amount.String(); got != tt.want {
+				t.Fatalf("Unmarshal(%s) = %s, want %s", tt.in, got, tt.want)
+			}
+
+			out, err := json.Marshal(v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := `{"amount":` + tt.want + `}`; string(out) != want {
+				t.Fatalf("Marshal = %s, want %s", out, want)
+			}
+		})
+	}
+
+	// null tidak mengubah nilai yang sudah ada
+	m := New(1, 0)
+	if err := json.Unmarshal([]byte(`null`), &m); err != nil || m.Cmp(New(1, 0)) != 0 {
+		t.Fatalf("Unmarshal(null) = %s, %v", m, err)
+	}
+}
+
+func TestScan(t *testing.T) {
+	tests := []struct {
+		src  interface{}
+		want string
+	}{
+		{nil, "0.00"},
+		{"12.3400", "12.34"},
+		{[]byte("-5.5"), "-5.50"},
+		{"3.33333333333", "3.3333"}, // hasil AVG
+		{int64(7), "7.00"},
+		{float64(0.1), "0.10"},
+	}
+	for _, tt := range tests {
+		var m Money
+		if err := m.Scan(tt.src); err != nil {
+			t.Fatalf("Scan(%v): %v", tt.src, err)
+		}
+		if got := m.String(); got != tt.want {
+			t.Errorf("Scan(%v) = %s, want %s", tt.src, got, tt.want)
+		}
+	}
+
+	var m Money
+	if err := m.Scan(true); err == nil {
+		t.Fatal("Scan(bool) accepted")
+	}
+}
+
+func TestCheckCurrency(t *testing.T) {
+	tests := []struct {
+		amount   string
+		currency string
+		ok       bool
+	}{
+		{"1500", "JPY", true},
+		{"1500.5", "JPY", false},
+		{"1500.00", "JPY", true}, // nol di belakang koma tidak dihitung
+		{"12.34", "USD", true},
+		{"12.345", "USD", false},
+		{"12.345", "usd", false},
+		{"1.234", "KWD", true},
+		{"1.2345", "KWD", false},
+		{"1.2345", "CLF", true},
+		{"10000", "IDR", true},
+		{"0.01", "XXX", true}, // tidak dikenal → 2 digit
+		{"0.001", "XXX", false},
+	}
+	for _, tt := range tests {
+		err := MustParse(tt.amount).CheckCurrency(tt.currency)
+		if (err == nil) != tt.ok {
+			t.Errorf("CheckCurrency(%s, %s) err = %v, want ok=%v", tt.amount, tt.currency, err, tt.ok)
+		}
+	}
+}
+
+func TestMul(t *testing.T) {
+	tests := []struct {
+		m       Money
+		n       int64
+		want    Money
+		wantErr bool
+	}{
+		{MustParse("12.5"), 3, MustParse("37.5"), false},
+		{MustParse("-1.25"), -4, MustParse("5"), false},
+		{MustParse("12.5"), 0, Money{}, false},
+		{Money{units: math.MaxInt64}, 1, Money{units: math.MaxInt64}, false},
+		{Money{units: math.MaxInt64}, 2, Money{}, true},
+		{Money{units: math.MinInt64}, -1, Money{}, true},
+		{MustParse("922337203685477"), 100, Money{}, true},
+	}
+	for _, tt := range tests {
+		got, err := tt.m.Mul(tt.n)
+		if tt.wantErr {
+			if !errors.Is(err, ErrOverflow) {
+				t.Errorf("%s.Mul(%d) = %s, %v, want ErrOverflow", tt.m, tt.n, got, err)
+			}
+			continue
+		}
+		if err != nil || got.Cmp(tt.want) != 0 {
+			t.Errorf("%s.Mul(%d) = %s, %v, want %s", tt.m, tt.n, got, err, tt.want)
+		}
+	}
+}
+
+func TestReachedPercent(t *testing.T) {
+	limit := MustParse("200")
+	max := MustParse("922337203685477")
+	tests := []struct {
+		spent Money
+		total Money
+		pct   int64
+		want  bool
+	}{
+		{MustParse("99.99"), limit, 50, false},
+		{MustParse("100"), limit, 50, true},
+		{MustParse("160"), limit, 80, true},
+		{MustParse("199.9999"), limit, 100, false},
+		{MustParse("250"), limit, 100, true},
+		// spent * 100 & limit * pct di luar int64, tidak boleh wrap
+		{max, max, 100, true},
+		{MustParse("922337203685476"), max, 100, false},
+		{max, max, 80, true},
+	}
+	for _, tt := range tests {
+		if got := tt.spent.ReachedPercent(tt.total, tt.pct); got != tt.want {
+			t.Errorf("%s.ReachedPercent(%s, %d) = %v, want %v", tt.spent, tt.total, tt.pct, got, tt.want)
+		}
+	}
+}
+
+func TestConvertOverflow(t *testing.T) {
+	rate, err := ParseRate("16250.5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := MustParse("100").Convert(rate, 2)
+	if err != nil || got.Cmp(MustParse("1625050")) != 0 {
+		t.Fatalf("Convert = %s, %v", got, err)
+	}
+	if _, err := MustParse("922337203685477").Convert(rate, 2); !errors.Is(err, ErrOverflow) {
+		t.Fatalf("err = %v, want ErrOverflow", err)
+	}
+	if _, err := MustParse("1").Convert(Rate{}, 2); !errors.Is(err, ErrInvalidRate) {
+		t.Fatalf("err = %v, want ErrInvalidRate", err)
+	}
+}
@@ -0,0 +1,146 @@
+package money
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// rateDecimals digit desimal saat rate ditulis ke DB / JSON (kolom NUMERIC(24,10))
+const rateDecimals = 10
+
+var ErrInvalidRate = errors.New("invalid exchange rate")
+
+// Rate kurs yang pasti (pecahan rasional), 1 unit mata uang asal = Rate unit mata uang tujuan
+type Rate struct {
+	r *big.Rat
+}
+
+// ParseRate dari string desimal, harus > 0
+func ParseRate(s string) (Rate, error) {
+	r, ok := new(big.Rat).SetString(strings.TrimSpace(s))
+	if !ok || r.Sign() <= 0 || strings.ContainsAny(s, "eE/") {
+		return Rate{}, ErrInvalidRate
+	}
+	return Rate{r: r}, nil
+}
+
+// OneRate kurs 1:1 (mata uang sama)
+func OneRate() Rate { return Rate{r: big.NewRat(1, 1)} }
+
+func (r Rate) IsZero() bool { return r.r == nil || r.r.Sign() == 0 }
+
+// Inverse kurs kebalikan (tujuan → asal)
+func (r Rate) Inverse() Rate {
+	if r.IsZero() {
+		return r
+	}
+	return Rate{r: new(big.Rat).Inv(r.r)}
+}
+
+// Mul gabungkan dua kurs (A→B x B→C = A→C)
+func (r Rate) Mul(o Rate) Rate {
+	if r.IsZero() || o.IsZero() {
+		return Rate{}
+	}
+	return Rate{r: new(big.Rat).Mul(r.r, o.r)}
+}
+
+func (r Rate) String() string {
+	if r.IsZero() {
+		return "0"
+	}
+	s := r.r.FloatString(rateDecimals)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// Convert kalikan amount dengan kurs lalu bulatkan ke digit desimal mata uang tujuan.
+// ErrOverflow kalau hasilnya di luar jangkauan Money.
+func (m Money) Convert(r Rate, exponent int) (Money, error) {
+	if r.IsZero() {
+		return Money{}, ErrInvalidRate
+	}
+
+	// units * num / den, dibulatkan half away from zero
+	n := new(big.Int).Mul(big.NewInt(m.units), r.r.Num())
+	q, rem := new(big.Int).QuoRem(n, r.r.Denom(), new(big.Int))
+	rem.Abs(rem).Mul(rem, big.NewInt(2))
+	if rem.Cmp(r.r.Denom()) >= 0 {
+		if n.Sign() < 0 {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	if !q.IsInt64() {
+		return Money{}, ErrOverflow
+	}
+	return Money{units: q.Int64()}.Round(exponent), nil
+}
+
+// MulDiv m * num / den dibulatkan ke exponent digit desimal, den harus > 0
+func (m Money) MulDiv(num, den int64, exponent int) (Money, error) {
+	return m.Convert(Rate{r: big.NewRat(num, den)}, exponent)
+}
+
+// MarshalJSON ditulis sebagai JSON number, mis. 15234.5
+func (r Rate) MarshalJSON() ([]byte, error) {
+	if r.IsZero() {
+		return []byte("null"), nil
+	}
+	return []byte(r.String()), nil
+}
+
+// UnmarshalJSON terima number maupun string
+func (r *Rate) UnmarshalJSON(b []byte) error {
+	s := strings.TrimSpace(string(b))
+	if s == "null" {
+		return nil
+	}
+	s = strings.Trim(s, `"`)
+
+	v, err := ParseRate(s)
+	if err != nil {
+		return err
+	}
+	*r = v
+	return nil
+}
+
+func (r Rate) Value() (driver.Value, error) {
+	if r.IsZero() {
+		return nil, nil
+	}
+	return r.r.FloatString(rateDecimals), nil
+}
+
+func (r *Rate) Scan(src interface{}) error {
+	var s string
+	switch x := src.(type) {
+	case nil:
+		*r = Rate{}
+		return nil
+	case string:
+		s = x
+	case []byte:
+		s = string(x)
+	case float64:
+		s = fmt.Sprintf("%.*f", rateDecimals, x)
+	case int64:
+		s = fmt.Sprint(x)
+	default:
+		return fmt.Errorf("money: cannot scan %T into rate", src)
+	}
+
+	v, err := ParseRate(s)
+	if err != nil {
+		return err
+	}
+	*r = v
+	return nil
+}
+
+func (Rate) GormDataType() string { return "numeric" }
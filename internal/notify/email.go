@@ -0,0 +1,26 @@
+package notify
+
+import (
+	"context"
+
+	"github.com/rifqi535/expense-tracker-api/internal/mailer"
+	"github.com/rifqi535/expense-tracker-api/internal/models"
+)
+
+// Email kirim notifikasi lewat mailer.Mailer, cuma ke email yang sudah diverifikasi
+type Email struct {
+	mail mailer.Mailer
+}
+
+func NewEmail(mail mailer.Mailer) *Email { return &Email{mail: mail} }
+
+func (c *Email) Name() string { return "email" }
+
+func (c *Email) Enabled(s *models.NotificationSettings) bool { return s.EmailEnabled }
+
+func (c *Email) Deliver(ctx context.Context, user *models.User, _ *models.NotificationSettings, n *models.Notification) error {
+	if user.EmailVerifiedAt == nil {
+		return nil
+	}
+	return c.mail.Send(ctx, mailer.Message{To: user.Email, Subject: n.Title, Body: n.Body})
+}
@@ -0,0 +1,82 @@
+// Package notify kirim notifikasi ke user lewat beberapa channel (in-app, email, webhook)
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rifqi535/expense-tracker-api/internal/models"
+	"github.com/rifqi535/expense-tracker-api/internal/repository"
+)
+
+// Channel satu cara pengiriman. Enabled dicek dulu dengan setting user sebelum Deliver.
+type Channel interface {
+	Name() string
+	Enabled(s *models.NotificationSettings) bool
+	Deliver(ctx context.Context, user *models.User, s *models.NotificationSettings, n *models.Notification) error
+}
+
+// Dispatcher kirim notifikasi ke semua channel yang aktif untuk user
+type Dispatcher struct {
+	users    *repository.UserRepo
+	repo     *repository.NotificationRepo
+	channels []Channel
+}
+
+func NewDispatcher(users *repository.UserRepo, repo *repository.NotificationRepo, channels ...Channel) *Dispatcher {
+	return &Dispatcher{users: users, repo: repo, channels: channels}
+}
+
+// Send isi ID / CreatedAt kalau kosong lalu kirim ke tiap channel.
+// Channel yang gagal tidak menghentikan channel lain, semua error digabung.
+func (d *Dispatcher) Send(ctx context.Context, n *models.Notification) error {
+	if n.ID == uuid.Nil {
+		n.ID = uuid.New()
+	}
+	if n.CreatedAt.IsZero() {
+		n.CreatedAt = time.Now()
+	}
+	if n.Data == nil {
+		n.Data = map[string]interface{}{}
+	}
+
+	user, err := d.users.GetByID(ctx, n.UserID)
+	if err != nil {
+		return err
+	}
+	settings, err := d.repo.GetSettings(ctx, n.UserID)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, ch := range d.channels {
+		if !ch.Enabled(settings) {
+			continue
+		}
+		if err := ch.Deliver(ctx, user, settings, n); err != nil {
+			log.Printf("❌ gagal kirim notifikasi %s lewat %s: %v", n.ID, ch.Name(), err)
+			errs = append(errs, fmt.Errorf("%s: %w", ch.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// InApp simpan notifikasi ke inbox, selalu aktif
+type InApp struct {
+	repo *repository.NotificationRepo
+}
+
+func NewInApp(repo *repository.NotificationRepo) *InApp { return &InApp{repo: repo} }
+
+func (c *InApp) Name() string { return "in_app" }
+
+func (c *InApp) Enabled(*models.NotificationSettings) bool { return true }
+
+func (c *InApp) Deliver(ctx context.Context, _ *models.User, _ *models.NotificationSettings, n *models.Notification) error {
+	return c.repo.Create(ctx, n)
+}
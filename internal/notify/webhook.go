@@ -0,0 +1,183 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/rifqi535/expense-tracker-api/internal/models"
+)
+
+var (
+	ErrInvalidWebhookURL = errors.New("webhook_url must be an absolute http or https URL")
+	errPrivateAddress    = errors.New("webhook address is not public")
+)
+
+// Webhook POST JSON notifikasi ke URL user.
+// Header X-Webhook-Signature = "sha256=" + hex(HMAC-SHA256(secret, timestamp + "." + body)),
+// timestamp (unix detik) ada di X-Webhook-Timestamp supaya penerima bisa tolak replay.
+type Webhook struct {
+	client *http.Client
+}
+
+// NewWebhook allowPrivate=false → alamat loopback / privat / link-local ditolak (cegah SSRF ke jaringan internal)
+func NewWebhook(timeout time.Duration, allowPrivate bool) *Webhook {
+	if allowPrivate {
+		return newWebhook(timeout, nil)
+	}
+	return newWebhook(timeout, publicIP)
+}
+
+// newWebhook allowed = filter IP tujuan (nil = semua boleh)
+func newWebhook(timeout time.Duration, allowed func(netip.Addr) bool) *Webhook {
+	dialer := &net.Dialer{Timeout: timeout}
+	if allowed != nil {
+		// dicek di IP hasil resolve, jadi DNS yang mengarah ke alamat internal juga ketahuan
+		dialer.Control = func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip, err := netip.ParseAddr(host)
+			if err != nil || !allowed(ip) {
+				return errPrivateAddress
+			}
+			return nil
+		}
+	}
+	transport := &http.Transport{DialContext: dialer.DialContext}
+	return &Webhook{client: &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		// redirect tidak diikuti, URL tujuan cuma yang didaftarkan user
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}}
+}
+
+func (c *Webhook) Name() string { return "webhook" }
+
+func (c *Webhook) Enabled(s *models.NotificationSettings) bool {
+	return s.WebhookURL != nil && *s.WebhookURL != ""
+}
+
+// webhookPayload body JSON yang dikirim
+type webhookPayload struct {
+	ID        string                 `json:"id"`
+	Kind      string                 `json:"kind"`
+	Title     string                 `json:"title"`
+	Body      string                 `json:"body"`
+	Data      map[string]interface{} `json:"data"`
+	CreatedAt time.Time              `json:"created_at"`
+}
+
+func (c *Webhook) Deliver(ctx context.Context, _ *models.User, s *models.NotificationSettings, n *models.Notification) error {
+	body, err := json.Marshal(webhookPayload{
+		ID:        n.ID.String(),
+		Kind:      n.Kind,
+		Title:     n.Title,
+		Body:      n.Body,
+		Data:      n.Data,
+		CreatedAt: n.CreatedAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, *s.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "expense-tracker-webhook")
+	req.Header.Set("X-Webhook-Timestamp", ts)
+	if s.WebhookSecret != nil {
+		req.Header.Set("X-Webhook-Signature", Sign(*s.WebhookSecret, ts, body))
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Sign signature webhook, dipakai juga penerima untuk verifikasi
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// ValidateWebhookURL cuma cek format, alamat tujuan dicek waktu kirim
+func ValidateWebhookURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.User != nil {
+		return ErrInvalidWebhookURL
+	}
+	return nil
+}
+
+// nonPublicPrefixes rentang alamat yang tidak boleh jadi tujuan webhook (IANA special-purpose registry)
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),       // "this network"
+	netip.MustParsePrefix("10.0.0.0/8"),      // privat
+	netip.MustParsePrefix("100.64.0.0/10"),   // CGNAT, termasuk metadata cloud 100.100.100.200
+	netip.MustParsePrefix("127.0.0.0/8"),     // loopback
+	netip.MustParsePrefix("169.254.0.0/16"),  // link-local, termasuk metadata 169.254.169.254
+	netip.MustParsePrefix("172.16.0.0/12"),   // privat
+	netip.MustParsePrefix("192.0.0.0/24"),    // IETF protocol assignments
+	netip.MustParsePrefix("192.0.2.0/24"),    // dokumentasi
+	netip.MustParsePrefix("192.88.99.0/24"),  // 6to4 relay
+	netip.MustParsePrefix("192.168.0.0/16"),  // privat
+	netip.MustParsePrefix("198.18.0.0/15"),   // benchmarking
+	netip.MustParsePrefix("198.51.100.0/24"), // dokumentasi
+	netip.MustParsePrefix("203.0.113.0/24"),  // dokumentasi
+	netip.MustParsePrefix("224.0.0.0/4"),     // multicast
+	netip.MustParsePrefix("240.0.0.0/4"),     // reserved + broadcast
+	netip.MustParsePrefix("::/128"),          // unspecified
+	netip.MustParsePrefix("::1/128"),         // loopback
+	netip.MustParsePrefix("64:ff9b::/96"),    // NAT64, bisa mengarah ke IPv4 mana saja
+	netip.MustParsePrefix("64:ff9b:1::/48"),  // NAT64 lokal
+	netip.MustParsePrefix("100::/64"),        // discard
+	netip.MustParsePrefix("2001:db8::/32"),   // dokumentasi
+	netip.MustParsePrefix("2002::/16"),       // 6to4, bisa membungkus IPv4 privat
+	netip.MustParsePrefix("fc00::/7"),        // unique local
+	netip.MustParsePrefix("fe80::/10"),       // link-local
+	netip.MustParsePrefix("ff00::/8"),        // multicast
+}
+
+// publicIP false untuk alamat di nonPublicPrefixes, IPv4-mapped IPv6 (::ffff:a.b.c.d) dicek sebagai IPv4
+func publicIP(ip netip.Addr) bool {
+	ip = ip.Unmap().WithZone("")
+	if !ip.IsValid() {
+		return false
+	}
+	for _, p := range nonPublicPrefixes {
+		if p.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
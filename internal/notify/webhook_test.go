@@ -0,0 +1,183 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rifqi535/expense-tracker-api/internal/models"
+)
+
+func TestPublicIP(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"8.8.8.8", true},
+		{"1.1.1.1", true},
+		{"100.63.255.255", true},
+		{"100.128.0.1", true},
+		{"198.17.255.255", true},
+		{"198.20.0.1", true},
+		{"2606:4700:4700::1111", true},
+		{"::ffff:8.8.8.8", true},
+
+		{"0.0.0.0", false},
+		{"0.1.2.3", false},
+		{"10.1.2.3", false},
+		{"100.64.0.1", false},
+		{"100.100.100.200", false}, // metadata cloud
+		{"127.0.0.1", false},
+		{"169.254.169.254", false}, // metadata cloud
+		{"172.16.0.1", false},
+		{"172.31.255.255", false},
+		{"192.0.0.8", false},
+		{"192.168.1.1", false},
+		{"198.18.0.1", false},
+		{"198.19.255.255", false},
+		{"224.0.0.1", false},
+		{"255.255.255.255", false},
+		{"::", false},
+		{"::1", false},
+		{"::ffff:127.0.0.1", false},
+		{"::ffff:169.254.169.254", false},
+		{"64:ff9b::a9fe:a9fe", false}, // NAT64 ke 169.254.169.254
+		{"64:ff9b::808:808", false},
+		{"2002:a00:1::1", false},
+		{"fc00::1", false},
+		{"fd12:3456::1", false},
+		{"fe80::1", false},
+		{"fe80::1%eth0", false},
+		{"ff02::1", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			if got := publicIP(netip.MustParseAddr(tt.ip)); got != tt.want {
+				t.Fatalf("publicIP(%s) = %v, want %v", tt.ip, got, tt.want)
+			}
+		})
+	}
+
+	if publicIP(netip.Addr{}) {
+		t.Fatal("zero Addr is public")
+	}
+}
+
+func webhookFixture(url string) (*models.NotificationSettings, *models.Notification) {
+	secret := "s3cret"
+	return &models.NotificationSettings{WebhookURL: &url, WebhookSecret: &secret},
+		&models.Notification{
+			ID:        uuid.New(),
+			Kind:      "budget_alert",
+			Title:     "Budget Food 80%",
+			Body:      "spent 80.00 of 100.00 IDR",
+			Data:      map[string]interface{}{"threshold": 80},
+			CreatedAt: time.Date(2026, 3, 10, 8, 0, 0, 0, time.UTC),
+		}
+}
+
+func TestWebhookSignRoundTrip(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		verified bool
+		payload  webhookPayload
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		body, _ := io.ReadAll(r.Body)
+		ts := r.Header.Get("X-Webhook-Timestamp")
+		if _, err := strconv.ParseInt(ts, 10, 64); err != nil {
+			http.Error(w, "bad timestamp", http.StatusBadRequest)
+			return
+		}
+		// penerima hitung ulang signature dari timestamp + body mentah
+		verified = r.Header.Get("X-Webhook-Signature") == Sign("s3cret", ts, body)
+		_ = json.Unmarshal(body, &payload)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	// hook Control tetap dipasang, loopback diizinkan khusus test ini
+	var (
+		dialMu sync.Mutex
+		dialed []netip.Addr
+	)
+	wh := newWebhook(5*time.Second, func(ip netip.Addr) bool {
+		dialMu.Lock()
+		dialed = append(dialed, ip)
+		dialMu.Unlock()
+		return ip.IsLoopback()
+	})
+
+	settings, n := webhookFixture(srv.URL + "/hook")
+	if err := wh.Deliver(context.Background(), nil, settings, n); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if !verified {
+		t.Fatal("signature did not verify")
+	}
+	if payload.ID != n.ID.String() || payload.Title != n.Title || payload.Kind != n.Kind {
+		t.Fatalf("payload = %+v", payload)
+	}
+	if len(dialed) == 0 || !dialed[0].IsLoopback() {
+		t.Fatalf("Control hook saw %v", dialed)
+	}
+
+	// body yang diubah tidak lolos verifikasi
+	if Sign("s3cret", "1", []byte(`{"a":1}`)) == Sign("s3cret", "1", []byte(`{"a":2}`)) ||
+		Sign("s3cret", "1", []byte(`{}`)) == Sign("other", "1", []byte(`{}`)) ||
+		Sign("s3cret", "1", []byte(`{}`)) == Sign("s3cret", "2", []byte(`{}`)) {
+		t.Fatal("signature does not cover secret, timestamp and body")
+	}
+}
+
+func TestWebhookRejectsPrivateAddress(t *testing.T) {
+	called := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer srv.Close()
+
+	settings, n := webhookFixture(srv.URL)
+	err := NewWebhook(5*time.Second, false).Deliver(context.Background(), nil, settings, n)
+	if !errors.Is(err, errPrivateAddress) {
+		t.Fatalf("err = %v, want errPrivateAddress", err)
+	}
+	if called {
+		t.Fatal("request reached the private address")
+	}
+
+	if err := NewWebhook(5*time.Second, true).Deliver(context.Background(), nil, settings, n); err != nil {
+		t.Fatalf("allowPrivate: %v", err)
+	}
+	if !called {
+		t.Fatal("allowPrivate did not deliver")
+	}
+}
+
+func TestWebhookErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// redirect tidak diikuti
+		http.Redirect(w, r, "http://169.254.169.254/", http.StatusFound)
+	}))
+	defer srv.Close()
+
+	settings, n := webhookFixture(srv.URL)
+	err := NewWebhook(5*time.Second, true).Deliver(context.Background(), nil, settings, n)
+	if err == nil || err.Error() != "webhook responded 302" {
+		t.Fatalf("err = %v, want webhook responded 302", err)
+	}
+}
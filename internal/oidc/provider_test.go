@@ -0,0 +1,291 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// stubIssuer issuer OIDC lokal: discovery, JWKS, dan token endpoint yang cek PKCE
+type stubIssuer struct {
+	t      *testing.T
+	srv    *httptest.Server
+	key    *rsa.PrivateKey // dipublikasikan di JWKS
+	signer *rsa.PrivateKey // nil = key
+	kid    string
+	code   string
+	chal   string               // code_challenge yang diharapkan untuk code
+	claims func() jwt.MapClaims // isi id_token
+}
+
+func newStubIssuer(t *testing.T) *stubIssuer {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &stubIssuer{t: t, key: key, kid: "stub-1", code: "good-code"}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 s.srv.URL,
+			"authorization_endpoint": s.srv.URL + "/authorize",
+			"token_endpoint":         s.srv.URL + "/token",
+			"jwks_uri":               s.srv.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		b64 := base64.RawURLEncoding
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": s.kid,
+			"use": "sig",
+			"n":   b64.EncodeToString(key.N.Bytes()),
+			"e":   b64.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if r.PostForm.Get("grant_type") != "authorization_code" || r.PostForm.Get("code") != s.code {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		if ChallengeS256(r.PostForm.Get("code_verifier")) != s.chal {
+			http.Error(w, `{"error":"invalid_grant","error_description":"pkce"}`, http.StatusBadRequest)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"id_token": s.sign(s.claims())})
+	})
+	s.srv = httptest.NewServer(mux)
+	t.Cleanup(s.srv.Close)
+	return s
+}
+
+func (s *stubIssuer) sign(claims jwt.MapClaims) string {
+	tok := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	tok.Header["kid"] = s.kid
+	signer := s.signer
+	if signer == nil {
+		signer = s.key
+	}
+	raw, err := tok.SignedString(signer)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	return raw
+}
+
+func (s *stubIssuer) provider() *Provider {
+	return NewProvider(Config{
+		Name:        "stub",
+		Issuer:      s.srv.URL,
+		ClientID:    "client-1",
+		RedirectURL: "http://localhost/callback",
+	})
+}
+
+func validClaims(s *stubIssuer, nonce string) jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":            s.srv.URL,
+		"aud":            "client-1",
+		"sub":            "subject-1",
+		"email":          "user@example.com",
+		"email_verified": true,
+		"name":           "Stub User",
+		"nonce":          nonce,
+		"exp":            time.Now().Add(time.Hour).Unix(),
+		"iat":            time.Now().Unix(),
+	}
+}
+
+func TestAuthCodeURLUsesDiscoveryAndPKCE(t *testing.T) {
+	s := newStubIssuer(t)
+
+	raw, err := s.provider().AuthCodeURL(context.Background(), "state-1", "nonce-1", ChallengeS256("verifier"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := u.Scheme + "://" + u.Host + u.Path; got != s.srv.URL+"/authorize" {
+		t.Fatalf("auth endpoint = %q", got)
+	}
+	q := u.Query()
+	want := map[string]string{
+		"response_type":         "code",
+		"client_id":             "client-1",
+		"state":                 "state-1",
+		"nonce":                 "nonce-1",
+		"code_challenge":        ChallengeS256("verifier"),
+		"code_challenge_method": "S256",
+	}
+	for k, v := range want {
+		if q.Get(k) != v {
+			t.Errorf("%s = %q, want %q", k, q.Get(k), v)
+		}
+	}
+}
+
+func TestExchange(t *testing.T) {
+	const verifier, nonce = "the-verifier", "the-nonce"
+
+	tests := []struct {
+		name     string
+		code     string
+		verifier string
+		claims   func(s *stubIssuer) jwt.MapClaims
+		want     *IDToken
+		wantErr  string
+	}{
+		{
+			name:     "ok",
+			code:     "good-code",
+			verifier: verifier,
+			claims:   func(s *stubIssuer) jwt.MapClaims { return validClaims(s, nonce) },
+			want:     &IDToken{Subject: "subject-1", Email: "user@example.com", EmailVerified: true, Name: "Stub User"},
+		},
+		{
+			name:     "email_verified as string",
+			code:     "good-code",
+			verifier: verifier,
+			claims: func(s *stubIssuer) jwt.MapClaims {
+				c := validClaims(s, nonce)
+				c["email_verified"] = "true"
+				return c
+			},
+			want: &IDToken{Subject: "subject-1", Email: "user@example.com", EmailVerified: true, Name: "Stub User"},
+		},
+		{
+			name:     "unverified email is reported",
+			code:     "good-code",
+			verifier: verifier,
+			claims: func(s *stubIssuer) jwt.MapClaims {
+				c := validClaims(s, nonce)
+				c["email_verified"] = false
+				return c
+			},
+			want: &IDToken{Subject: "subject-1", Email: "user@example.com", EmailVerified: false, Name: "Stub User"},
+		},
+		{
+			name:     "wrong code verifier",
+			code:     "good-code",
+			verifier: "other",
+			claims:   func(s *stubIssuer) jwt.MapClaims { return validClaims(s, nonce) },
+			wantErr:  "token endpoint returned 400",
+		},
+		{
+			name:     "wrong code",
+			code:     "bad-code",
+			verifier: verifier,
+			claims:   func(s *stubIssuer) jwt.MapClaims { return validClaims(s, nonce) },
+			wantErr:  "token endpoint returned 400",
+		},
+		{
+			name:     "nonce mismatch",
+			code:     "good-code",
+			verifier: verifier,
+			claims:   func(s *stubIssuer) jwt.MapClaims { return validClaims(s, "replayed") },
+			wantErr:  "nonce mismatch",
+		},
+		{
+			name:     "wrong audience",
+			code:     "good-code",
+			verifier: verifier,
+			claims: func(s *stubIssuer) jwt.MapClaims {
+				c := validClaims(s, nonce)
+				c["aud"] = "someone-else"
+				return c
+			},
+			wantErr: "invalid id_token",
+		},
+		{
+			name:     "wrong issuer",
+			code:     "good-code",
+			verifier: verifier,
+			claims: func(s *stubIssuer) jwt.MapClaims {
+				c := validClaims(s, nonce)
+				c["iss"] = "https://evil.example"
+				return c
+			},
+			wantErr: "invalid id_token",
+		},
+		{
+			name:     "expired",
+			code:     "good-code",
+			verifier: verifier,
+			claims: func(s *stubIssuer) jwt.MapClaims {
+				c := validClaims(s, nonce)
+				c["exp"] = time.Now().Add(-time.Hour).Unix()
+				return c
+			},
+			wantErr: "invalid id_token",
+		},
+		{
+			name:     "missing sub",
+			code:     "good-code",
+			verifier: verifier,
+			claims: func(s *stubIssuer) jwt.MapClaims {
+				c := validClaims(s, nonce)
+				delete(c, "sub")
+				return c
+			},
+			wantErr: "no sub",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newStubIssuer(t)
+			s.chal = ChallengeS256(verifier)
+			s.claims = func() jwt.MapClaims { return tt.claims(s) }
+
+			got, err := s.provider().Exchange(context.Background(), tt.code, tt.verifier, nonce)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *got != *tt.want {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExchangeRejectsForeignKey(t *testing.T) {
+	s := newStubIssuer(t)
+	s.chal = ChallengeS256("v")
+	forger, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// JWKS tetap kunci asli, id_token ditandatangani kunci lain dengan kid yang sama
+	s.signer = forger
+	s.claims = func() jwt.MapClaims { return validClaims(s, "n") }
+
+	_, err = s.provider().Exchange(context.Background(), "good-code", "v", "n")
+	if err == nil || !strings.Contains(err.Error(), "invalid id_token") {
+		t.Fatalf("err = %v, want invalid id_token", err)
+	}
+}
@@ -0,0 +1,69 @@
+// Package recurring scheduler yang membuat expense dari jadwal berulang
+package recurring
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/rifqi535/expense-tracker-api/internal/repository"
+)
+
+// maxPerClaim batas kejadian per jadwal per transaksi, catch-up panjang dicicil
+const maxPerClaim = 500
+
+// Scheduler cek jadwal yang jatuh tempo secara berkala.
+// Setelah server mati, kejadian yang terlewat dibuat saat jalan lagi (catch-up).
+type Scheduler struct {
+	repo     *repository.RecurringExpenseRepo
+	interval time.Duration
+	wake     chan struct{}
+}
+
+func NewScheduler(repo *repository.RecurringExpenseRepo, interval time.Duration) *Scheduler {
+	return &Scheduler{repo: repo, interval: interval, wake: make(chan struct{}, 1)}
+}
+
+// Notify bangunkan scheduler setelah jadwal dibuat / diubah (tidak nge-block)
+func (s *Scheduler) Notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run loop utama, berhenti kalau ctx selesai
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.runDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.wake:
+		}
+	}
+}
+
+// runDue proses semua jadwal yang jatuh tempo sampai habis
+func (s *Scheduler) runDue(ctx context.Context) {
+	var total int64
+	for ctx.Err() == nil {
+		found, created, err := s.repo.MaterializeNext(ctx, time.Now(), maxPerClaim)
+		if err != nil {
+			log.Println("❌ gagal proses expense berulang:", err)
+			return
+		}
+		if !found {
+			break
+		}
+		total += created
+	}
+	if total > 0 {
+		log.Printf("🔁 %d expense berulang dibuat", total)
+	}
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/rifqi535/expense-tracker-api/internal/money"
+	"gorm.io/gorm"
+)
+
+type AdminRepo struct{ db *gorm.DB }
+
+func NewAdminRepo(db *gorm.DB) *AdminRepo { return &AdminRepo{db: db} }
+
+// CurrencyTotal total expense dalam satu mata uang
+type CurrencyTotal struct {
+	Currency string      `json:"currency"`
+	Expenses int64       `json:"expenses"`
+	Total    money.Money `json:"total"`
+}
+
+// SystemStats ringkasan seluruh sistem untuk dashboard admin
+type SystemStats struct {
+	Users           int64 `json:"users"`
+	VerifiedUsers   int64 `json:"verified_users"`
+	DisabledUsers   int64 `json:"disabled_users"`
+	Admins          int64 `json:"admins"`
+	NewUsersLast30d int64 `json:"new_users_last_30d"`
+	Categories      int64 `json:"categories"`
+	Expenses        int64 `json:"expenses"`
+	ExpensesLast30d int64 `json:"expenses_last_30d"`
+	// total tidak dijumlah lintas mata uang, jadi dipisah per currency
+	TotalsByCurrency []CurrencyTotal `gorm:"-" json:"totals_by_currency"`
+	ActiveSessions   int64           `json:"active_sessions"`
+	ActiveAPIKeys    int64           `json:"active_api_keys"`
+	MFAEnabledUsers  int64           `json:"mfa_enabled_users"`
+	LinkedIdentities int64           `json:"linked_identities"`
+}
+
+func (r *AdminRepo) Stats(ctx context.Context) (*SystemStats, error) {
+	var s SystemStats
+	since := time.Now().AddDate(0, 0, -30)
+	now := time.Now()
+
+	err := r.db.WithContext(ctx).Raw(`
+SELECT
+	(SELECT COUNT(*) FROM users) AS users,
+	(SELECT COUNT(*) FROM users WHERE email_verified_at IS NOT NULL) AS verified_users,
+	(SELECT COUNT(*) FROM users WHERE disabled_at IS NOT NULL) AS disabled_users,
+	(SELECT COUNT(*) FROM users WHERE role = 'admin') AS admins,
+	(SELECT COUNT(*) FROM users WHERE created_at >= ?) AS new_users_last30d,
+	(SELECT COUNT(*) FROM categories) AS categories,
+	(SELECT COUNT(*) FROM expenses WHERE deleted_at IS NULL) AS expenses,
+	(SELECT COUNT(*) FROM expenses WHERE deleted_at IS NULL AND spent_at >= ?) AS expenses_last30d,
+	(SELECT COUNT(DISTINCT family_id) FROM refresh_tokens WHERE revoked_at IS NULL AND expires_at > ?) AS active_sessions,
+	(SELECT COUNT(*) FROM api_keys WHERE revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)) AS active_api_keys,
+	(SELECT COUNT(*) FROM users WHERE totp_enabled_at IS NOT NULL) AS mfa_enabled_users,
+	(SELECT COUNT(*) FROM user_identities) AS linked_identities
+`, since, since, now, now).Scan(&s).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.db.WithContext(ctx).Raw(`
+SELECT currency, COUNT(*) AS expenses, SUM(amount) AS total
+FROM expenses
+WHERE deleted_at IS NULL
+GROUP BY currency
+ORDER BY currency`).Scan(&s.TotalsByCurrency).Error
+	if err != nil {
+		return nil, err
+	}
+	if s.TotalsByCurrency == nil {
+		s.TotalsByCurrency = []CurrencyTotal{}
+	}
+	return &s, nil
+}
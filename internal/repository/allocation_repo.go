@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rifqi535/expense-tracker-api/internal/models"
+	"github.com/rifqi535/expense-tracker-api/internal/money"
+	"gorm.io/gorm"
+)
+
+var ErrSameEnvelope = errors.New("source and destination envelope must be different categories")
+
+// AllocationRepo ledger alokasi envelope, baris tidak pernah diubah (koreksi = baris baru / hapus)
+type AllocationRepo struct{ db *gorm.DB }
+
+func NewAllocationRepo(db *gorm.DB) *AllocationRepo { return &AllocationRepo{db: db} }
+
+// ListByMonth: ledger satu bulan (tanggal 1)
+func (r *AllocationRepo) ListByMonth(ctx context.Context, userID uuid.UUID, month time.Time) ([]models.BudgetAllocation, error) {
+	var list []models.BudgetAllocation
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND month = ?", userID, month.Format("2006-01-02")).
+		Order("created_at").
+		Find(&list).Error
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// ListUntil: semua ledger sampai bulan month (inklusif), buat hitung saldo yang dibawa
+func (r *AllocationRepo) ListUntil(ctx context.Context, userID uuid.UUID, month time.Time) ([]models.BudgetAllocation, error) {
+	var list []models.BudgetAllocation
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND month <= ?", userID, month.Format("2006-01-02")).
+		Order("month, created_at").
+		Find(&list).Error
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// ListByUser: semua ledger milik user (buat export)
+func (r *AllocationRepo) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.BudgetAllocation, error) {
+	var list []models.BudgetAllocation
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("month, created_at").
+		Find(&list).Error
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// Create: tambah / kurangi alokasi satu envelope, kategorinya harus milik user
+func (r *AllocationRepo) Create(ctx context.Context, a *models.BudgetAllocation) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkCategory(tx, a.UserID, a.CategoryID); err != nil {
+			return err
+		}
+		return tx.Create(a).Error
+	})
+}
+
+// Transfer: pindahkan amount (positif) dari envelope from ke to di bulan month, dua baris dalam satu transaksi
+func (r *AllocationRepo) Transfer(ctx context.Context, userID, from, to uuid.UUID, month time.Time, amount money.Money, currency string, note *string) ([]models.BudgetAllocation, error) {
+	if from == to {
+		return nil, ErrSameEnvelope
+	}
+
+	now := time.Now()
+	transferID := uuid.New()
+	legs := []models.BudgetAllocation{
+		{ID: uuid.New(), UserID: userID, CategoryID: from, Month: month, Amount: amount.Neg(), Currency: currency, TransferID: &transferID, Note: note, CreatedAt: now},
+		{ID: uuid.New(), UserID: userID, CategoryID: to, Month: month, Amount: amount, Currency: currency, TransferID: &transferID, Note: note, CreatedAt: now},
+	}
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, id := range []uuid.UUID{from, to} {
+			if err := checkCategory(tx, userID, id); err != nil {
+				return err
+			}
+		}
+		return tx.Create(&legs).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return legs, nil
+}
+
+// Delete: hapus satu baris ledger, kalau bagian dari transfer kedua sisinya ikut dihapus
+func (r *AllocationRepo) Delete(ctx context.Context, userID, id uuid.UUID) (bool, error) {
+	var deleted bool
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var a models.BudgetAllocation
+		err := tx.Where("id = ? AND user_id = ?", id, userID).First(&a).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		query := tx.Where("user_id = ?", userID)
+		if a.TransferID != nil {
+			query = query.Where("transfer_id = ?", *a.TransferID)
+		} else {
+			query = query.Where("id = ?", a.ID)
+		}
+		result := query.Delete(&models.BudgetAllocation{})
+		if result.Error != nil {
+			return result.Error
+		}
+		deleted = result.RowsAffected > 0
+		return nil
+	})
+	return deleted, err
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rifqi535/expense-tracker-api/internal/models"
+	"gorm.io/gorm"
+)
+
+// last_used_at tidak di-update tiap request, cukup sekali per interval ini
+const apiKeyTouchInterval = time.Minute
+
+type APIKeyRepo struct{ db *gorm.DB }
+
+func NewAPIKeyRepo(db *gorm.DB) *APIKeyRepo { return &APIKeyRepo{db: db} }
+
+func (r *APIKeyRepo) Create(ctx context.Context, k *models.APIKey) error {
+	return r.db.WithContext(ctx).Create(k).Error
+}
+
+// ListByUser: key yang sudah di-revoke tidak ditampilkan
+func (r *APIKeyRepo) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.APIKey, error) {
+	var keys []models.APIKey
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Order("created_at DESC").
+		Find(&keys).Error
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (r *APIKeyRepo) Rename(ctx context.Context, userID, id uuid.UUID, name string) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&models.APIKey{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Updates(map[string]interface{}{
+			"name":       name,
+			"updated_at": time.Now(),
+		})
+
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *APIKeyRepo) Revoke(ctx context.Context, userID, id uuid.UUID) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&models.APIKey{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Updates(map[string]interface{}{
+			"revoked_at": time.Now(),
+			"updated_at": time.Now(),
+		})
+
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// FindActiveByHash: key yang belum di-revoke, belum expired, dan pemiliknya tidak di-disable / sedang dihapus
+func (r *APIKeyRepo) FindActiveByHash(ctx context.Context, hash string) (*models.APIKey, error) {
+	var k models.APIKey
+
+	err := r.db.WithContext(ctx).
+		Joins("JOIN users u ON u.id = api_keys.user_id AND u.disabled_at IS NULL AND u.deletion_requested_at IS NULL").
+		Where("api_keys.key_hash = ? AND api_keys.revoked_at IS NULL", hash).
+		Where("api_keys.expires_at IS NULL OR api_keys.expires_at > ?", time.Now()).
+		First(&k).Error
+
+	if err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+// TouchLastUsed: catat waktu terakhir key dipakai
+func (r *APIKeyRepo) TouchLastUsed(ctx context.Context, id uuid.UUID) error {
+	now := time.Now()
+	return r.db.WithContext(ctx).
+		Model(&models.APIKey{}).
+		Where("id = ?", id).
+		Where("last_used_at IS NULL OR last_used_at < ?", now.Add(-apiKeyTouchInterval)).
+		Update("last_used_at", now).Error
+}
@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/rifqi535/expense-tracker-api/internal/models"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrAttachmentQuota    = errors.New("attachment storage quota exceeded")
+	ErrTooManyAttachments = errors.New("too many attachments on this expense")
+)
+
+type AttachmentRepo struct{ db *gorm.DB }
+
+// QuotaExceeded true kalau file size byte tidak muat lagi di kuota (quota 0 = tanpa batas)
+func QuotaExceeded(used, size, quota int64) bool {
+	return quota > 0 && used+size > quota
+}
+
+func NewAttachmentRepo(db *gorm.DB) *AttachmentRepo { return &AttachmentRepo{db: db} }
+
+func (r *AttachmentRepo) ListByExpense(ctx context.Context, userID, expenseID uuid.UUID) ([]models.Attachment, error) {
+	var list []models.Attachment
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND expense_id = ?", userID, expenseID).
+		Order("created_at").
+		Find(&list).Error
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// ListByUser: semua lampiran milik user (buat export)
+func (r *AttachmentRepo) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.Attachment, error) {
+	var list []models.Attachment
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at").
+		Find(&list).Error
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// GetByID: nil kalau tidak ada / bukan milik user / bukan lampiran expense ini
+func (r *AttachmentRepo) GetByID(ctx context.Context, userID, expenseID, id uuid.UUID) (*models.Attachment, error) {
+	var a models.Attachment
+	err := r.db.WithContext(ctx).
+		Where("id = ? AND user_id = ? AND expense_id = ?", id, userID, expenseID).
+		First(&a).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// Usage: total ukuran lampiran milik user (byte)
+func (r *AttachmentRepo) Usage(ctx context.Context, userID uuid.UUID) (int64, error) {
+	var used int64
+	err := r.db.WithContext(ctx).
+		Model(&models.Attachment{}).
+		Select("COALESCE(SUM(size_bytes), 0)").
+		Where("user_id = ?", userID).
+		Scan(&used).Error
+	return used, err
+}
+
+// Create: simpan metadata kalau kuota user (quota, 0 = tanpa batas) & batas per expense masih cukup.
+// Baris user dikunci supaya upload paralel tidak bisa lewat kuota bareng-bareng.
+func (r *AttachmentRepo) Create(ctx context.Context, a *models.Attachment, quota int64, maxPerExpense int) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockUser(tx, a.UserID); err != nil {
+			return err
+		}
+
+		var count int64
+		if err := tx.Model(&models.Attachment{}).Where("expense_id = ?", a.ExpenseID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count >= int64(maxPerExpense) {
+			return ErrTooManyAttachments
+		}
+
+		if quota > 0 {
+			var used int64
+			err := tx.Model(&models.Attachment{}).
+				Select("COALESCE(SUM(size_bytes), 0)").
+				Where("user_id = ?", a.UserID).
+				Scan(&used).Error
+			if err != nil {
+				return err
+			}
+			if QuotaExceeded(used, a.SizeBytes, quota) {
+				return ErrAttachmentQuota
+			}
+		}
+
+		return tx.Create(a).Error
+	})
+}
+
+func (r *AttachmentRepo) Delete(ctx context.Context, userID, expenseID, id uuid.UUID) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Where("id = ? AND user_id = ? AND expense_id = ?", id, userID, expenseID).
+		Delete(&models.Attachment{})
+
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
@@ -0,0 +1,25 @@
+package repository
+
+import "testing"
+
+func TestQuotaExceeded(t *testing.T) {
+	tests := []struct {
+		name              string
+		used, size, quota int64
+		want              bool
+	}{
+		{"no quota", 1 << 40, 1 << 30, 0, false},
+		{"fits", 100, 50, 200, false},
+		{"exactly full", 150, 50, 200, false},
+		{"one byte over", 151, 50, 200, true},
+		{"already over", 300, 1, 200, true},
+		{"single file too big", 0, 201, 200, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := QuotaExceeded(tt.used, tt.size, tt.quota); got != tt.want {
+				t.Fatalf("QuotaExceeded(%d, %d, %d) = %v, want %v", tt.used, tt.size, tt.quota, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,214 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rifqi535/expense-tracker-api/internal/models"
+	"github.com/rifqi535/expense-tracker-api/internal/money"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrBudgetOverlap = errors.New("another budget for this category already covers part of this period")
+	ErrBudgetPeriod  = errors.New("effective_from must be within the budget period")
+)
+
+type BudgetRepo struct{ db *gorm.DB }
+
+func NewBudgetRepo(db *gorm.DB) *BudgetRepo { return &BudgetRepo{db: db} }
+
+// ListByUser: semua budget milik user termasuk periode yang sudah lewat
+func (r *BudgetRepo) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.Budget, error) {
+	var list []models.Budget
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("category_id NULLS FIRST, start_month").
+		Find(&list).Error
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// ActiveIn: budget yang berlaku di bulan month (tanggal 1), maksimal satu per kategori
+func (r *BudgetRepo) ActiveIn(ctx context.Context, userID uuid.UUID, month time.Time) ([]models.Budget, error) {
+	var list []models.Budget
+	day := month.Format("2006-01-02")
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND start_month <= ? AND (end_month IS NULL OR end_month >= ?)", userID, day, day).
+		Order("category_id NULLS FIRST").
+		Find(&list).Error
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// GetByID: nil kalau tidak ada / bukan milik user
+func (r *BudgetRepo) GetByID(ctx context.Context, userID, id uuid.UUID) (*models.Budget, error) {
+	var b models.Budget
+	err := r.db.WithContext(ctx).
+		Where("id = ? AND user_id = ?", id, userID).
+		First(&b).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// Create: kategori (kalau ada) harus milik user & periodenya tidak boleh tumpang tindih
+// dengan budget lain untuk kategori yang sama
+func (r *BudgetRepo) Create(ctx context.Context, b *models.Budget) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockUser(tx, b.UserID); err != nil {
+			return err
+		}
+		if b.CategoryID != nil {
+			if err := checkCategory(tx, b.UserID, *b.CategoryID); err != nil {
+				return err
+			}
+		}
+		if err := checkBudgetOverlap(tx, b); err != nil {
+			return err
+		}
+		return tx.Create(b).Error
+	})
+}
+
+// Update: ubah limit & periode, kategori tidak bisa diganti (ID, UserID & CategoryID dari b)
+func (r *BudgetRepo) Update(ctx context.Context, b *models.Budget) (bool, error) {
+	var updated bool
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockUser(tx, b.UserID); err != nil {
+			return err
+		}
+		if err := checkBudgetOverlap(tx, b); err != nil {
+			return err
+		}
+
+		b.UpdatedAt = time.Now()
+		result := tx.Model(&models.Budget{}).
+			Where("id = ? AND user_id = ?", b.ID, b.UserID).
+			Updates(map[string]interface{}{
+				"amount":      b.Amount,
+				"currency":    b.Currency,
+				"start_month": b.StartMonth,
+				"end_month":   b.EndMonth,
+				"updated_at":  b.UpdatedAt,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		updated = result.RowsAffected > 0
+		return nil
+	})
+	return updated, err
+}
+
+// Split: limit baru mulai bulan from. Periode lama ditutup sebulan sebelumnya dan sisanya
+// jadi budget baru, jadi status bulan-bulan sebelumnya tidak berubah. Alert yang sudah terkirim ikut ke budget baru.
+// from = awal periode → limit diubah di tempat. nil, nil kalau budget tidak ada.
+func (r *BudgetRepo) Split(ctx context.Context, userID, id uuid.UUID, from time.Time, amount money.Money, currency string) (*models.Budget, error) {
+	var result *models.Budget
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockUser(tx, userID); err != nil {
+			return err
+		}
+
+		var old models.Budget
+		err := tx.Where("id = ? AND user_id = ?", id, userID).First(&old).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		start := old.StartMonth.Format("2006-01")
+		switch month := from.Format("2006-01"); {
+		case month < start, old.EndMonth != nil && month > old.EndMonth.Format("2006-01"):
+			return ErrBudgetPeriod
+		case month == start:
+			old.Amount, old.Currency, old.UpdatedAt = amount, currency, time.Now()
+			result = &old
+			return tx.Model(&models.Budget{}).
+				Where("id = ?", old.ID).
+				Updates(map[string]interface{}{"amount": amount, "currency": currency, "updated_at": old.UpdatedAt}).Error
+		}
+
+		now := time.Now()
+		prev := from.AddDate(0, -1, 0)
+		err = tx.Model(&models.Budget{}).
+			Where("id = ?", old.ID).
+			Updates(map[string]interface{}{"end_month": prev, "updated_at": now}).Error
+		if err != nil {
+			return err
+		}
+
+		next := &models.Budget{
+			ID:         uuid.New(),
+			UserID:     userID,
+			CategoryID: old.CategoryID,
+			Amount:     amount,
+			Currency:   currency,
+			StartMonth: from,
+			EndMonth:   old.EndMonth,
+			CreatedAt:  now,
+			UpdatedAt:  now,
+		}
+		if err := tx.Create(next).Error; err != nil {
+			return err
+		}
+
+		// ambang yang sudah dikirim di bulan from ke atas ikut pindah, supaya alert tidak terkirim ulang
+		err = tx.Exec(`INSERT INTO budget_alerts (budget_id, month, threshold, created_at)
+SELECT ?, month, threshold, created_at FROM budget_alerts WHERE budget_id = ? AND month >= ?
+ON CONFLICT DO NOTHING`, next.ID, old.ID, from.Format("2006-01-02")).Error
+		if err != nil {
+			return err
+		}
+		result = next
+		return nil
+	})
+	return result, err
+}
+
+func (r *BudgetRepo) Delete(ctx context.Context, userID, id uuid.UUID) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Where("id = ? AND user_id = ?", id, userID).
+		Delete(&models.Budget{})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// checkBudgetOverlap: periode b tidak boleh beririsan dengan budget lain di kategori yang sama
+func checkBudgetOverlap(tx *gorm.DB, b *models.Budget) error {
+	query := tx.Model(&models.Budget{}).
+		Where("user_id = ? AND id <> ?", b.UserID, b.ID).
+		Where("(end_month IS NULL OR end_month >= ?)", b.StartMonth.Format("2006-01-02"))
+	if b.CategoryID == nil {
+		query = query.Where("category_id IS NULL")
+	} else {
+		query = query.Where("category_id = ?", *b.CategoryID)
+	}
+	if b.EndMonth != nil {
+		query = query.Where("start_month <= ?", b.EndMonth.Format("2006-01-02"))
+	}
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrBudgetOverlap
+	}
+	return nil
+}
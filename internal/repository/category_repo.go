@@ -2,21 +2,139 @@ package repository
 
 import (
 	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/rifqi535/expense-tracker-api/internal/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-type CategoryRepo struct{ db *gorm.DB }
+// MaxCategoryDepth jumlah tingkat maksimal, mis. Food > Groceries > Fruits = 3 tingkat
+const MaxCategoryDepth = 4
+
+var (
+	ErrCategoryNotFound = errors.New("category not found")
+
+	ErrParentNotFound  = errors.New("parent category not found")
+	ErrCategoryCycle   = errors.New("category cannot be moved under itself or its subcategories")
+	ErrCategoryTooDeep = fmt.Errorf("categories can be nested at most %d levels deep", MaxCategoryDepth)
+
+	ErrInvalidColor = errors.New("color must be a hex color like #1E88E5")
+	ErrInvalidIcon  = errors.New("icon must be 1-40 characters of a-z, 0-9 and -")
+
+	ErrInvalidRollover = errors.New("rollover must be none, surplus, deficit or full")
+
+	ErrTargetNotFound      = errors.New("target category not found")
+	ErrTargetIsSelf        = errors.New("target category must be a different category")
+	ErrMergeIntoDescendant = errors.New("category cannot be merged into its own subcategory")
+	ErrSubcategoryConflict = errors.New("a subcategory with the same title already exists at the destination")
+	ErrBudgetConflict      = errors.New("target category already has a budget for some of the same months, adjust the budgets first")
+)
+
+// CategoryInUseError kategori masih dipakai dan tidak ada tujuan pemindahan
+type CategoryInUseError struct {
+	Expenses          int64 // expense aktif
+	DeletedExpenses   int64 // expense yang sudah di-soft delete, tetap mengunci FK
+	RecurringExpenses int64 // jadwal berulang (template atau pengecualiannya)
+	Budgets           int64 // budget bulanan kategori ini
+	Allocations       int64 // baris ledger envelope, transfer tidak boleh tinggal satu sisi
+}
+
+func (e *CategoryInUseError) Error() string {
+	return fmt.Sprintf("category is used by %d expenses, %d recurring expenses, %d budgets and %d envelope allocations, pass reassign_to or merge it into another category",
+		e.Expenses+e.DeletedExpenses, e.RecurringExpenses, e.Budgets, e.Allocations)
+}
+
+// CategoryRemoval ringkasan data yang dipindah saat kategori dihapus / digabung
+type CategoryRemoval struct {
+	Expenses          int64 `json:"moved_expenses"`
+	RecurringExpenses int64 `json:"moved_recurring_expenses"`
+	Subcategories     int64 `json:"moved_subcategories"`
+	Budgets           int64 `json:"moved_budgets"`
+}
+
+// categorySubtreeSQL subquery id kategori + semua turunannya, parameter: id kategori, user id.
+// UNION (bukan UNION ALL) supaya tetap berhenti walaupun datanya rusak dan ada siklus.
+const categorySubtreeSQL = `WITH RECURSIVE subtree AS (
+	SELECT id FROM categories WHERE id = ? AND user_id = ?
+	UNION
+	SELECT c.id FROM categories c JOIN subtree s ON c.parent_id = s.id
+) SELECT id FROM subtree`
+
+// categoryAncestorsSQL subquery id kategori + semua leluhurnya, parameter: id kategori, user id
+const categoryAncestorsSQL = `WITH RECURSIVE ancestors AS (
+	SELECT id, parent_id FROM categories WHERE id = ? AND user_id = ?
+	UNION
+	SELECT c.id, c.parent_id FROM categories c JOIN ancestors a ON c.id = a.parent_id
+) SELECT id FROM ancestors`
+
+// NormalizeColor: "#RRGGBB" huruf besar, "" tetap kosong (= tanpa warna)
+func NormalizeColor(color string) (string, error) {
+	color = strings.ToUpper(strings.TrimSpace(color))
+	if color == "" {
+		return "", nil
+	}
+	if len(color) != 7 || color[0] != '#' {
+		return "", ErrInvalidColor
+	}
+	if _, err := hex.DecodeString(color[1:]); err != nil {
+		return "", ErrInvalidColor
+	}
+	return color, nil
+}
+
+// ValidIcon: key ikon bebas (client yang memetakan ke gambar), "" = tanpa ikon
+func ValidIcon(icon string) bool {
+	if len(icon) > 40 {
+		return false
+	}
+	for _, r := range icon {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidRollover aturan rollover envelope yang dikenal
+func ValidRollover(rule string) bool {
+	switch rule {
+	case models.RolloverNone, models.RolloverSurplus, models.RolloverDeficit, models.RolloverFull:
+		return true
+	}
+	return false
+}
+
+type CategoryRepo struct {
+	db *gorm.DB
+	expenseObservers
+}
 
 func NewCategoryRepo(db *gorm.DB) *CategoryRepo { return &CategoryRepo{db: db} }
 
+// ListByUser: semua kategori termasuk yang diarsipkan, urut sort_order lalu judul
 func (r *CategoryRepo) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.Category, error) {
+	return r.list(ctx, userID, true)
+}
+
+// ListActive: tanpa kategori yang diarsipkan (untuk pilihan kategori di client)
+func (r *CategoryRepo) ListActive(ctx context.Context, userID uuid.UUID) ([]models.Category, error) {
+	return r.list(ctx, userID, false)
+}
+
+func (r *CategoryRepo) list(ctx context.Context, userID uuid.UUID, includeArchived bool) ([]models.Category, error) {
 	var categories []models.Category
-	err := r.db.WithContext(ctx).
-		Where("user_id = ?", userID).
-		Order("title").
+	query := r.db.WithContext(ctx).Where("user_id = ?", userID)
+	if !includeArchived {
+		query = query.Where("archived_at IS NULL")
+	}
+	err := query.
+		Order("sort_order, title").
 		Find(&categories).Error
 	if err != nil {
 		return nil, err
@@ -25,31 +143,394 @@ func (r *CategoryRepo) ListByUser(ctx context.Context, userID uuid.UUID) ([]mode
 
 }
 
+// ExpenseCounts: jumlah expense (yang belum dihapus) langsung di tiap kategori, tanpa turunan
+func (r *CategoryRepo) ExpenseCounts(ctx context.Context, userID uuid.UUID) (map[uuid.UUID]int64, error) {
+	var rows []struct {
+		CategoryID uuid.UUID
+		Count      int64
+	}
+	err := r.db.WithContext(ctx).
+		Model(&models.Expense{}).
+		Select("category_id, COUNT(*) AS count").
+		Where("user_id = ?", userID).
+		Group("category_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[uuid.UUID]int64, len(rows))
+	for _, row := range rows {
+		counts[row.CategoryID] = row.Count
+	}
+	return counts, nil
+}
+
+// Create: kalau ParentID diisi, parent harus milik user & kedalamannya masih muat
 func (r *CategoryRepo) Create(ctx context.Context, c *models.Category) error {
-	return r.db.WithContext(ctx).Create(c).Error
+	if c.ParentID == nil {
+		return r.db.WithContext(ctx).Create(c).Error
+	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkParent(tx, c.UserID, nil, *c.ParentID); err != nil {
+			return err
+		}
+		return tx.Create(c).Error
+	})
 }
 
-func (r *CategoryRepo) Update(ctx context.Context, userID, id uuid.UUID, title string) (bool, error) {
-	result := r.db.WithContext(ctx).
-		Model(&models.Category{}).
-		Where("id = ? AND user_id = ?", id, userID).
-		Updates(map[string]interface{}{
-			"title": title,
-		})
+// CategoryUpdate field kategori yang mau diubah, nil = tidak diubah
+type CategoryUpdate struct {
+	Title *string
+	// SetParent=true → pindahkan ke ParentID (nil = jadi kategori utama)
+	SetParent bool
+	ParentID  *uuid.UUID
+	Color     *string // "" = hapus warna
+	Icon      *string // "" = hapus ikon
+	SortOrder *int
+	// Archived ikut berlaku ke semua subkategori
+	Archived *bool
+	Rollover *string
+}
 
-	if result.Error != nil {
-		return false, result.Error
+func (r *CategoryRepo) Update(ctx context.Context, userID, id uuid.UUID, u CategoryUpdate) (bool, error) {
+	updates := map[string]interface{}{"updated_at": time.Now()}
+	if u.Title != nil {
+		updates["title"] = *u.Title
+	}
+	if u.SetParent {
+		updates["parent_id"] = u.ParentID
+	}
+	if u.Color != nil {
+		updates["color"] = nullIfEmpty(*u.Color)
 	}
-	return result.RowsAffected > 0, nil
+	if u.Icon != nil {
+		updates["icon"] = nullIfEmpty(*u.Icon)
+	}
+	if u.SortOrder != nil {
+		updates["sort_order"] = *u.SortOrder
+	}
+	if u.Rollover != nil {
+		updates["rollover"] = *u.Rollover
+	}
+
+	var updated bool
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if u.SetParent && u.ParentID != nil {
+			if err := checkParent(tx, userID, &id, *u.ParentID); err != nil {
+				return err
+			}
+		}
+
+		result := tx.Model(&models.Category{}).
+			Where("id = ? AND user_id = ?", id, userID).
+			Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		updated = result.RowsAffected > 0
+		if !updated || u.Archived == nil {
+			return nil
+		}
+
+		// arsip berlaku ke satu subtree, buka arsip juga membuka leluhurnya supaya tree tetap utuh.
+		// archived_at yang sudah ada tidak ditimpa.
+		now := time.Now()
+		if *u.Archived {
+			return tx.Model(&models.Category{}).
+				Where("id IN ("+categorySubtreeSQL+") AND archived_at IS NULL", id, userID).
+				Updates(map[string]interface{}{"archived_at": now, "updated_at": now}).Error
+		}
+		return tx.Model(&models.Category{}).
+			Where("(id IN ("+categorySubtreeSQL+") OR id IN ("+categoryAncestorsSQL+")) AND archived_at IS NOT NULL", id, userID, id, userID).
+			Updates(map[string]interface{}{"archived_at": nil, "updated_at": now}).Error
+	})
+	if err == nil && updated && u.SetParent {
+		// pindah parent → expense subtree ini masuk ke budget leluhur yang lain
+		r.changed(userID)
+	}
+	return updated, err
 }
 
-func (r *CategoryRepo) Delete(ctx context.Context, userID, id uuid.UUID) (bool, error) {
-	result := r.db.WithContext(ctx).
-		Where("id = ? AND user_id = ?", id, userID).
-		Delete(&models.Category{})
+func nullIfEmpty(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// Delete: hapus kategori. Expense, jadwal berulang, budget & alokasi envelope dipindah ke reassignTo (nil = tidak boleh ada,
+// kalau ada → *CategoryInUseError). Subkategori naik satu tingkat ke parent kategori ini.
+// nil, nil kalau kategori tidak ada.
+func (r *CategoryRepo) Delete(ctx context.Context, userID, id uuid.UUID, reassignTo *uuid.UUID) (*CategoryRemoval, error) {
+	return r.remove(ctx, userID, id, reassignTo, false)
+}
+
+// Merge: gabungkan kategori id ke into, expense, jadwal berulang, budget & subkategorinya pindah ke into
+func (r *CategoryRepo) Merge(ctx context.Context, userID, id, into uuid.UUID) (*CategoryRemoval, error) {
+	return r.remove(ctx, userID, id, &into, true)
+}
+
+func (r *CategoryRepo) remove(ctx context.Context, userID, id uuid.UUID, target *uuid.UUID, merge bool) (*CategoryRemoval, error) {
+	var removal *CategoryRemoval
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockUser(tx, userID); err != nil {
+			return err
+		}
+
+		var source models.Category
+		err := tx.Where("id = ? AND user_id = ?", id, userID).First(&source).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if target != nil {
+			if *target == id {
+				return ErrTargetIsSelf
+			}
+			var count int64
+			err := tx.Model(&models.Category{}).Where("id = ? AND user_id = ?", *target, userID).Count(&count).Error
+			if err != nil {
+				return err
+			}
+			if count == 0 {
+				return ErrTargetNotFound
+			}
+		}
+
+		res := &CategoryRemoval{}
+		if target == nil {
+			inUse := &CategoryInUseError{}
+			err := tx.Unscoped().Model(&models.Expense{}).
+				Where("category_id = ? AND deleted_at IS NULL", id).
+				Count(&inUse.Expenses).Error
+			if err != nil {
+				return err
+			}
+			err = tx.Unscoped().Model(&models.Expense{}).
+				Where("category_id = ? AND deleted_at IS NOT NULL", id).
+				Count(&inUse.DeletedExpenses).Error
+			if err != nil {
+				return err
+			}
+			err = tx.Model(&models.RecurringExpense{}).
+				Where("category_id = ? OR id IN (SELECT recurring_id FROM recurring_expense_exceptions WHERE category_id = ?)", id, id).
+				Count(&inUse.RecurringExpenses).Error
+			if err != nil {
+				return err
+			}
+			err = tx.Model(&models.Budget{}).Where("category_id = ?", id).Count(&inUse.Budgets).Error
+			if err != nil {
+				return err
+			}
+			err = tx.Model(&models.BudgetAllocation{}).Where("category_id = ?", id).Count(&inUse.Allocations).Error
+			if err != nil {
+				return err
+			}
+			if inUse.Expenses+inUse.DeletedExpenses+inUse.RecurringExpenses+inUse.Budgets+inUse.Allocations > 0 {
+				return inUse
+			}
+		} else {
+			// expense yang sudah di-soft delete ikut dipindah, FK-nya tetap mengunci
+			now := time.Now()
+			result := tx.Unscoped().Model(&models.Expense{}).
+				Where("category_id = ?", id).
+				Updates(map[string]interface{}{"category_id": *target, "updated_at": now})
+			if result.Error != nil {
+				return result.Error
+			}
+			res.Expenses = result.RowsAffected
+
+			result = tx.Model(&models.RecurringExpense{}).
+				Where("category_id = ?", id).
+				Updates(map[string]interface{}{"category_id": *target, "updated_at": now})
+			if result.Error != nil {
+				return result.Error
+			}
+			res.RecurringExpenses = result.RowsAffected
+
+			err := tx.Model(&models.RecurringException{}).
+				Where("category_id = ?", id).
+				Updates(map[string]interface{}{"category_id": *target, "updated_at": now}).Error
+			if err != nil {
+				return err
+			}
+
+			// alokasi envelope ikut pindah supaya saldo tujuan tetap cocok dengan expense-nya
+			err = tx.Model(&models.BudgetAllocation{}).
+				Where("category_id = ?", id).
+				Update("category_id", *target).Error
+			if err != nil {
+				return err
+			}
+
+			// budget (beserta riwayat alert-nya) ikut pindah, asal periodenya tidak bentrok dengan budget tujuan
+			var overlaps int64
+			err = tx.Raw(`SELECT COUNT(*) FROM budgets s JOIN budgets t ON t.category_id = ?
+WHERE s.category_id = ?
+AND s.start_month <= COALESCE(t.end_month, 'infinity'::date)
+AND t.start_month <= COALESCE(s.end_month, 'infinity'::date)`, *target, id).
+				Scan(&overlaps).Error
+			if err != nil {
+				return err
+			}
+			if overlaps > 0 {
+				return ErrBudgetConflict
+			}
+			result = tx.Model(&models.Budget{}).
+				Where("category_id = ?", id).
+				Updates(map[string]interface{}{"category_id": *target, "updated_at": now})
+			if result.Error != nil {
+				return result.Error
+			}
+			res.Budgets = result.RowsAffected
+		}
+
+		// --- SUBKATEGORI ---
+		// delete: naik ke parent kategori ini, merge: pindah ke bawah kategori tujuan
+		newParent := source.ParentID
+		if merge {
+			newParent = target
+
+			var inSubtree int64
+			err := tx.Raw("SELECT COUNT(*) FROM ("+categorySubtreeSQL+") s WHERE s.id = ?", id, userID, *target).
+				Scan(&inSubtree).Error
+			if err != nil {
+				return err
+			}
+			if inSubtree > 0 {
+				return ErrMergeIntoDescendant
+			}
+		}
+
+		var children []models.Category
+		if err := tx.Where("parent_id = ?", id).Find(&children).Error; err != nil {
+			return err
+		}
+		if len(children) > 0 {
+			titles := make([]string, 0, len(children))
+			for _, child := range children {
+				titles = append(titles, child.Title)
+			}
+			conflict := tx.Model(&models.Category{}).Where("user_id = ? AND title IN ?", userID, titles)
+			if newParent == nil {
+				conflict = conflict.Where("parent_id IS NULL")
+			} else {
+				conflict = conflict.Where("parent_id = ?", *newParent)
+			}
+			var conflicts int64
+			if err := conflict.Count(&conflicts).Error; err != nil {
+				return err
+			}
+			if conflicts > 0 {
+				return ErrSubcategoryConflict
+			}
 
-	if result.Error != nil {
-		return false, result.Error
+			if merge {
+				for _, child := range children {
+					if err := checkParent(tx, userID, &child.ID, *target); err != nil {
+						return err
+					}
+				}
+			}
+
+			result := tx.Model(&models.Category{}).
+				Where("parent_id = ?", id).
+				Updates(map[string]interface{}{"parent_id": newParent, "updated_at": time.Now()})
+			if result.Error != nil {
+				return result.Error
+			}
+			res.Subcategories = result.RowsAffected
+		}
+
+		if err := tx.Delete(&source).Error; err != nil {
+			return err
+		}
+		removal = res
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if removal != nil && removal.Expenses+removal.Subcategories+removal.Budgets > 0 {
+		// total per budget kategori bisa berubah (expense / subkategori / budget pindah),
+		// tanggal expense yang terdampak tidak dikumpulkan
+		r.changed(userID)
+	}
+	return removal, nil
+}
+
+// checkParent: parentID harus milik user, bukan id sendiri / turunannya (siklus),
+// dan subtree id (nil = kategori baru) masih muat di bawah parent tanpa lewat MaxCategoryDepth.
+// Baris user dikunci supaya dua pemindahan paralel tidak bisa bikin siklus.
+func checkParent(tx *gorm.DB, userID uuid.UUID, id *uuid.UUID, parentID uuid.UUID) error {
+	if err := lockUser(tx, userID); err != nil {
+		return err
+	}
+
+	// leluhur parent (termasuk parent sendiri) sampai kategori utama
+	var ancestors []uuid.UUID
+	err := tx.Raw(`WITH RECURSIVE up AS (
+	SELECT id, parent_id, 1 AS depth FROM categories WHERE id = ? AND user_id = ?
+	UNION ALL
+	SELECT c.id, c.parent_id, up.depth + 1 FROM categories c JOIN up ON c.id = up.parent_id
+	WHERE up.depth <= ?
+) SELECT id FROM up`, parentID, userID, MaxCategoryDepth).Scan(&ancestors).Error
+	if err != nil {
+		return err
+	}
+	if len(ancestors) == 0 {
+		return ErrParentNotFound
 	}
-	return result.RowsAffected > 0, nil
+
+	// tinggi subtree yang dipindah, kategori tanpa anak = 1
+	height := 1
+	if id != nil {
+		for _, a := range ancestors {
+			if a == *id {
+				return ErrCategoryCycle
+			}
+		}
+		err = tx.Raw(`WITH RECURSIVE down AS (
+	SELECT id, 1 AS level FROM categories WHERE id = ? AND user_id = ?
+	UNION ALL
+	SELECT c.id, down.level + 1 FROM categories c JOIN down ON c.parent_id = down.id
+	WHERE down.level <= ?
+) SELECT COALESCE(MAX(level), 1) FROM down`, *id, userID, MaxCategoryDepth).Scan(&height).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(ancestors)+height > MaxCategoryDepth {
+		return ErrCategoryTooDeep
+	}
+	return nil
+}
+
+// checkCategory: kategori id harus ada & milik user
+func checkCategory(tx *gorm.DB, userID, id uuid.UUID) error {
+	var count int64
+	err := tx.Model(&models.Category{}).
+		Where("id = ? AND user_id = ?", id, userID).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrCategoryNotFound
+	}
+	return nil
+}
+
+// lockUser kunci baris user sampai transaksi selesai, dipakai untuk perubahan struktur data per user
+func lockUser(tx *gorm.DB, userID uuid.UUID) error {
+	var user models.User
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id").
+		Where("id = ?", userID).
+		First(&user).Error
 }
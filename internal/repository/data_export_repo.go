@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rifqi535/expense-tracker-api/internal/models"
+	"gorm.io/gorm"
+)
+
+type DataExportRepo struct{ db *gorm.DB }
+
+func NewDataExportRepo(db *gorm.DB) *DataExportRepo { return &DataExportRepo{db: db} }
+
+func (r *DataExportRepo) Create(ctx context.Context, e *models.DataExport) error {
+	return r.db.WithContext(ctx).Create(e).Error
+}
+
+// HasActive: user masih punya export yang pending / running
+func (r *DataExportRepo) HasActive(ctx context.Context, userID uuid.UUID) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Model(&models.DataExport{}).
+		Where("user_id = ? AND status IN ?", userID, []string{models.ExportPending, models.ExportRunning}).
+		Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// ListByUser: riwayat export milik user, terbaru dulu
+func (r *DataExportRepo) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.DataExport, error) {
+	var exports []models.DataExport
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Limit(20).
+		Find(&exports).Error
+	if err != nil {
+		return nil, err
+	}
+	return exports, nil
+}
+
+// GetByID: ambil export milik user (nil kalau tidak ada)
+func (r *DataExportRepo) GetByID(ctx context.Context, userID, id uuid.UUID) (*models.DataExport, error) {
+	var e models.DataExport
+	err := r.db.WithContext(ctx).
+		Where("id = ? AND user_id = ?", id, userID).
+		First(&e).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &e, nil
+}
+
+// ClaimNext: ambil satu job pending paling lama & tandai running (aman kalau ada beberapa worker)
+func (r *DataExportRepo) ClaimNext(ctx context.Context) (*models.DataExport, error) {
+	var exports []models.DataExport
+	err := r.db.WithContext(ctx).Raw(`
+UPDATE data_exports SET status = ?, started_at = ?
+WHERE id = (
+	SELECT id FROM data_exports
+	WHERE status = ?
+	ORDER BY created_at
+	LIMIT 1
+	FOR UPDATE SKIP LOCKED
+)
+RETURNING *`,
+		models.ExportRunning, time.Now(), models.ExportPending,
+	).Scan(&exports).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(exports) == 0 {
+		return nil, nil
+	}
+	return &exports[0], nil
+}
+
+// MarkReady: file export sudah jadi, bisa didownload sampai expiresAt
+func (r *DataExportRepo) MarkReady(ctx context.Context, id uuid.UUID, path string, size int64, expiresAt time.Time) error {
+	return r.db.WithContext(ctx).
+		Model(&models.DataExport{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"status":       models.ExportReady,
+			"file_path":    path,
+			"size_bytes":   size,
+			"completed_at": time.Now(),
+			"expires_at":   expiresAt,
+		}).Error
+}
+
+// MarkFailed: simpan pesan error supaya user bisa coba lagi
+func (r *DataExportRepo) MarkFailed(ctx context.Context, id uuid.UUID, msg string) error {
+	return r.db.WithContext(ctx).
+		Model(&models.DataExport{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"status":       models.ExportFailed,
+			"error":        msg,
+			"completed_at": time.Now(),
+		}).Error
+}
+
+// RequeueStale: job running yang kelamaan (worker mati di tengah jalan) dikembalikan ke pending
+func (r *DataExportRepo) RequeueStale(ctx context.Context, olderThan time.Duration) error {
+	return r.db.WithContext(ctx).
+		Model(&models.DataExport{}).
+		Where("status = ? AND started_at < ?", models.ExportRunning, time.Now().Add(-olderThan)).
+		Update("status", models.ExportPending).Error
+}
+
+// ListExpired: export ready yang masa download-nya sudah lewat
+func (r *DataExportRepo) ListExpired(ctx context.Context) ([]models.DataExport, error) {
+	var exports []models.DataExport
+	err := r.db.WithContext(ctx).
+		Where("status = ? AND expires_at <= ?", models.ExportReady, time.Now()).
+		Find(&exports).Error
+	if err != nil {
+		return nil, err
+	}
+	return exports, nil
+}
+
+// MarkExpired: file sudah dihapus dari disk
+func (r *DataExportRepo) MarkExpired(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).
+		Model(&models.DataExport{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"status":    models.ExportExpired,
+			"file_path": "",
+		}).Error
+}
@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rifqi535/expense-tracker-api/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ExchangeRateRepo struct{ db *gorm.DB }
+
+func NewExchangeRateRepo(db *gorm.DB) *ExchangeRateRepo { return &ExchangeRateRepo{db: db} }
+
+// Upsert simpan kurs, kurs untuk pasangan + tanggal yang sama ditimpa
+func (r *ExchangeRateRepo) Upsert(ctx context.Context, rates []models.ExchangeRate) (int64, error) {
+	if len(rates) == 0 {
+		return 0, nil
+	}
+
+	now := time.Now()
+	for i := range rates {
+		if rates[i].ID == uuid.Nil {
+			rates[i].ID = uuid.New()
+		}
+		rates[i].CreatedAt = now
+		rates[i].UpdatedAt = now
+	}
+
+	result := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "base"}, {Name: "quote"}, {Name: "rate_date"}},
+			DoUpdates: clause.AssignmentColumns([]string{"rate", "source", "updated_at"}),
+		}).
+		CreateInBatches(rates, 500)
+	return result.RowsAffected, result.Error
+}
+
+// List kurs untuk admin, filter opsional
+func (r *ExchangeRateRepo) List(ctx context.Context, base, quote string, from, to *time.Time, limit, offset int) ([]models.ExchangeRate, int64, error) {
+	var (
+		rates []models.ExchangeRate
+		total int64
+	)
+
+	query := r.db.WithContext(ctx).Model(&models.ExchangeRate{})
+	if base != "" {
+		query = query.Where("base = ?", base)
+	}
+	if quote != "" {
+		query = query.Where("quote = ?", quote)
+	}
+	if from != nil {
+		query = query.Where("rate_date >= ?", *from)
+	}
+	if to != nil {
+		query = query.Where("rate_date <= ?", *to)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Order("rate_date DESC, base, quote").
+		Limit(limit).
+		Offset(offset).
+		Find(&rates).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return rates, total, nil
+}
+
+func (r *ExchangeRateRepo) Delete(ctx context.Context, id uuid.UUID) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Where("id = ?", id).
+		Delete(&models.ExchangeRate{})
+
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// LatestFor kurs terakhir (pada / sebelum tanggal on) untuk setiap pasangan yang melibatkan salah satu currencies
+func (r *ExchangeRateRepo) LatestFor(ctx context.Context, currencies []string, on time.Time) ([]models.ExchangeRate, error) {
+	var rates []models.ExchangeRate
+	err := r.db.WithContext(ctx).Raw(`
+SELECT DISTINCT ON (base, quote) *
+FROM exchange_rates
+WHERE (base IN ? OR quote IN ?) AND rate_date <= ?
+ORDER BY base, quote, rate_date DESC`,
+		currencies, currencies, on.Format("2006-01-02"),
+	).Scan(&rates).Error
+	if err != nil {
+		return nil, err
+	}
+	return rates, nil
+}
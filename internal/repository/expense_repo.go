@@ -2,29 +2,65 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/rifqi535/expense-tracker-api/internal/models"
+	"github.com/rifqi535/expense-tracker-api/internal/money"
 	"gorm.io/gorm"
 )
 
 type ExpenseRepo struct {
 	db *gorm.DB
+	expenseObservers
+}
+
+// ExpenseObserver dikabari setelah perubahan expense user berhasil disimpan: Create / Update / Delete di sini,
+// expense dari jadwal berulang, dan expense yang dipindah waktu kategori dihapus / digabung.
+// spentAt = tanggal transaksi yang terdampak (lama & baru), kosong = tidak diketahui / terlalu banyak.
+// Dipanggil sinkron, jangan nge-block.
+type ExpenseObserver interface {
+	ExpensesChanged(userID uuid.UUID, spentAt ...time.Time)
+}
+
+// expenseObservers di-embed repo yang mengubah expense
+type expenseObservers []ExpenseObserver
+
+// Observe daftarkan observer, dipanggil waktu setup sebelum server jalan
+func (o *expenseObservers) Observe(observer ExpenseObserver) {
+	*o = append(*o, observer)
+}
+
+func (o expenseObservers) changed(userID uuid.UUID, spentAt ...time.Time) {
+	for _, observer := range o {
+		observer.ExpensesChanged(userID, spentAt...)
+	}
 }
 
 func NewExpenseRepo(db *gorm.DB) *ExpenseRepo {
 	return &ExpenseRepo{db: db}
 }
 
+// ExpenseFilter filter opsional untuk List
+type ExpenseFilter struct {
+	// CategoryID termasuk semua subkategorinya, kecuali ExactCategory=true
+	CategoryID    *uuid.UUID
+	ExactCategory bool
+	StartDate     *time.Time
+	EndDate       *time.Time // eksklusif
+	// Tags nama tag (sudah dinormalisasi), MatchAllTags=true → expense harus punya semua tag
+	Tags         []string
+	MatchAllTags bool
+}
+
 // List dengan filter, sort, pagination
 func (r *ExpenseRepo) List(
 	ctx context.Context,
 	userID uuid.UUID,
-	categoryID *uuid.UUID,
-	startDate, endDate *time.Time,
+	filter ExpenseFilter,
 	limit, offset int,
 	sortBy, order string,
 ) ([]models.Expense, error) {
@@ -45,74 +81,269 @@ func (r *ExpenseRepo) List(
 	}
 	order = strings.ToUpper(order) // ASC / DESC
 
-	// pilih kolom sort
-	sortColumn := "created_at"
+	// pilih kolom sort, "date" = tanggal transaksi
+	sortColumn := "spent_at"
 	if sortBy == "amount" {
 		sortColumn = "amount"
 	}
 
 	// build query dengan GORM
 	query := r.db.WithContext(ctx).Model(&models.Expense{}).Where("user_id = ?", userID)
+	query = applyFilter(query, userID, filter)
 
-	if categoryID != nil {
-		query = query.Where("category_id = ?", *categoryID)
+	// order + pagination, created_at sebagai tie-breaker supaya urutan stabil
+	err := query.Order(fmt.Sprintf("%s %s, created_at %s", sortColumn, order, order)).
+		Limit(limit).
+		Offset(offset).
+		Find(&expenses).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return expenses, r.attachTags(ctx, expenses)
+}
+
+// applyFilter tambahkan kondisi ExpenseFilter ke query expenses milik userID
+func applyFilter(query *gorm.DB, userID uuid.UUID, filter ExpenseFilter) *gorm.DB {
+	if filter.CategoryID != nil {
+		if filter.ExactCategory {
+			query = query.Where("category_id = ?", *filter.CategoryID)
+		} else {
+			query = query.Where("category_id IN ("+categorySubtreeSQL+")", *filter.CategoryID, userID)
+		}
+	}
+	if filter.StartDate != nil {
+		query = query.Where("spent_at >= ?", *filter.StartDate)
 	}
-	if startDate != nil {
-		query = query.Where("created_at >= ?", *startDate)
+	if filter.EndDate != nil {
+		// endDate eksklusif, caller kirim awal hari berikutnya untuk filter per tanggal
+		query = query.Where("spent_at < ?", *filter.EndDate)
 	}
-	if endDate != nil {
-		query = query.Where("created_at <= ?", *endDate)
+	if len(filter.Tags) > 0 {
+		tagged := query.Session(&gorm.Session{NewDB: true}).
+			Table("expense_tags et").
+			Select("et.expense_id").
+			Joins("JOIN tags t ON t.id = et.tag_id").
+			Where("t.user_id = ? AND t.name IN ?", userID, filter.Tags)
+		if filter.MatchAllTags {
+			tagged = tagged.Group("et.expense_id").Having("COUNT(DISTINCT t.id) = ?", len(filter.Tags))
+		}
+		query = query.Where("id IN (?)", tagged)
 	}
+	return query
+}
 
-	// order + pagination
-	err := query.Order(fmt.Sprintf("%s %s", sortColumn, order)).
-		Limit(limit).
-		Offset(offset).
-		Find(&expenses).Error
+// attachTags isi Expense.Tags untuk semua expense di list (satu query)
+func (r *ExpenseRepo) attachTags(ctx context.Context, expenses []models.Expense) error {
+	if len(expenses) == 0 {
+		return nil
+	}
+	ids := make([]uuid.UUID, 0, len(expenses))
+	for _, e := range expenses {
+		ids = append(ids, e.ID)
+	}
+
+	var rows []struct {
+		ExpenseID uuid.UUID
+		Name      string
+	}
+	err := r.db.WithContext(ctx).
+		Table("expense_tags et").
+		Select("et.expense_id, t.name").
+		Joins("JOIN tags t ON t.id = et.tag_id").
+		Where("et.expense_id IN ?", ids).
+		Order("t.name").
+		Scan(&rows).Error
+	if err != nil {
+		return err
+	}
+
+	byExpense := make(map[uuid.UUID][]string, len(expenses))
+	for _, row := range rows {
+		byExpense[row.ExpenseID] = append(byExpense[row.ExpenseID], row.Name)
+	}
+	for i := range expenses {
+		expenses[i].Tags = byExpense[expenses[i].ID]
+		if expenses[i].Tags == nil {
+			expenses[i].Tags = []string{}
+		}
+	}
+	return nil
+}
+
+// DailyTotal total expense per tanggal lokal & mata uang
+type DailyTotal struct {
+	Day      string      `json:"day"` // YYYY-MM-DD di zona timezone
+	Currency string      `json:"currency"`
+	Count    int64       `json:"count"`
+	Total    money.Money `json:"total"`
+}
+
+// DailyTotals: total per hari (dihitung di zona timezone) untuk expense yang lolos filter,
+// rentangnya dari filter.StartDate / EndDate
+func (r *ExpenseRepo) DailyTotals(ctx context.Context, userID uuid.UUID, timezone string, filter ExpenseFilter) ([]DailyTotal, error) {
+	var totals []DailyTotal
+
+	query := r.db.WithContext(ctx).
+		Model(&models.Expense{}).
+		Select("to_char(spent_at AT TIME ZONE ?, 'YYYY-MM-DD') AS day, currency, COUNT(*) AS count, SUM(amount) AS total", timezone).
+		Where("user_id = ?", userID)
+	query = applyFilter(query, userID, filter)
 
-	return expenses, err
+	err := query.Group("day, currency").
+		Order("day, currency").
+		Scan(&totals).Error
+	if err != nil {
+		return nil, err
+	}
+	return totals, nil
+}
+
+// CategoryDailyTotal DailyTotal per kategori (kategori expense itu sendiri, tanpa roll-up ke parent)
+type CategoryDailyTotal struct {
+	CategoryID uuid.UUID `json:"category_id"`
+	DailyTotal
+}
+
+// CategoryDailyTotals: seperti DailyTotals tapi dipisah per kategori
+func (r *ExpenseRepo) CategoryDailyTotals(ctx context.Context, userID uuid.UUID, timezone string, filter ExpenseFilter) ([]CategoryDailyTotal, error) {
+	var totals []CategoryDailyTotal
+
+	query := r.db.WithContext(ctx).
+		Model(&models.Expense{}).
+		Select("category_id, to_char(spent_at AT TIME ZONE ?, 'YYYY-MM-DD') AS day, currency, COUNT(*) AS count, SUM(amount) AS total", timezone).
+		Where("user_id = ?", userID)
+	query = applyFilter(query, userID, filter)
+
+	err := query.Group("category_id, day, currency").
+		Order("day, category_id, currency").
+		Scan(&totals).Error
+	if err != nil {
+		return nil, err
+	}
+	return totals, nil
 }
 
 // ListByUser: shortcut tanpa filter
 func (r *ExpenseRepo) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.Expense, error) {
-	return r.List(ctx, userID, nil, nil, nil, 10, 0, "date", "desc")
+	return r.List(ctx, userID, ExpenseFilter{}, 10, 0, "date", "desc")
 }
 
-// Create: tambah expense baru
-func (r *ExpenseRepo) Create(ctx context.Context, e *models.Expense) error {
-	return r.db.WithContext(ctx).Create(e).Error
-}
+// ListAll: semua expense milik user tanpa pagination (buat export), termasuk yang sudah di-soft delete
+func (r *ExpenseRepo) ListAll(ctx context.Context, userID uuid.UUID, includeDeleted bool) ([]models.Expense, error) {
+	var expenses []models.Expense
 
-// Update: ubah expense milik user
-func (r *ExpenseRepo) Update(ctx context.Context, userID, id uuid.UUID, title, description string, amount float64, categoryID uuid.UUID) (bool, error) {
-	updates := map[string]interface{}{
-		"title":       title,
-		"description": description,
-		"amount":      amount,
-		"category_id": categoryID,
-		"updated_at":  time.Now(),
+	query := r.db.WithContext(ctx)
+	if includeDeleted {
+		query = query.Unscoped()
 	}
 
-	result := r.db.WithContext(ctx).
-		Model(&models.Expense{}).
+	err := query.Where("user_id = ?", userID).
+		Order("spent_at, created_at").
+		Find(&expenses).Error
+	if err != nil {
+		return nil, err
+	}
+	return expenses, r.attachTags(ctx, expenses)
+}
+
+// GetByID: satu expense milik user, nil kalau tidak ada
+func (r *ExpenseRepo) GetByID(ctx context.Context, userID, id uuid.UUID) (*models.Expense, error) {
+	var e models.Expense
+	err := r.db.WithContext(ctx).
 		Where("id = ? AND user_id = ?", id, userID).
-		Updates(updates)
+		First(&e).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	list := []models.Expense{e}
+	if err := r.attachTags(ctx, list); err != nil {
+		return nil, err
+	}
+	return &list[0], nil
+}
 
-	if result.Error != nil {
-		return false, result.Error
+// Create: tambah expense baru + tag-nya (tag yang belum ada otomatis dibuat)
+func (r *ExpenseRepo) Create(ctx context.Context, e *models.Expense) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(e).Error; err != nil {
+			return err
+		}
+		if e.Tags == nil {
+			e.Tags = []string{}
+		}
+		return setExpenseTags(tx, e.UserID, e.ID, e.Tags)
+	})
+	if err == nil {
+		r.changed(e.UserID, e.SpentAt)
 	}
+	return err
+}
 
-	if result.RowsAffected == 0 {
-		// tidak ada baris yang berubah → mungkin ID salah atau user bukan pemilik data
-		return false, nil
+// Update: ubah expense milik user, field diambil dari e (ID & UserID diabaikan).
+// e.Tags nil = tag tidak diubah, slice kosong = hapus semua tag.
+func (r *ExpenseRepo) Update(ctx context.Context, userID, id uuid.UUID, e *models.Expense) (bool, error) {
+	updates := map[string]interface{}{
+		"title":       e.Title,
+		"description": e.Description,
+		"amount":      e.Amount,
+		"currency":    e.Currency,
+		"spent_at":    e.SpentAt,
+		"spent_tz":    e.SpentTZ,
+		"category_id": e.CategoryID,
+		"updated_at":  time.Now(),
 	}
 
-	return true, nil
+	var updated bool
+	var previous time.Time
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// tanggal lama ikut dikabarkan, expense bisa pindah bulan
+		err := tx.Model(&models.Expense{}).
+			Select("spent_at").
+			Where("id = ? AND user_id = ?", id, userID).
+			Scan(&previous).Error
+		if err != nil {
+			return err
+		}
+
+		result := tx.Model(&models.Expense{}).
+			Where("id = ? AND user_id = ?", id, userID).
+			Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			// tidak ada baris yang berubah → mungkin ID salah atau user bukan pemilik data
+			return nil
+		}
+		updated = true
 
+		if e.Tags == nil {
+			return nil
+		}
+		return setExpenseTags(tx, userID, id, e.Tags)
+	})
+	if err == nil && updated {
+		r.changed(userID, previous, e.SpentAt)
+	}
+	return updated, err
 }
 
 // Delete: hapus expense milik user
 func (r *ExpenseRepo) Delete(ctx context.Context, userID, id uuid.UUID) (bool, error) {
+	var spentAt time.Time
+	err := r.db.WithContext(ctx).
+		Model(&models.Expense{}).
+		Select("spent_at").
+		Where("id = ? AND user_id = ?", id, userID).
+		Scan(&spentAt).Error
+	if err != nil {
+		return false, err
+	}
+
 	result := r.db.WithContext(ctx).
 		Where("id = ? AND user_id = ?", id, userID).
 		Delete(&models.Expense{})
@@ -120,5 +351,9 @@ func (r *ExpenseRepo) Delete(ctx context.Context, userID, id uuid.UUID) (bool, e
 	if result.Error != nil {
 		return false, result.Error
 	}
-	return result.RowsAffected > 0, nil
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	r.changed(userID, spentAt)
+	return true, nil
 }
@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type recordingObserver struct {
+	users []uuid.UUID
+	dates [][]time.Time
+}
+
+func (o *recordingObserver) ExpensesChanged(userID uuid.UUID, spentAt ...time.Time) {
+	o.users = append(o.users, userID)
+	o.dates = append(o.dates, spentAt)
+}
+
+func TestExpenseObservers(t *testing.T) {
+	// semua repo yang mengubah expense harus bisa diamati, termasuk yang di luar ExpenseRepo
+	expenses, recurring, categories := NewExpenseRepo(nil), NewRecurringExpenseRepo(nil), NewCategoryRepo(nil)
+	first, second := &recordingObserver{}, &recordingObserver{}
+	for _, o := range []ExpenseObserver{first, second} {
+		expenses.Observe(o)
+		recurring.Observe(o)
+		categories.Observe(o)
+	}
+
+	user := uuid.New()
+	at := time.Date(2026, 3, 31, 23, 0, 0, 0, time.UTC)
+	expenses.changed(user, at)
+	recurring.changed(user, at, at.AddDate(0, 0, 1))
+	categories.changed(user)
+
+	for _, o := range []*recordingObserver{first, second} {
+		if len(o.users) != 3 {
+			t.Fatalf("observer got %d notifications, want 3", len(o.users))
+		}
+		for _, u := range o.users {
+			if u != user {
+				t.Fatalf("user = %s, want %s", u, user)
+			}
+		}
+		if len(o.dates[0]) != 1 || len(o.dates[1]) != 2 || len(o.dates[2]) != 0 {
+			t.Fatalf("dates = %v", o.dates)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rifqi535/expense-tracker-api/internal/models"
+	"gorm.io/gorm"
+)
+
+type LoginEventRepo struct{ db *gorm.DB }
+
+func NewLoginEventRepo(db *gorm.DB) *LoginEventRepo { return &LoginEventRepo{db: db} }
+
+func (r *LoginEventRepo) Create(ctx context.Context, e *models.LoginEvent) error {
+	return r.db.WithContext(ctx).Create(e).Error
+}
+
+// CountFailuresByIP: jumlah login gagal dari satu IP sejak waktu tertentu
+func (r *LoginEventRepo) CountFailuresByIP(ctx context.Context, ip string, since time.Time) (int64, time.Time, error) {
+	var row struct {
+		Count int64
+		Last  *time.Time
+	}
+
+	err := r.db.WithContext(ctx).
+		Model(&models.LoginEvent{}).
+		Select("COUNT(*) AS count, MAX(created_at) AS last").
+		Where("ip = ? AND event = ? AND created_at >= ?", ip, models.LoginEventFailure, since).
+		Scan(&row).Error
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+
+	var last time.Time
+	if row.Last != nil {
+		last = *row.Last
+	}
+	return row.Count, last, nil
+}
+
+// ListByUser: riwayat event auth milik user, terbaru dulu
+func (r *LoginEventRepo) ListByUser(ctx context.Context, userID uuid.UUID, limit, offset int) ([]models.LoginEvent, error) {
+	var events []models.LoginEvent
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&events).Error
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rifqi535/expense-tracker-api/internal/models"
+	"gorm.io/gorm"
+)
+
+type MFARepo struct{ db *gorm.DB }
+
+func NewMFARepo(db *gorm.DB) *MFARepo { return &MFARepo{db: db} }
+
+// SetPendingSecret: simpan secret baru selama MFA belum aktif (enroll ulang menimpa secret lama)
+func (r *MFARepo) SetPendingSecret(ctx context.Context, userID uuid.UUID, secret string) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&models.User{}).
+		Where("id = ? AND totp_enabled_at IS NULL", userID).
+		Updates(map[string]interface{}{
+			"totp_secret": secret,
+			"updated_at":  time.Now(),
+		})
+
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// Enable: aktifkan MFA + ganti recovery codes dalam satu transaksi
+func (r *MFARepo) Enable(ctx context.Context, userID uuid.UUID, step int64, codes []models.MFARecoveryCode) (bool, error) {
+	enabled := false
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.User{}).
+			Where("id = ? AND totp_enabled_at IS NULL AND totp_secret IS NOT NULL", userID).
+			Updates(map[string]interface{}{
+				"totp_enabled_at": time.Now(),
+				"totp_last_step":  step,
+				"updated_at":      time.Now(),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		if err := tx.Where("user_id = ?", userID).Delete(&models.MFARecoveryCode{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&codes).Error; err != nil {
+			return err
+		}
+		enabled = true
+		return nil
+	})
+
+	return enabled, err
+}
+
+// Disable: matikan MFA, hapus secret & recovery codes
+func (r *MFARepo) Disable(ctx context.Context, userID uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).
+			Where("id = ?", userID).
+			Updates(map[string]interface{}{
+				"totp_secret":     nil,
+				"totp_enabled_at": nil,
+				"totp_last_step":  nil,
+				"updated_at":      time.Now(),
+			}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&models.MFARecoveryCode{}).Error
+	})
+}
+
+// ClaimStep: catat time-step TOTP yang sudah dipakai supaya kode yang sama tidak bisa dipakai ulang
+func (r *MFARepo) ClaimStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&models.User{}).
+		Where("id = ? AND (totp_last_step IS NULL OR totp_last_step < ?)", userID, step).
+		Update("totp_last_step", step)
+
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// UseRecoveryCode: tandai recovery code terpakai, false kalau tidak ada / sudah dipakai
+func (r *MFARepo) UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&models.MFARecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", time.Now())
+
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// CountUnusedRecoveryCodes: sisa recovery code yang masih bisa dipakai
+func (r *MFARepo) CountUnusedRecoveryCodes(ctx context.Context, userID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Model(&models.MFARecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&count).Error
+	return count, err
+}
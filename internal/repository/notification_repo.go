@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rifqi535/expense-tracker-api/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type NotificationRepo struct{ db *gorm.DB }
+
+func NewNotificationRepo(db *gorm.DB) *NotificationRepo { return &NotificationRepo{db: db} }
+
+// List inbox user, terbaru dulu. unreadOnly=true → cuma yang belum dibaca
+func (r *NotificationRepo) List(ctx context.Context, userID uuid.UUID, unreadOnly bool, limit, offset int) ([]models.Notification, error) {
+	var list []models.Notification
+	query := r.db.WithContext(ctx).Where("user_id = ?", userID)
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+	err := query.Order("created_at DESC, id").
+		Limit(limit).
+		Offset(offset).
+		Find(&list).Error
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// ListByUser: semua notifikasi milik user (buat export)
+func (r *NotificationRepo) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.Notification, error) {
+	var list []models.Notification
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at").
+		Find(&list).Error
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (r *NotificationRepo) UnreadCount(ctx context.Context, userID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *NotificationRepo) Create(ctx context.Context, n *models.Notification) error {
+	return r.db.WithContext(ctx).Create(n).Error
+}
+
+// MarkRead: tandai sudah dibaca (read=true) atau belum dibaca lagi (read=false)
+func (r *NotificationRepo) MarkRead(ctx context.Context, userID, id uuid.UUID, read bool) (bool, error) {
+	var readAt interface{}
+	if read {
+		readAt = gorm.Expr("COALESCE(read_at, ?)", time.Now())
+	}
+	result := r.db.WithContext(ctx).
+		Model(&models.Notification{}).
+		Where("id = ? AND user_id = ?", id, userID).
+		Update("read_at", readAt)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// MarkAllRead: tandai semua yang belum dibaca, return jumlah yang berubah
+func (r *NotificationRepo) MarkAllRead(ctx context.Context, userID uuid.UUID) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Update("read_at", time.Now())
+	return result.RowsAffected, result.Error
+}
+
+func (r *NotificationRepo) Delete(ctx context.Context, userID, id uuid.UUID) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Where("id = ? AND user_id = ?", id, userID).
+		Delete(&models.Notification{})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// GetSettings: setting channel user, default (email aktif, tanpa webhook) kalau belum pernah disimpan
+func (r *NotificationRepo) GetSettings(ctx context.Context, userID uuid.UUID) (*models.NotificationSettings, error) {
+	var s models.NotificationSettings
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).First(&s).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &models.NotificationSettings{UserID: userID, EmailEnabled: true}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// SaveSettings: insert / replace setting user
+func (r *NotificationRepo) SaveSettings(ctx context.Context, s *models.NotificationSettings) error {
+	s.UpdatedAt = time.Now()
+	return r.db.WithContext(ctx).
+		Select("*").
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"email_enabled", "webhook_url", "webhook_secret", "updated_at"}),
+		}).
+		Create(s).Error
+}
+
+// ClaimBudgetAlerts: catat ambang batas budget di bulan month, return ambang yang baru dicatat
+// (yang sudah pernah dicatat dilewati), jadi tiap ambang cuma dikirim sekali walau dicek paralel
+func (r *NotificationRepo) ClaimBudgetAlerts(ctx context.Context, budgetID uuid.UUID, month time.Time, thresholds []int) ([]int, error) {
+	// tanggal 1 dalam UTC supaya kolom DATE tidak bergeser karena zona waktu
+	month = time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+	var claimed []int
+	for _, th := range thresholds {
+		result := r.db.WithContext(ctx).
+			Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&models.BudgetAlert{BudgetID: budgetID, Month: month, Threshold: th, CreatedAt: time.Now()})
+		if result.Error != nil {
+			return claimed, result.Error
+		}
+		if result.RowsAffected == 1 {
+			claimed = append(claimed, th)
+		}
+	}
+	return claimed, nil
+}
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/rifqi535/expense-tracker-api/internal/models"
+	"gorm.io/gorm"
+)
+
+type OIDCRepo struct{ db *gorm.DB }
+
+func NewOIDCRepo(db *gorm.DB) *OIDCRepo { return &OIDCRepo{db: db} }
+
+func (r *OIDCRepo) CreateState(ctx context.Context, s *models.OIDCState) error {
+	return r.db.WithContext(ctx).Create(s).Error
+}
+
+// ConsumeState: ambil lalu hapus state (sekali pakai). Return nil kalau tidak ada / expired.
+func (r *OIDCRepo) ConsumeState(ctx context.Context, provider, stateHash string) (*models.OIDCState, error) {
+	var found *models.OIDCState
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var s models.OIDCState
+		err := tx.Where("state_hash = ? AND provider = ?", stateHash, provider).First(&s).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
+
+		result := tx.Where("id = ?", s.ID).Delete(&models.OIDCState{})
+		if result.Error != nil {
+			return result.Error
+		}
+		// dipakai request lain barusan
+		if result.RowsAffected == 0 || time.Now().After(s.ExpiresAt) {
+			return nil
+		}
+		found = &s
+		return nil
+	})
+
+	return found, err
+}
+
+// DeleteExpiredStates: bersih-bersih state login yang tidak pernah diselesaikan
+func (r *OIDCRepo) DeleteExpiredStates(ctx context.Context) error {
+	return r.db.WithContext(ctx).
+		Where("expires_at <= ?", time.Now()).
+		Delete(&models.OIDCState{}).Error
+}
+
+// FindUserByIdentity: user yang ter-link ke (provider, subject)
+func (r *OIDCRepo) FindUserByIdentity(ctx context.Context, provider, subject string) (*models.User, error) {
+	var u models.User
+
+	err := r.db.WithContext(ctx).
+		Joins("JOIN user_identities ui ON ui.user_id = users.id").
+		Where("ui.provider = ? AND ui.subject = ?", provider, subject).
+		First(&u).Error
+
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+func (r *OIDCRepo) CreateIdentity(ctx context.Context, i *models.UserIdentity) error {
+	return r.db.WithContext(ctx).Create(i).Error
+}
+
+// CreateUserWithIdentity: user baru dari login OIDC, sekaligus identity & kategori default-nya
+func (r *OIDCRepo) CreateUserWithIdentity(ctx context.Context, u *models.User, i *models.UserIdentity, categories []models.Category) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(u).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(i).Error; err != nil {
+			return err
+		}
+		if len(categories) == 0 {
+			return nil
+		}
+		return tx.Create(&categories).Error
+	})
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rifqi535/expense-tracker-api/internal/models"
+	"gorm.io/gorm"
+)
+
+type PasswordResetRepo struct{ db *gorm.DB }
+
+func NewPasswordResetRepo(db *gorm.DB) *PasswordResetRepo { return &PasswordResetRepo{db: db} }
+
+func (r *PasswordResetRepo) Create(ctx context.Context, t *models.PasswordResetToken) error {
+	return r.db.WithContext(ctx).Create(t).Error
+}
+
+// ResetPassword: pakai token reset (sekali pakai), ganti password, lalu revoke semua sesi user.
+// Return false kalau token tidak ada, sudah dipakai, atau expired.
+func (r *PasswordResetRepo) ResetPassword(ctx context.Context, tokenHash, passwordHash string) (bool, error) {
+	ok := false
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var t models.PasswordResetToken
+		err := tx.Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", tokenHash, time.Now()).
+			First(&t).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
+
+		now := time.Now()
+
+		// tandai semua token reset milik user sebagai terpakai (termasuk token lain yang belum dipakai)
+		result := tx.Model(&models.PasswordResetToken{}).
+			Where("user_id = ? AND used_at IS NULL", t.UserID).
+			Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			// sudah dipakai request lain barusan
+			return nil
+		}
+
+		if err := tx.Model(&models.User{}).
+			Where("id = ?", t.UserID).
+			Updates(map[string]interface{}{
+				"password_hash":           passwordHash,
+				"password_reset_required": false,
+				"updated_at":              now,
+			}).Error; err != nil {
+			return err
+		}
+
+		// semua JWT lama ikut mati karena family-nya di-revoke
+		if err := revokeUserSessions(tx, t.UserID); err != nil {
+			return err
+		}
+
+		ok = true
+		return nil
+	})
+
+	return ok, err
+}
+
+// DeleteExpired: bersih-bersih token yang sudah lewat
+func (r *PasswordResetRepo) DeleteExpired(ctx context.Context, userID uuid.UUID) error {
+	return r.db.WithContext(ctx).
+		Where("user_id = ? AND expires_at <= ?", userID, time.Now()).
+		Delete(&models.PasswordResetToken{}).Error
+}
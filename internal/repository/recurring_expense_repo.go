@@ -0,0 +1,310 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rifqi535/expense-tracker-api/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type RecurringExpenseRepo struct {
+	db *gorm.DB
+	expenseObservers
+}
+
+func NewRecurringExpenseRepo(db *gorm.DB) *RecurringExpenseRepo {
+	return &RecurringExpenseRepo{db: db}
+}
+
+func (r *RecurringExpenseRepo) Create(ctx context.Context, re *models.RecurringExpense) error {
+	return r.db.WithContext(ctx).Create(re).Error
+}
+
+func (r *RecurringExpenseRepo) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.RecurringExpense, error) {
+	var list []models.RecurringExpense
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at").
+		Find(&list).Error
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// GetByID: nil kalau tidak ada / bukan milik user
+func (r *RecurringExpenseRepo) GetByID(ctx context.Context, userID, id uuid.UUID) (*models.RecurringExpense, error) {
+	var re models.RecurringExpense
+	err := r.db.WithContext(ctx).
+		Where("id = ? AND user_id = ?", id, userID).
+		First(&re).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &re, nil
+}
+
+// Update: simpan template + jadwal + posisi scheduler
+func (r *RecurringExpenseRepo) Update(ctx context.Context, userID, id uuid.UUID, re *models.RecurringExpense) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&models.RecurringExpense{}).
+		Where("id = ? AND user_id = ?", id, userID).
+		Updates(map[string]interface{}{
+			"category_id":    re.CategoryID,
+			"title":          re.Title,
+			"description":    re.Description,
+			"amount":         re.Amount,
+			"currency":       re.Currency,
+			"freq":           re.Freq,
+			"interval_count": re.Interval,
+			"starts_at":      re.StartsAt,
+			"timezone":       re.Timezone,
+			"until_date":     re.UntilDate,
+			"max_count":      re.MaxCount,
+			"next_index":     re.NextIndex,
+			"next_run_at":    re.NextRunAt,
+			"updated_at":     time.Now(),
+		})
+
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// Delete: hapus jadwal, expense yang sudah dibuat tetap ada (recurring_id jadi NULL)
+func (r *RecurringExpenseRepo) Delete(ctx context.Context, userID, id uuid.UUID) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Where("id = ? AND user_id = ?", id, userID).
+		Delete(&models.RecurringExpense{})
+
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// Exceptions: pengecualian dalam rentang tanggal [from, to] (inklusif)
+func (r *RecurringExpenseRepo) Exceptions(ctx context.Context, id uuid.UUID, from, to time.Time) ([]models.RecurringException, error) {
+	var list []models.RecurringException
+	err := r.db.WithContext(ctx).
+		Where("recurring_id = ? AND occurrence_date BETWEEN ? AND ?", id, from.Format("2006-01-02"), to.Format("2006-01-02")).
+		Order("occurrence_date").
+		Find(&list).Error
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// Materialized: expense yang sudah dibuat dari jadwal dalam rentang [from, to], key = YYYY-MM-DD
+func (r *RecurringExpenseRepo) Materialized(ctx context.Context, id uuid.UUID, from, to time.Time) (map[string]uuid.UUID, error) {
+	var rows []struct {
+		ID             uuid.UUID
+		OccurrenceDate time.Time
+	}
+	err := r.db.WithContext(ctx).
+		Model(&models.Expense{}).
+		Select("id, occurrence_date").
+		Where("recurring_id = ? AND occurrence_date BETWEEN ? AND ?", id, from.Format("2006-01-02"), to.Format("2006-01-02")).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]uuid.UUID, len(rows))
+	for _, row := range rows {
+		out[row.OccurrenceDate.Format("2006-01-02")] = row.ID
+	}
+	return out, nil
+}
+
+// SaveException: simpan skip / override satu kejadian. Kalau expense-nya sudah terlanjur dibuat,
+// skip menghapusnya dan override mengubahnya. Balikin true kalau ada expense yang ikut berubah.
+func (r *RecurringExpenseRepo) SaveException(ctx context.Context, userID uuid.UUID, exc *models.RecurringException) (bool, error) {
+	var touched bool
+	var spentAt []time.Time
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		exc.CreatedAt = now
+		exc.UpdatedAt = now
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "recurring_id"}, {Name: "occurrence_date"}},
+			DoUpdates: clause.AssignmentColumns([]string{"action", "title", "description", "amount", "category_id", "updated_at"}),
+		}).Create(exc).Error
+		if err != nil {
+			return err
+		}
+
+		existing := func() *gorm.DB {
+			return tx.Where("user_id = ? AND recurring_id = ? AND occurrence_date = ?",
+				userID, exc.RecurringID, exc.OccurrenceDate.Format("2006-01-02"))
+		}
+		if err := existing().Model(&models.Expense{}).Pluck("spent_at", &spentAt).Error; err != nil {
+			return err
+		}
+
+		var result *gorm.DB
+		if exc.Action == models.RecurringSkip {
+			result = existing().Delete(&models.Expense{})
+		} else {
+			updates := map[string]interface{}{"updated_at": now}
+			if exc.Title != nil {
+				updates["title"] = *exc.Title
+			}
+			if exc.Description != nil {
+				updates["description"] = *exc.Description
+			}
+			if exc.Amount != nil {
+				updates["amount"] = *exc.Amount
+			}
+			if exc.CategoryID != nil {
+				updates["category_id"] = *exc.CategoryID
+			}
+			result = existing().Model(&models.Expense{}).Updates(updates)
+		}
+		if result.Error != nil {
+			return result.Error
+		}
+		touched = result.RowsAffected > 0
+		return nil
+	})
+	if err == nil && touched {
+		r.changed(userID, spentAt...)
+	}
+	return touched, err
+}
+
+// DeleteException: batalkan skip / override, hanya berlaku untuk kejadian yang belum dibuat
+func (r *RecurringExpenseRepo) DeleteException(ctx context.Context, id uuid.UUID, date time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Where("recurring_id = ? AND occurrence_date = ?", id, date.Format("2006-01-02")).
+		Delete(&models.RecurringException{})
+
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// MaterializeNext: ambil satu jadwal yang jatuh tempo lalu buat expense untuk semua kejadian
+// sampai now (maksimal max per panggilan, sisanya di panggilan berikutnya).
+// Aman dipanggil berulang / paralel: baris dikunci SKIP LOCKED dan expense unik per (recurring_id, occurrence_date).
+// found=false kalau tidak ada jadwal yang jatuh tempo.
+func (r *RecurringExpenseRepo) MaterializeNext(ctx context.Context, now time.Time, max int) (found bool, created int64, err error) {
+	var userID uuid.UUID
+	var spentAt []time.Time
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var due []models.RecurringExpense
+		err := tx.Raw(`
+SELECT * FROM recurring_expenses
+WHERE next_run_at IS NOT NULL AND next_run_at <= ?
+	AND user_id IN (SELECT id FROM users WHERE deletion_requested_at IS NULL)
+ORDER BY next_run_at
+LIMIT 1
+FOR UPDATE SKIP LOCKED`, now).Scan(&due).Error
+		if err != nil || len(due) == 0 {
+			return err
+		}
+		found = true
+		re := &due[0]
+		userID = re.UserID
+
+		rule, err := re.Rule()
+		if err != nil {
+			// data jadwal rusak, hentikan supaya tidak diambil terus-menerus
+			return tx.Model(re).Updates(map[string]interface{}{"next_run_at": nil, "last_run_at": now}).Error
+		}
+
+		n := re.NextIndex
+		exceptions := map[string]models.RecurringException{}
+		var list []models.RecurringException
+		err = tx.Where("recurring_id = ? AND occurrence_date >= ?", re.ID, rule.At(n).Format("2006-01-02")).
+			Find(&list).Error
+		if err != nil {
+			return err
+		}
+		for _, e := range list {
+			exceptions[e.OccurrenceDate.Format("2006-01-02")] = e
+		}
+
+		for i := 0; i < max && !rule.Done(n); i++ {
+			at := rule.At(n)
+			if at.After(now) {
+				break
+			}
+			n++
+
+			date := at.Format("2006-01-02")
+			exc, hasExc := exceptions[date]
+			if hasExc && exc.Action == models.RecurringSkip {
+				continue
+			}
+
+			occurrence, _ := time.Parse("2006-01-02", date)
+			e := &models.Expense{
+				ID:             uuid.New(),
+				Title:          re.Title,
+				Description:    re.Description,
+				Amount:         re.Amount,
+				Currency:       re.Currency,
+				SpentAt:        at,
+				SpentTZ:        &re.Timezone,
+				CategoryID:     re.CategoryID,
+				UserID:         re.UserID,
+				RecurringID:    &re.ID,
+				OccurrenceDate: &occurrence,
+				CreatedAt:      now,
+				UpdatedAt:      now,
+			}
+			if hasExc {
+				if exc.Title != nil {
+					e.Title = *exc.Title
+				}
+				if exc.Description != nil {
+					e.Description = exc.Description
+				}
+				if exc.Amount != nil {
+					e.Amount = *exc.Amount
+				}
+				if exc.CategoryID != nil {
+					e.CategoryID = *exc.CategoryID
+				}
+			}
+
+			result := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "recurring_id"}, {Name: "occurrence_date"}},
+				DoNothing: true,
+			}).Create(e)
+			if result.Error != nil {
+				return result.Error
+			}
+			created += result.RowsAffected
+			if result.RowsAffected > 0 {
+				spentAt = append(spentAt, e.SpentAt)
+			}
+		}
+
+		var nextRunAt *time.Time
+		if !rule.Done(n) {
+			t := rule.At(n)
+			nextRunAt = &t
+		}
+		return tx.Model(re).Updates(map[string]interface{}{
+			"next_index":  n,
+			"next_run_at": nextRunAt,
+			"last_run_at": now,
+		}).Error
+	})
+	if err == nil && created > 0 {
+		r.changed(userID, spentAt...)
+	}
+	return found, created, err
+}
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/rifqi535/expense-tracker-api/internal/models"
+	"gorm.io/gorm"
+)
+
+type RefreshTokenRepo struct{ db *gorm.DB }
+
+func NewRefreshTokenRepo(db *gorm.DB) *RefreshTokenRepo { return &RefreshTokenRepo{db: db} }
+
+func (r *RefreshTokenRepo) Create(ctx context.Context, t *models.RefreshToken) error {
+	return r.db.WithContext(ctx).Create(t).Error
+}
+
+// GetByHash: ambil refresh token berdasarkan hash-nya (termasuk yang sudah di-revoke)
+func (r *RefreshTokenRepo) GetByHash(ctx context.Context, hash string) (*models.RefreshToken, error) {
+	var t models.RefreshToken
+
+	err := r.db.WithContext(ctx).
+		Where("token_hash = ?", hash).
+		First(&t).Error
+
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// Rotate: tandai token lama sebagai terpakai dan simpan penggantinya dalam satu transaksi,
+// sekalian perpanjang sesinya. Return false kalau token lama ternyata sudah di-revoke duluan (dipakai dua kali).
+func (r *RefreshTokenRepo) Rotate(ctx context.Context, old, next *models.RefreshToken) (bool, error) {
+	rotated := false
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.RefreshToken{}).
+			Where("id = ? AND revoked_at IS NULL", old.ID).
+			Updates(map[string]interface{}{
+				"revoked_at":  time.Now(),
+				"replaced_by": next.ID,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		if err := tx.Create(next).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.Session{}).
+			Where("id = ?", next.FamilyID).
+			Updates(map[string]interface{}{
+				"expires_at":   next.ExpiresAt,
+				"last_seen_at": time.Now(),
+			}).Error; err != nil {
+			return err
+		}
+		rotated = true
+		return nil
+	})
+
+	return rotated, err
+}
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rifqi535/expense-tracker-api/internal/models"
+	"gorm.io/gorm"
+)
+
+// lastSeenInterval: last_seen_at tidak di-update tiap request, cukup sekali per interval ini
+const lastSeenInterval = time.Minute
+
+type SessionRepo struct{ db *gorm.DB }
+
+func NewSessionRepo(db *gorm.DB) *SessionRepo { return &SessionRepo{db: db} }
+
+// Start: bikin sesi baru beserta refresh token pertamanya dalam satu transaksi
+func (r *SessionRepo) Start(ctx context.Context, s *models.Session, rt *models.RefreshToken) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(s).Error; err != nil {
+			return err
+		}
+		return tx.Create(rt).Error
+	})
+}
+
+// ListActive: sesi user yang belum di-revoke dan belum expired, terakhir dipakai dulu
+func (r *SessionRepo) ListActive(ctx context.Context, userID uuid.UUID) ([]models.Session, error) {
+	var sessions []models.Session
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error
+	if err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// Touch: cek sesi masih aktif, sekalian update last_seen_at (dipakai AuthMiddleware)
+func (r *SessionRepo) Touch(ctx context.Context, id uuid.UUID) (bool, error) {
+	var s models.Session
+	err := r.db.WithContext(ctx).
+		Select("id", "last_seen_at").
+		Where("id = ? AND revoked_at IS NULL AND expires_at > ?", id, time.Now()).
+		Limit(1).
+		Find(&s).Error
+	if err != nil {
+		return false, err
+	}
+	if s.ID == uuid.Nil {
+		return false, nil
+	}
+
+	if time.Since(s.LastSeenAt) >= lastSeenInterval {
+		if err := r.db.WithContext(ctx).
+			Model(&models.Session{}).
+			Where("id = ?", id).
+			Update("last_seen_at", time.Now()).Error; err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// Revoke: revoke satu sesi milik user beserta semua refresh token-nya.
+// Return false kalau sesi tidak ada / bukan milik user / sudah di-revoke.
+func (r *SessionRepo) Revoke(ctx context.Context, userID, id uuid.UUID) (bool, error) {
+	ok := false
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		result := tx.Model(&models.Session{}).
+			Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+			Update("revoked_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		if err := tx.Model(&models.RefreshToken{}).
+			Where("family_id = ? AND revoked_at IS NULL", id).
+			Update("revoked_at", now).Error; err != nil {
+			return err
+		}
+
+		ok = true
+		return nil
+	})
+
+	return ok, err
+}
+
+// RevokeAllForUser: revoke semua sesi milik user (logout dari semua device)
+func (r *SessionRepo) RevokeAllForUser(ctx context.Context, userID uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return revokeUserSessions(tx, userID)
+	})
+}
+
+// revokeUserSessions revoke semua sesi + refresh token user (access token ikut ditolak AuthMiddleware)
+func revokeUserSessions(tx *gorm.DB, userID uuid.UUID) error {
+	now := time.Now()
+
+	if err := tx.Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", now).Error; err != nil {
+		return err
+	}
+
+	return tx.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", now).Error
+}
@@ -0,0 +1,175 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/rifqi535/expense-tracker-api/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MaxTagLength panjang maksimal nama tag (karakter)
+const MaxTagLength = 50
+
+var (
+	ErrInvalidTag = errors.New("tag must be 1-50 characters and must not contain commas")
+	ErrTagTaken   = errors.New("tag already exists")
+)
+
+// NormalizeTag: trim + huruf kecil, koma tidak boleh karena dipakai sebagai pemisah di ?tags=
+func NormalizeTag(name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" || utf8.RuneCountInString(name) > MaxTagLength || strings.Contains(name, ",") {
+		return "", ErrInvalidTag
+	}
+	return name, nil
+}
+
+// NormalizeTags: normalisasi + buang duplikat, urutan dipertahankan
+func NormalizeTags(names []string) ([]string, error) {
+	out := make([]string, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, n := range names {
+		tag, err := NormalizeTag(n)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			out = append(out, tag)
+		}
+	}
+	return out, nil
+}
+
+// TagWithCount tag + jumlah expense (yang belum dihapus) yang memakainya
+type TagWithCount struct {
+	models.Tag
+	ExpenseCount int64 `json:"expense_count"`
+}
+
+type TagRepo struct{ db *gorm.DB }
+
+func NewTagRepo(db *gorm.DB) *TagRepo { return &TagRepo{db: db} }
+
+func (r *TagRepo) ListByUser(ctx context.Context, userID uuid.UUID) ([]TagWithCount, error) {
+	var tags []TagWithCount
+	err := r.db.WithContext(ctx).
+		Table("tags t").
+		Select("t.*, COUNT(e.id) AS expense_count").
+		Joins("LEFT JOIN expense_tags et ON et.tag_id = t.id").
+		Joins("LEFT JOIN expenses e ON e.id = et.expense_id AND e.deleted_at IS NULL").
+		Where("t.user_id = ?", userID).
+		Group("t.id").
+		Order("t.name").
+		Scan(&tags).Error
+	if err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// Create: ErrTagTaken kalau nama sudah dipakai user yang sama
+func (r *TagRepo) Create(ctx context.Context, t *models.Tag) error {
+	result := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(t)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrTagTaken
+	}
+	return nil
+}
+
+// Rename: ErrTagTaken kalau nama baru sudah dipakai tag lain
+func (r *TagRepo) Rename(ctx context.Context, userID, id uuid.UUID, name string) (bool, error) {
+	var taken int64
+	err := r.db.WithContext(ctx).
+		Model(&models.Tag{}).
+		Where("user_id = ? AND name = ? AND id <> ?", userID, name, id).
+		Count(&taken).Error
+	if err != nil {
+		return false, err
+	}
+	if taken > 0 {
+		return false, ErrTagTaken
+	}
+
+	result := r.db.WithContext(ctx).
+		Model(&models.Tag{}).
+		Where("id = ? AND user_id = ?", id, userID).
+		Updates(map[string]interface{}{
+			"name":       name,
+			"updated_at": time.Now(),
+		})
+
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// Delete: relasi di expense_tags ikut terhapus (ON DELETE CASCADE), expense-nya tetap
+func (r *TagRepo) Delete(ctx context.Context, userID, id uuid.UUID) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Where("id = ? AND user_id = ?", id, userID).
+		Delete(&models.Tag{})
+
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// ensureTags: buat tag yang belum ada lalu balikin id semua nama (sudah dinormalisasi)
+func ensureTags(tx *gorm.DB, userID uuid.UUID, names []string) ([]uuid.UUID, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	now := time.Now()
+	tags := make([]models.Tag, 0, len(names))
+	for _, n := range names {
+		tags = append(tags, models.Tag{ID: uuid.New(), UserID: userID, Name: n, CreatedAt: now, UpdatedAt: now})
+	}
+	err := tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "name"}},
+		DoNothing: true,
+	}).Create(&tags).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []uuid.UUID
+	err = tx.Model(&models.Tag{}).
+		Where("user_id = ? AND name IN ?", userID, names).
+		Pluck("id", &ids).Error
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// setExpenseTags: ganti semua tag satu expense
+func setExpenseTags(tx *gorm.DB, userID, expenseID uuid.UUID, names []string) error {
+	if err := tx.Where("expense_id = ?", expenseID).Delete(&models.ExpenseTag{}).Error; err != nil {
+		return err
+	}
+	ids, err := ensureTags(tx, userID, names)
+	if err != nil || len(ids) == 0 {
+		return err
+	}
+
+	links := make([]models.ExpenseTag, 0, len(ids))
+	for _, id := range ids {
+		links = append(links, models.ExpenseTag{ExpenseID: expenseID, TagID: id})
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&links).Error
+}
@@ -2,12 +2,17 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/rifqi535/expense-tracker-api/internal/models"
 	"gorm.io/gorm"
 )
 
+// ErrEmailTaken: email sudah dipakai user lain
+var ErrEmailTaken = errors.New("email already in use")
+
 type UserRepo struct{ db *gorm.DB }
 
 func NewUserRepo(db *gorm.DB) *UserRepo { return &UserRepo{db: db} }
@@ -41,3 +46,466 @@ func (r *UserRepo) GetByID(ctx context.Context, id uuid.UUID) (*models.User, err
 	}
 	return &u, nil
 }
+
+// MarkEmailVerified: set email_verified_at kalau email user masih sama dengan yang ada di link
+func (r *UserRepo) MarkEmailVerified(ctx context.Context, id uuid.UUID, email string) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&models.User{}).
+		Where("id = ? AND email = ?", id, email).
+		Updates(map[string]interface{}{
+			"email_verified_at": gorm.Expr("COALESCE(email_verified_at, ?)", time.Now()),
+			"updated_at":        time.Now(),
+		})
+
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// ClaimVerificationResend: update verification_sent_at kalau cooldown sudah lewat.
+// Return false kalau masih cooldown atau email sudah terverifikasi.
+func (r *UserRepo) ClaimVerificationResend(ctx context.Context, id uuid.UUID, cooldown time.Duration) (bool, error) {
+	now := time.Now()
+	result := r.db.WithContext(ctx).
+		Model(&models.User{}).
+		Where("id = ? AND email_verified_at IS NULL", id).
+		Where("verification_sent_at IS NULL OR verification_sent_at <= ?", now.Add(-cooldown)).
+		Update("verification_sent_at", now)
+
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// Search: list user untuk admin, q dicocokkan ke nama / email. status: active | disabled | deleting | "" (semua)
+func (r *UserRepo) Search(ctx context.Context, q, role, status string, limit, offset int) ([]models.User, int64, error) {
+	var (
+		users []models.User
+		total int64
+	)
+
+	query := r.db.WithContext(ctx).Model(&models.User{})
+	if q != "" {
+		like := "%" + q + "%"
+		query = query.Where("name ILIKE ? OR email ILIKE ?", like, like)
+	}
+	if role != "" {
+		query = query.Where("role = ?", role)
+	}
+	switch status {
+	case "active":
+		query = query.Where("disabled_at IS NULL")
+	case "disabled":
+		query = query.Where("disabled_at IS NOT NULL")
+	case "deleting":
+		query = query.Where("deletion_requested_at IS NOT NULL")
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Order("created_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&users).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
+
+// SetDisabled: disable / enable akun. Disable sekaligus mematikan semua sesi user.
+func (r *UserRepo) SetDisabled(ctx context.Context, id uuid.UUID, disabled bool) (bool, error) {
+	ok := false
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var disabledAt interface{}
+		if disabled {
+			disabledAt = time.Now()
+		}
+
+		result := tx.Model(&models.User{}).
+			Where("id = ?", id).
+			Updates(map[string]interface{}{
+				"disabled_at": disabledAt,
+				"updated_at":  time.Now(),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		if disabled {
+			if err := revokeUserSessions(tx, id); err != nil {
+				return err
+			}
+		}
+		ok = true
+		return nil
+	})
+
+	return ok, err
+}
+
+// SetRole: ganti role, sesi lama di-revoke supaya claim role di token ikut berubah
+func (r *UserRepo) SetRole(ctx context.Context, id uuid.UUID, role string) (bool, error) {
+	ok := false
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.User{}).
+			Where("id = ?", id).
+			Updates(map[string]interface{}{
+				"role":       role,
+				"updated_at": time.Now(),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		if err := revokeUserSessions(tx, id); err != nil {
+			return err
+		}
+		ok = true
+		return nil
+	})
+
+	return ok, err
+}
+
+// RequirePasswordReset: user wajib reset password sebelum bisa login lagi
+func (r *UserRepo) RequirePasswordReset(ctx context.Context, id uuid.UUID) (bool, error) {
+	ok := false
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.User{}).
+			Where("id = ?", id).
+			Updates(map[string]interface{}{
+				"password_reset_required": true,
+				"updated_at":              time.Now(),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		if err := revokeUserSessions(tx, id); err != nil {
+			return err
+		}
+		ok = true
+		return nil
+	})
+
+	return ok, err
+}
+
+// RegisterFailedLogin: tambah counter gagal login, kunci akun kalau sudah mencapai lockThreshold (0 = tidak pernah dikunci).
+// Return counter terbaru dan locked_until (nil kalau tidak terkunci).
+func (r *UserRepo) RegisterFailedLogin(ctx context.Context, id uuid.UUID, lockThreshold int, lockFor time.Duration) (int, *time.Time, error) {
+	var row struct {
+		FailedLoginCount int
+		LockedUntil      *time.Time
+	}
+
+	now := time.Now()
+	err := r.db.WithContext(ctx).Raw(`
+UPDATE users SET
+	failed_login_count = failed_login_count + 1,
+	last_failed_login_at = ?,
+	locked_until = CASE WHEN ? > 0 AND failed_login_count + 1 >= ? THEN ?::timestamptz ELSE locked_until END
+WHERE id = ?
+RETURNING failed_login_count, locked_until`,
+		now, lockThreshold, lockThreshold, now.Add(lockFor), id,
+	).Scan(&row).Error
+	if err != nil {
+		return 0, nil, err
+	}
+	return row.FailedLoginCount, row.LockedUntil, nil
+}
+
+// ResetFailedLogins: dipanggil setelah login sukses
+func (r *UserRepo) ResetFailedLogins(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).
+		Model(&models.User{}).
+		Where("id = ? AND (failed_login_count > 0 OR locked_until IS NOT NULL)", id).
+		Updates(map[string]interface{}{
+			"failed_login_count": 0,
+			"locked_until":       nil,
+		}).Error
+}
+
+// Unlock: buka kunci akun (dari admin atau link di email)
+func (r *UserRepo) Unlock(ctx context.Context, id uuid.UUID) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&models.User{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"failed_login_count": 0,
+			"locked_until":       nil,
+			"updated_at":         time.Now(),
+		})
+
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// ProfileUpdate field profil yang boleh diubah user sendiri, nil = tidak berubah
+type ProfileUpdate struct {
+	Name      *string
+	Currency  *string
+	Timezone  *string
+	Locale    *string
+	WeekStart *int
+}
+
+// UpdateProfile: update data profil yang boleh diubah user sendiri
+func (r *UserRepo) UpdateProfile(ctx context.Context, id uuid.UUID, p ProfileUpdate) (bool, error) {
+	updates := map[string]interface{}{"updated_at": time.Now()}
+	if p.Name != nil {
+		updates["name"] = *p.Name
+	}
+	if p.Currency != nil {
+		updates["currency"] = *p.Currency
+	}
+	if p.Timezone != nil {
+		updates["timezone"] = *p.Timezone
+	}
+	if p.Locale != nil {
+		updates["locale"] = *p.Locale
+	}
+	if p.WeekStart != nil {
+		updates["week_start"] = *p.WeekStart
+	}
+
+	result := r.db.WithContext(ctx).
+		Model(&models.User{}).
+		Where("id = ?", id).
+		Updates(updates)
+
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// Preferences preferensi tampilan & laporan milik user
+type Preferences struct {
+	Currency  string
+	Timezone  string
+	Locale    string
+	WeekStart int
+}
+
+// GetPreferences: mata uang dasar, zona waktu, locale & awal minggu user
+func (r *UserRepo) GetPreferences(ctx context.Context, id uuid.UUID) (*Preferences, error) {
+	var p Preferences
+	result := r.db.WithContext(ctx).
+		Model(&models.User{}).
+		Select("currency", "timezone", "locale", "week_start").
+		Where("id = ?", id).
+		Scan(&p)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &p, nil
+}
+
+// ChangePassword: ganti password lalu revoke semua sesi lain, sesi keepSessionID tetap login
+func (r *UserRepo) ChangePassword(ctx context.Context, id uuid.UUID, passwordHash string, keepSessionID uuid.UUID) (bool, error) {
+	ok := false
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		result := tx.Model(&models.User{}).
+			Where("id = ?", id).
+			Updates(map[string]interface{}{
+				"password_hash": passwordHash,
+				"updated_at":    now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		if err := tx.Model(&models.Session{}).
+			Where("user_id = ? AND id <> ? AND revoked_at IS NULL", id, keepSessionID).
+			Update("revoked_at", now).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.RefreshToken{}).
+			Where("user_id = ? AND family_id <> ? AND revoked_at IS NULL", id, keepSessionID).
+			Update("revoked_at", now).Error; err != nil {
+			return err
+		}
+
+		ok = true
+		return nil
+	})
+
+	return ok, err
+}
+
+// SetPendingEmail: simpan email baru yang menunggu konfirmasi (link lama otomatis tidak berlaku)
+func (r *UserRepo) SetPendingEmail(ctx context.Context, id uuid.UUID, email string) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&models.User{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"pending_email": email,
+			"updated_at":    time.Now(),
+		})
+
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// ConfirmEmailChange: pindahkan pending_email ke email kalau masih sama dengan yang ada di link.
+// Return ErrEmailTaken kalau email-nya keburu dipakai user lain.
+func (r *UserRepo) ConfirmEmailChange(ctx context.Context, id uuid.UUID, email string) (bool, error) {
+	ok := false
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.User{}).
+			Where("email = ? AND id <> ?", email, id).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrEmailTaken
+		}
+
+		now := time.Now()
+		result := tx.Model(&models.User{}).
+			Where("id = ? AND pending_email = ?", id, email).
+			Updates(map[string]interface{}{
+				"email":             email,
+				"pending_email":     nil,
+				"email_verified_at": now,
+				"updated_at":        now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		ok = result.RowsAffected > 0
+		return nil
+	})
+
+	return ok, err
+}
+
+// RequestDeletion: nonaktifkan akun (masa tenggang sebelum dihapus permanen) & matikan semua sesi
+func (r *UserRepo) RequestDeletion(ctx context.Context, id uuid.UUID) (bool, error) {
+	ok := false
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.User{}).
+			Where("id = ? AND deletion_requested_at IS NULL", id).
+			Updates(map[string]interface{}{
+				"deletion_requested_at": time.Now(),
+				"updated_at":            time.Now(),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		if err := revokeUserSessions(tx, id); err != nil {
+			return err
+		}
+		ok = true
+		return nil
+	})
+
+	return ok, err
+}
+
+// CancelDeletion: batalkan penghapusan akun selama masa tenggang belum lewat
+func (r *UserRepo) CancelDeletion(ctx context.Context, id uuid.UUID, grace time.Duration) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&models.User{}).
+		Where("id = ? AND deletion_requested_at > ?", id, time.Now().Add(-grace)).
+		Updates(map[string]interface{}{
+			"deletion_requested_at": nil,
+			"updated_at":            time.Now(),
+		})
+
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// PurgeDeleted: hapus permanen akun yang masa tenggangnya sudah lewat.
+// Category & data lain ikut terhapus lewat ON DELETE CASCADE.
+// Balikin storage key lampiran milik akun yang dihapus, file-nya dibuang caller dari BlobStore.
+func (r *UserRepo) PurgeDeleted(ctx context.Context, grace time.Duration) (int64, []string, error) {
+	var (
+		purged      int64
+		storageKeys []string
+	)
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		due := tx.Model(&models.User{}).
+			Select("id").
+			Where("deletion_requested_at <= ?", time.Now().Add(-grace))
+
+		if err := tx.Model(&models.Attachment{}).
+			Where("user_id IN (?)", due).
+			Pluck("storage_key", &storageKeys).Error; err != nil {
+			return err
+		}
+
+		// expenses, recurring_expenses, budgets & budget_allocations.category_id ON DELETE RESTRICT, jadi dihapus duluan sebelum cascade ke categories
+		if err := tx.Unscoped().
+			Where("user_id IN (?)", due).
+			Delete(&models.Expense{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id IN (?)", due).
+			Delete(&models.RecurringExpense{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id IN (?)", due).
+			Delete(&models.Budget{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id IN (?)", due).
+			Delete(&models.BudgetAllocation{}).Error; err != nil {
+			return err
+		}
+
+		result := tx.Where("id IN (?)", due).Delete(&models.User{})
+		if result.Error != nil {
+			return result.Error
+		}
+		purged = result.RowsAffected
+		return nil
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return purged, storageKeys, nil
+}
@@ -0,0 +1,232 @@
+// Package rrule jadwal berulang gaya RFC 5545 RRULE (subset: FREQ, INTERVAL, COUNT, UNTIL)
+package rrule
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	Daily   = "daily"
+	Weekly  = "weekly"
+	Monthly = "monthly"
+	Yearly  = "yearly"
+)
+
+// batas wajar supaya jadwal tidak aneh-aneh
+const (
+	MaxInterval = 1000
+	MaxCount    = 10000
+)
+
+var (
+	ErrInvalidFreq      = errors.New("freq must be daily, weekly, monthly or yearly")
+	ErrInvalidInterval  = fmt.Errorf("interval must be between 1 and %d", MaxInterval)
+	ErrInvalidCount     = fmt.Errorf("count must be between 1 and %d", MaxCount)
+	ErrUntilBeforeStart = errors.New("until must not be before the start date")
+)
+
+// Rule satu jadwal. Start menentukan tanggal, jam & zona waktu semua kejadian:
+// bulanan tanggal 31 jatuh di tanggal terakhir bulan yang lebih pendek.
+type Rule struct {
+	Freq     string
+	Interval int
+	Start    time.Time
+	Until    *time.Time // tanggal lokal terakhir (inklusif), nil = tanpa batas
+	Count    int        // jumlah kejadian maksimal, 0 = tanpa batas
+}
+
+// Validate cek isi rule
+func (r Rule) Validate() error {
+	switch r.Freq {
+	case Daily, Weekly, Monthly, Yearly:
+	default:
+		return ErrInvalidFreq
+	}
+	if r.Interval < 1 || r.Interval > MaxInterval {
+		return ErrInvalidInterval
+	}
+	if r.Count < 0 || r.Count > MaxCount {
+		return ErrInvalidCount
+	}
+	if r.Until != nil && civil(*r.Until).Before(civil(r.Start)) {
+		return ErrUntilBeforeStart
+	}
+	return nil
+}
+
+// At waktu kejadian ke-n (mulai 0), dihitung dari Start (bukan dari kejadian sebelumnya)
+func (r Rule) At(n int) time.Time {
+	s := r.Start
+	step := n * r.Interval
+	switch r.Freq {
+	case Weekly:
+		return s.AddDate(0, 0, 7*step)
+	case Monthly:
+		return clamped(s, 0, step)
+	case Yearly:
+		return clamped(s, step, 0)
+	default:
+		return s.AddDate(0, 0, step)
+	}
+}
+
+// Done true kalau kejadian ke-n sudah di luar jadwal (lewat COUNT / UNTIL)
+func (r Rule) Done(n int) bool {
+	if r.Count > 0 && n >= r.Count {
+		return true
+	}
+	if r.Until != nil && civil(r.At(n)).After(civil(*r.Until)) {
+		return true
+	}
+	return false
+}
+
+// IndexOf nomor kejadian yang jatuh pada tanggal lokal date
+func (r Rule) IndexOf(date time.Time) (int, bool) {
+	start, d := civil(r.Start), civil(date)
+	if d.Before(start) {
+		return 0, false
+	}
+
+	var units int
+	switch r.Freq {
+	case Daily:
+		units = int(d.Sub(start).Hours() / 24)
+	case Weekly:
+		units = int(d.Sub(start).Hours() / 24 / 7)
+	case Monthly:
+		units = (d.Year()-start.Year())*12 + int(d.Month()-start.Month())
+	case Yearly:
+		units = d.Year() - start.Year()
+	}
+	if units%r.Interval != 0 {
+		return 0, false
+	}
+
+	n := units / r.Interval
+	if !civil(r.At(n)).Equal(d) || r.Done(n) {
+		return 0, false
+	}
+	return n, true
+}
+
+// FirstAfter nomor kejadian pertama yang waktunya setelah t
+func (r Rule) FirstAfter(t time.Time) int {
+	// perkiraan kasar lalu maju satu per satu, cukup karena interval minimal 1 hari
+	n := 0
+	if t.After(r.Start) {
+		days := int(t.Sub(r.Start).Hours() / 24)
+		switch r.Freq {
+		case Daily:
+			n = days / r.Interval
+		case Weekly:
+			n = days / 7 / r.Interval
+		case Monthly:
+			n = days / 31 / r.Interval
+		case Yearly:
+			n = days / 366 / r.Interval
+		}
+		if n > 0 {
+			n--
+		}
+	}
+	for !r.At(n).After(t) {
+		n++
+	}
+	return n
+}
+
+// String bentuk RRULE, mis. "FREQ=MONTHLY;INTERVAL=1;COUNT=12"
+func (r Rule) String() string {
+	parts := []string{"FREQ=" + strings.ToUpper(r.Freq), "INTERVAL=" + strconv.Itoa(r.Interval)}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.Format("20060102"))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Spec bagian rule yang bisa ditulis sebagai string RRULE (tanpa Start)
+type Spec struct {
+	Freq     string
+	Interval int
+	Count    int
+	Until    *time.Time // tanggal (UTC, jam 00:00)
+}
+
+// Parse string RRULE, mis. "RRULE:FREQ=WEEKLY;INTERVAL=2;UNTIL=20251231"
+func Parse(s string) (Spec, error) {
+	spec := Spec{Interval: 1}
+	s = strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")
+	if s == "" {
+		return spec, errors.New("empty rrule")
+	}
+
+	for _, part := range strings.Split(s, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return spec, fmt.Errorf("invalid rrule part %q", part)
+		}
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			spec.Freq = strings.ToLower(value)
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return spec, ErrInvalidInterval
+			}
+			spec.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return spec, ErrInvalidCount
+			}
+			spec.Count = n
+		case "UNTIL":
+			// cuma tanggal yang dipakai, jam (kalau ada) diabaikan
+			if len(value) < 8 {
+				return spec, fmt.Errorf("invalid UNTIL %q", value)
+			}
+			t, err := time.Parse("20060102", value[:8])
+			if err != nil {
+				return spec, fmt.Errorf("invalid UNTIL %q", value)
+			}
+			spec.Until = &t
+		default:
+			return spec, fmt.Errorf("unsupported rrule part %q", key)
+		}
+	}
+
+	switch spec.Freq {
+	case Daily, Weekly, Monthly, Yearly:
+	default:
+		return spec, ErrInvalidFreq
+	}
+	if spec.Count > 0 && spec.Until != nil {
+		return spec, errors.New("rrule cannot have both COUNT and UNTIL")
+	}
+	return spec, nil
+}
+
+// clamped tambah tahun/bulan tanpa "meluber" ke bulan berikutnya (31 Jan + 1 bulan = 28/29 Feb)
+func clamped(t time.Time, years, months int) time.Time {
+	y, m, d := t.Date()
+	first := time.Date(y+years, m+time.Month(months), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	last := first.AddDate(0, 1, -1).Day()
+	if d > last {
+		d = last
+	}
+	return time.Date(first.Year(), first.Month(), d, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+}
+
+// civil tanggal lokal t sebagai jam 00:00 UTC, untuk bandingkan / hitung selisih hari tanpa efek DST
+func civil(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,217 @@
+package rrule
+
+import (
+	"testing"
+	"time"
+)
+
+func date(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 9, 30, 0, 0, time.UTC)
+}
+
+func ptr(t time.Time) *time.Time { return &t }
+
+func TestAt(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("tzdata not available:", err)
+	}
+
+	tests := []struct {
+		name string
+		rule Rule
+		n    int
+		want time.Time
+	}{
+		{"daily first", Rule{Freq: Daily, Interval: 1, Start: date(2025, 1, 1)}, 0, date(2025, 1, 1)},
+		{"daily interval 3", Rule{Freq: Daily, Interval: 3, Start: date(2025, 1, 30)}, 2, date(2025, 2, 5)},
+		{"weekly interval 2", Rule{Freq: Weekly, Interval: 2, Start: date(2025, 1, 6)}, 3, date(2025, 2, 17)},
+		{"monthly 31st clamps to feb", Rule{Freq: Monthly, Interval: 1, Start: date(2025, 1, 31)}, 1, date(2025, 2, 28)},
+		{"monthly 31st clamps to leap feb", Rule{Freq: Monthly, Interval: 1, Start: date(2024, 1, 31)}, 1, date(2024, 2, 29)},
+		{"monthly 31st back to 31 after short month", Rule{Freq: Monthly, Interval: 1, Start: date(2025, 1, 31)}, 2, date(2025, 3, 31)},
+		{"monthly 31st clamps to 30", Rule{Freq: Monthly, Interval: 1, Start: date(2025, 1, 31)}, 3, date(2025, 4, 30)},
+		{"monthly across year", Rule{Freq: Monthly, Interval: 5, Start: date(2025, 10, 15)}, 1, date(2026, 3, 15)},
+		{"yearly leap day clamps", Rule{Freq: Yearly, Interval: 1, Start: date(2024, 2, 29)}, 1, date(2025, 2, 28)},
+		{"yearly leap day back on leap year", Rule{Freq: Yearly, Interval: 1, Start: date(2024, 2, 29)}, 4, date(2028, 2, 29)},
+		{
+			"daily keeps wall clock across DST",
+			Rule{Freq: Daily, Interval: 1, Start: time.Date(2025, 3, 8, 9, 0, 0, 0, ny)},
+			1,
+			time.Date(2025, 3, 9, 9, 0, 0, 0, ny),
+		},
+		{
+			"monthly keeps wall clock across DST",
+			Rule{Freq: Monthly, Interval: 1, Start: time.Date(2025, 10, 31, 9, 0, 0, 0, ny)},
+			1,
+			time.Date(2025, 11, 30, 9, 0, 0, 0, ny),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rule.At(tt.n); !got.Equal(tt.want) {
+				t.Fatalf("At(%d) = %s, want %s", tt.n, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDone(t *testing.T) {
+	tests := []struct {
+		name string
+		rule Rule
+		n    int
+		want bool
+	}{
+		{"no limit", Rule{Freq: Daily, Interval: 1, Start: date(2025, 1, 1)}, 1000, false},
+		{"count last", Rule{Freq: Daily, Interval: 1, Start: date(2025, 1, 1), Count: 3}, 2, false},
+		{"count past", Rule{Freq: Daily, Interval: 1, Start: date(2025, 1, 1), Count: 3}, 3, true},
+		{"until inclusive", Rule{Freq: Monthly, Interval: 1, Start: date(2025, 1, 31), Until: ptr(time.Date(2025, 2, 28, 0, 0, 0, 0, time.UTC))}, 1, false},
+		{"until past", Rule{Freq: Monthly, Interval: 1, Start: date(2025, 1, 31), Until: ptr(time.Date(2025, 2, 28, 0, 0, 0, 0, time.UTC))}, 2, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rule.Done(tt.n); got != tt.want {
+				t.Fatalf("Done(%d) = %v, want %v", tt.n, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIndexOf(t *testing.T) {
+	monthEnd := Rule{Freq: Monthly, Interval: 1, Start: date(2025, 1, 31)}
+
+	tests := []struct {
+		name   string
+		rule   Rule
+		day    time.Time
+		want   int
+		wantOK bool
+	}{
+		{"start", monthEnd, date(2025, 1, 31), 0, true},
+		{"clamped feb", monthEnd, date(2025, 2, 28), 1, true},
+		{"not the clamped day", monthEnd, date(2025, 2, 27), 0, false},
+		{"clamped apr", monthEnd, date(2025, 4, 30), 3, true},
+		{"time of day ignored", monthEnd, time.Date(2025, 3, 31, 23, 59, 0, 0, time.UTC), 2, true},
+		{"before start", monthEnd, date(2024, 12, 31), 0, false},
+		{"off interval month", Rule{Freq: Monthly, Interval: 2, Start: date(2025, 1, 10)}, date(2025, 2, 10), 0, false},
+		{"on interval month", Rule{Freq: Monthly, Interval: 2, Start: date(2025, 1, 10)}, date(2025, 5, 10), 2, true},
+		{"weekly wrong weekday", Rule{Freq: Weekly, Interval: 1, Start: date(2025, 1, 6)}, date(2025, 1, 14), 0, false},
+		{"weekly", Rule{Freq: Weekly, Interval: 1, Start: date(2025, 1, 6)}, date(2025, 1, 20), 2, true},
+		{"daily interval", Rule{Freq: Daily, Interval: 3, Start: date(2025, 1, 1)}, date(2025, 1, 10), 3, true},
+		{"daily off interval", Rule{Freq: Daily, Interval: 3, Start: date(2025, 1, 1)}, date(2025, 1, 9), 0, false},
+		{"yearly leap clamp", Rule{Freq: Yearly, Interval: 1, Start: date(2024, 2, 29)}, date(2025, 2, 28), 1, true},
+		{"past count", Rule{Freq: Daily, Interval: 1, Start: date(2025, 1, 1), Count: 2}, date(2025, 1, 3), 0, false},
+		{"past until", Rule{Freq: Daily, Interval: 1, Start: date(2025, 1, 1), Until: ptr(time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC))}, date(2025, 1, 3), 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.rule.IndexOf(tt.day)
+			if ok != tt.wantOK || got != tt.want {
+				t.Fatalf("IndexOf(%s) = %d, %v, want %d, %v", tt.day.Format("2006-01-02"), got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestFirstAfter(t *testing.T) {
+	tests := []struct {
+		name string
+		rule Rule
+		t    time.Time
+		want int
+	}{
+		{"before start", Rule{Freq: Daily, Interval: 1, Start: date(2025, 1, 10)}, date(2025, 1, 1), 0},
+		{"exactly at start", Rule{Freq: Daily, Interval: 1, Start: date(2025, 1, 10)}, date(2025, 1, 10), 1},
+		{"just before occurrence", Rule{Freq: Daily, Interval: 1, Start: date(2025, 1, 10)}, date(2025, 1, 12).Add(-time.Second), 2},
+		{"weekly catch-up", Rule{Freq: Weekly, Interval: 2, Start: date(2025, 1, 6)}, date(2025, 3, 1), 4},
+		// downtime panjang: kejadian bulanan tanggal 31 yang ter-clamp tetap dihitung
+		{"monthly month-end catch-up", Rule{Freq: Monthly, Interval: 1, Start: date(2025, 1, 31)}, date(2025, 6, 30), 6},
+		{"monthly exactly at clamped", Rule{Freq: Monthly, Interval: 1, Start: date(2025, 1, 31)}, date(2025, 2, 28), 2},
+		{"yearly many years later", Rule{Freq: Yearly, Interval: 1, Start: date(2024, 2, 29)}, date(2030, 1, 1), 6},
+		{"daily after a year", Rule{Freq: Daily, Interval: 7, Start: date(2025, 1, 1)}, date(2026, 1, 1), 53},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.rule.FirstAfter(tt.t)
+			if got != tt.want {
+				t.Fatalf("FirstAfter(%s) = %d (%s), want %d (%s)", tt.t, got, tt.rule.At(got), tt.want, tt.rule.At(tt.want))
+			}
+			// hasilnya harus kejadian pertama yang lewat t
+			if !tt.rule.At(got).After(tt.t) || (got > 0 && tt.rule.At(got-1).After(tt.t)) {
+				t.Fatalf("FirstAfter(%s) = %d is not the first occurrence after t", tt.t, got)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	start := date(2025, 1, 10)
+	tests := []struct {
+		name string
+		rule Rule
+		want error
+	}{
+		{"ok", Rule{Freq: Weekly, Interval: 1, Start: start}, nil},
+		{"bad freq", Rule{Freq: "hourly", Interval: 1, Start: start}, ErrInvalidFreq},
+		{"zero interval", Rule{Freq: Daily, Interval: 0, Start: start}, ErrInvalidInterval},
+		{"interval too big", Rule{Freq: Daily, Interval: MaxInterval + 1, Start: start}, ErrInvalidInterval},
+		{"count too big", Rule{Freq: Daily, Interval: 1, Start: start, Count: MaxCount + 1}, ErrInvalidCount},
+		{"until same day", Rule{Freq: Daily, Interval: 1, Start: start, Until: ptr(time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC))}, nil},
+		{"until before start", Rule{Freq: Daily, Interval: 1, Start: start, Until: ptr(time.Date(2025, 1, 9, 0, 0, 0, 0, time.UTC))}, ErrUntilBeforeStart},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rule.Validate(); got != tt.want {
+				t.Fatalf("Validate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Spec
+		wantErr bool
+	}{
+		{in: "RRULE:FREQ=MONTHLY;INTERVAL=1;COUNT=12", want: Spec{Freq: Monthly, Interval: 1, Count: 12}},
+		{in: "FREQ=weekly;INTERVAL=2", want: Spec{Freq: Weekly, Interval: 2}},
+		{in: "FREQ=DAILY", want: Spec{Freq: Daily, Interval: 1}},
+		{in: "FREQ=YEARLY;UNTIL=20301231T000000Z", want: Spec{Freq: Yearly, Interval: 1, Until: ptr(time.Date(2030, 12, 31, 0, 0, 0, 0, time.UTC))}},
+		{in: "", wantErr: true},
+		{in: "FREQ=HOURLY", wantErr: true},
+		{in: "FREQ=DAILY;COUNT=0", wantErr: true},
+		{in: "FREQ=DAILY;COUNT=2;UNTIL=20301231", wantErr: true},
+		{in: "FREQ=DAILY;BYDAY=MO", wantErr: true},
+		{in: "FREQ=DAILY;UNTIL=2030", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := Parse(tt.in)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Parse(%q) = %+v, want error", tt.in, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Freq != tt.want.Freq || got.Interval != tt.want.Interval || got.Count != tt.want.Count ||
+				(got.Until == nil) != (tt.want.Until == nil) || (got.Until != nil && !got.Until.Equal(*tt.want.Until)) {
+				t.Fatalf("Parse(%q) = %+v, want %+v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStringRoundTrip(t *testing.T) {
+	r := Rule{Freq: Weekly, Interval: 2, Start: date(2025, 1, 6), Until: ptr(time.Date(2025, 12, 31, 0, 0, 0, 0, time.UTC))}
+	spec, err := Parse(r.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spec.Freq != r.Freq || spec.Interval != r.Interval || !spec.Until.Equal(*r.Until) {
+		t.Fatalf("round trip %q = %+v", r.String(), spec)
+	}
+}
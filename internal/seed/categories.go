@@ -0,0 +1,139 @@
+// Package seed data awal untuk user baru (kategori default per bahasa)
+package seed
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rifqi535/expense-tracker-api/internal/calendar"
+	"github.com/rifqi535/expense-tracker-api/internal/models"
+	"github.com/rifqi535/expense-tracker-api/internal/repository"
+)
+
+// Category satu kategori default, urutan di list = sort_order
+type Category struct {
+	Title string `json:"title"`
+	Color string `json:"color"`
+	Icon  string `json:"icon"`
+}
+
+// CategorySet kategori default per locale ("id-ID") atau bahasa ("id"),
+// "en" dipakai kalau tidak ada yang cocok (tidak ada "en" = user tidak dapat kategori default)
+type CategorySet map[string][]Category
+
+// fallbackLocale dipakai kalau locale user tidak ada di set
+const fallbackLocale = "en"
+
+// BuiltinCategories set bawaan kalau DEFAULT_CATEGORIES_FILE tidak diisi
+func BuiltinCategories() CategorySet {
+	return CategorySet{
+		"en": {
+			{Title: "Food & Drinks", Color: "#F97316", Icon: "utensils"},
+			{Title: "Groceries", Color: "#22C55E", Icon: "shopping-basket"},
+			{Title: "Transport", Color: "#3B82F6", Icon: "car"},
+			{Title: "Bills & Utilities", Color: "#EAB308", Icon: "receipt"},
+			{Title: "Shopping", Color: "#EC4899", Icon: "shopping-bag"},
+			{Title: "Health", Color: "#EF4444", Icon: "heart-pulse"},
+			{Title: "Entertainment", Color: "#8B5CF6", Icon: "film"},
+			{Title: "Education", Color: "#14B8A6", Icon: "book"},
+			{Title: "Other", Color: "#6B7280", Icon: "dots"},
+		},
+		"id": {
+			{Title: "Makan & Minum", Color: "#F97316", Icon: "utensils"},
+			{Title: "Belanja Harian", Color: "#22C55E", Icon: "shopping-basket"},
+			{Title: "Transportasi", Color: "#3B82F6", Icon: "car"},
+			{Title: "Tagihan", Color: "#EAB308", Icon: "receipt"},
+			{Title: "Belanja", Color: "#EC4899", Icon: "shopping-bag"},
+			{Title: "Kesehatan", Color: "#EF4444", Icon: "heart-pulse"},
+			{Title: "Hiburan", Color: "#8B5CF6", Icon: "film"},
+			{Title: "Pendidikan", Color: "#14B8A6", Icon: "book"},
+			{Title: "Lainnya", Color: "#6B7280", Icon: "dots"},
+		},
+	}
+}
+
+// LoadCategories baca set dari file JSON: {"en": [{"title": "Food", "color": "#F97316", "icon": "utensils"}], "id-ID": [...]}.
+// path kosong = set bawaan.
+func LoadCategories(path string) (CategorySet, error) {
+	if path == "" {
+		return BuiltinCategories(), nil
+	}
+
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var raw CategorySet
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	set := make(CategorySet, len(raw))
+	for locale, list := range raw {
+		key, err := calendar.NormalizeLocale(locale)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		seen := map[string]bool{}
+		for i, c := range list {
+			c.Title = strings.TrimSpace(c.Title)
+			if c.Title == "" || seen[c.Title] {
+				return nil, fmt.Errorf("%s: %s[%d]: title is empty or duplicated", path, locale, i)
+			}
+			seen[c.Title] = true
+			if c.Color, err = repository.NormalizeColor(c.Color); err != nil {
+				return nil, fmt.Errorf("%s: %s[%d]: %w", path, locale, i, err)
+			}
+			if !repository.ValidIcon(c.Icon) {
+				return nil, fmt.Errorf("%s: %s[%d]: %w", path, locale, i, repository.ErrInvalidIcon)
+			}
+			list[i] = c
+		}
+		set[key] = list
+	}
+	return set, nil
+}
+
+// For kategori untuk locale: cocok persis, lalu bahasanya saja, lalu "en"
+func (s CategorySet) For(locale string) []Category {
+	if locale, err := calendar.NormalizeLocale(locale); err == nil {
+		if list, ok := s[locale]; ok {
+			return list
+		}
+		lang, _, _ := strings.Cut(locale, "-")
+		if list, ok := s[lang]; ok {
+			return list
+		}
+	}
+	return s[fallbackLocale]
+}
+
+// Models kategori default siap disimpan untuk userID
+func (s CategorySet) Models(userID uuid.UUID, locale string, now time.Time) []models.Category {
+	list := s.For(locale)
+	out := make([]models.Category, 0, len(list))
+	for i, c := range list {
+		cat := models.Category{
+			ID:        uuid.New(),
+			Title:     c.Title,
+			UserID:    userID,
+			SortOrder: i,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		if c.Color != "" {
+			color := c.Color
+			cat.Color = &color
+		}
+		if c.Icon != "" {
+			icon := c.Icon
+			cat.Icon = &icon
+		}
+		out = append(out, cat)
+	}
+	return out
+}
@@ -0,0 +1,151 @@
+package token
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// key satu kunci verifikasi (dan kunci signing kalau private-nya ada)
+type key struct {
+	kid     string
+	method  jwt.SigningMethod
+	private crypto.Signer // nil kalau cuma public key (kunci lama yang sudah dirotasi)
+	public  crypto.PublicKey
+}
+
+// loadKeysDir baca semua <kid>.pem di dir, isinya private key (PKCS#8 / PKCS#1) atau public key (PKIX)
+func loadKeysDir(dir string) (map[string]*key, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	keys := map[string]*key{}
+	for _, f := range files {
+		kid := strings.TrimSuffix(filepath.Base(f), ".pem")
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		k, err := parseKeyPEM(kid, data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f, err)
+		}
+		keys[kid] = k
+	}
+	return keys, nil
+}
+
+func parseKeyPEM(kid string, data []byte) (*key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		signer, ok := parsed.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("unsupported private key type %T", parsed)
+		}
+		return newKey(kid, signer, signer.Public())
+	case "RSA PRIVATE KEY":
+		parsed, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return newKey(kid, parsed, parsed.Public())
+	case "PUBLIC KEY":
+		parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return newKey(kid, nil, parsed)
+	case "RSA PUBLIC KEY":
+		parsed, err := x509.ParsePKCS1PublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return newKey(kid, nil, parsed)
+	default:
+		return nil, fmt.Errorf("unsupported PEM type %q", block.Type)
+	}
+}
+
+func newKey(kid string, private crypto.Signer, public crypto.PublicKey) (*key, error) {
+	k := &key{kid: kid, private: private, public: public}
+
+	switch pub := public.(type) {
+	case *rsa.PublicKey:
+		if pub.N.BitLen() < 2048 {
+			return nil, errors.New("RSA key must be at least 2048 bits")
+		}
+		k.method = jwt.SigningMethodRS256
+	case ed25519.PublicKey:
+		k.method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("unsupported key type %T, use RSA or Ed25519", public)
+	}
+	return k, nil
+}
+
+// generateEphemeralKey kunci Ed25519 sementara buat development (hilang setiap restart)
+func generateEphemeralKey() (*key, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return newKey("dev-ephemeral", priv, pub)
+}
+
+// JWK public key dalam format RFC 7517
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+
+	// OKP (Ed25519)
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+func (k *key) jwk() JWK {
+	b64 := base64.RawURLEncoding
+	j := JWK{Kid: k.kid, Use: "sig", Alg: k.method.Alg()}
+
+	switch pub := k.public.(type) {
+	case *rsa.PublicKey:
+		j.Kty = "RSA"
+		j.N = b64.EncodeToString(pub.N.Bytes())
+		j.E = b64.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+	case ed25519.PublicKey:
+		j.Kty = "OKP"
+		j.Crv = "Ed25519"
+		j.X = b64.EncodeToString(pub)
+	}
+	return j
+}
@@ -0,0 +1,259 @@
+// Package token satu-satunya tempat bikin & verifikasi JWT.
+// Token ditandatangani RS256 / EdDSA dengan header kid, dan semua public key
+// dipublikasikan di /.well-known/jwks.json supaya service lain bisa verifikasi.
+package token
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// jenis token di claim "typ"
+const (
+	typeAccess     = "access"
+	typeMFAPending = "mfa_pending"
+)
+
+// Claims isi access token
+type Claims struct {
+	UserID    uuid.UUID
+	SessionID uuid.UUID
+	Role      string
+	// Restricted: email belum diverifikasi, cuma boleh akses route yang pakai AllowRestricted()
+	Restricted bool
+}
+
+// Options konfigurasi Service
+type Options struct {
+	KeysDir        string // folder berisi <kid>.pem
+	ActiveKID      string // kid yang dipakai untuk signing
+	Issuer         string
+	AccessTokenTTL time.Duration
+	MFAPendingTTL  time.Duration
+}
+
+type Service struct {
+	signer    *key
+	keys      map[string]*key
+	issuer    string
+	accessTTL time.Duration
+	mfaTTL    time.Duration
+}
+
+// NewService load kunci dari KeysDir. Kalau KeysDir kosong, pakai kunci Ed25519 sementara (khusus development).
+func NewService(opts Options) (*Service, error) {
+	s := &Service{
+		keys:      map[string]*key{},
+		issuer:    opts.Issuer,
+		accessTTL: opts.AccessTokenTTL,
+		mfaTTL:    opts.MFAPendingTTL,
+	}
+	if s.accessTTL <= 0 {
+		s.accessTTL = 15 * time.Minute
+	}
+	if s.mfaTTL <= 0 {
+		s.mfaTTL = 5 * time.Minute
+	}
+
+	if opts.KeysDir == "" {
+		k, err := generateEphemeralKey()
+		if err != nil {
+			return nil, err
+		}
+		log.Println("[WARN] JWT_KEYS_DIR kosong, pakai kunci sementara (semua token invalid setelah restart)")
+		s.keys[k.kid] = k
+		s.signer = k
+		return s, nil
+	}
+
+	keys, err := loadKeysDir(opts.KeysDir)
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no *.pem keys found in %s", opts.KeysDir)
+	}
+	s.keys = keys
+
+	activeKID := opts.ActiveKID
+	if activeKID == "" {
+		// tidak diset → harus tepat satu private key
+		for kid, k := range keys {
+			if k.private == nil {
+				continue
+			}
+			if activeKID != "" {
+				return nil, errors.New("several private keys found, set JWT_ACTIVE_KID")
+			}
+			activeKID = kid
+		}
+	}
+
+	signer, ok := keys[activeKID]
+	if !ok || signer.private == nil {
+		return nil, fmt.Errorf("no private key for active kid %q", activeKID)
+	}
+	s.signer = signer
+	return s, nil
+}
+
+// AccessTTL umur access token
+func (s *Service) AccessTTL() time.Duration { return s.accessTTL }
+
+// MFAPendingTTL umur token mfa_pending
+func (s *Service) MFAPendingTTL() time.Duration { return s.mfaTTL }
+
+// GenerateAccessToken bikin access token (umur pendek) dari userID + sesi login-nya
+func (s *Service) GenerateAccessToken(c Claims) (string, error) {
+	claims := s.baseClaims(typeAccess, c.UserID, s.accessTTL)
+	claims["sid"] = c.SessionID.String()
+	claims["role"] = c.Role
+	if c.Restricted {
+		claims["restricted"] = true
+	}
+	return s.sign(claims)
+}
+
+// GenerateMFAPendingToken token sementara setelah password benar, cuma bisa ditukar di /auth/mfa/verify
+func (s *Service) GenerateMFAPendingToken(userID uuid.UUID) (string, error) {
+	return s.sign(s.baseClaims(typeMFAPending, userID, s.mfaTTL))
+}
+
+// ParseAccessToken validasi access token & extract claims
+func (s *Service) ParseAccessToken(tokenString string) (*Claims, error) {
+	claims, err := s.parse(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	// token mfa_pending (atau jenis lain) tidak boleh dipakai sebagai access token
+	if typ, _ := claims["typ"].(string); typ != typeAccess {
+		if typ == typeMFAPending {
+			return nil, errors.New("mfa verification required")
+		}
+		return nil, fmt.Errorf("unexpected token type %q", typ)
+	}
+
+	uid, err := userIDFromClaims(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	// token lama (sebelum ada tabel sessions) pakai "fid", nilainya sama
+	sessionID, ok := claims["sid"].(string)
+	if !ok {
+		sessionID, ok = claims["fid"].(string)
+	}
+	if !ok {
+		return nil, errors.New("token has no session, please login again")
+	}
+	sid, err := uuid.Parse(sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid session id: %w", err)
+	}
+
+	role, _ := claims["role"].(string)
+	restricted, _ := claims["restricted"].(bool)
+
+	return &Claims{UserID: uid, SessionID: sid, Role: role, Restricted: restricted}, nil
+}
+
+// ParseMFAPendingToken validasi token mfa_pending & ambil user_id-nya
+func (s *Service) ParseMFAPendingToken(tokenString string) (uuid.UUID, error) {
+	claims, err := s.parse(tokenString)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if typ, _ := claims["typ"].(string); typ != typeMFAPending {
+		return uuid.Nil, errors.New("not an mfa token")
+	}
+	return userIDFromClaims(claims)
+}
+
+// JWKS semua public key (termasuk kunci lama yang masih dipakai verifikasi)
+func (s *Service) JWKS() map[string][]JWK {
+	kids := make([]string, 0, len(s.keys))
+	for kid := range s.keys {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+
+	out := make([]JWK, 0, len(kids))
+	for _, kid := range kids {
+		out = append(out, s.keys[kid].jwk())
+	}
+	return map[string][]JWK{"keys": out}
+}
+
+func (s *Service) baseClaims(typ string, userID uuid.UUID, ttl time.Duration) jwt.MapClaims {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"typ":     typ,
+		"sub":     userID.String(),
+		"user_id": userID.String(),
+		"iat":     now.Unix(),
+		"exp":     now.Add(ttl).Unix(),
+	}
+	if s.issuer != "" {
+		claims["iss"] = s.issuer
+	}
+	return claims
+}
+
+func (s *Service) sign(claims jwt.MapClaims) (string, error) {
+	t := jwt.NewWithClaims(s.signer.method, claims)
+	t.Header["kid"] = s.signer.kid
+	return t.SignedString(s.signer.private)
+}
+
+// parse verifikasi signature (pilih kunci dari header kid), exp dan iss
+func (s *Service) parse(tokenString string) (jwt.MapClaims, error) {
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}),
+		jwt.WithExpirationRequired(),
+	}
+	if s.issuer != "" {
+		opts = append(opts, jwt.WithIssuer(s.issuer))
+	}
+
+	token, err := jwt.Parse(tokenString, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		k, ok := s.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown kid %q", kid)
+		}
+		if t.Method.Alg() != k.method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+		return k.public, nil
+	}, opts...)
+	if err != nil {
+		// 🔎 kasih detail biar ketahuan error aslinya
+		return nil, fmt.Errorf("token parse error: %w", err)
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, errors.New("invalid claims")
+	}
+	return claims, nil
+}
+
+func userIDFromClaims(claims jwt.MapClaims) (uuid.UUID, error) {
+	userID, ok := claims["user_id"].(string)
+	if !ok {
+		return uuid.Nil, fmt.Errorf("invalid user id, got type %T, value: %#v", claims["user_id"], claims["user_id"])
+	}
+
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("invalid UUID format: %w", err)
+	}
+	return uid, nil
+}
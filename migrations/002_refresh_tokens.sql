@@ -0,0 +1,13 @@
+-- refresh tokens (disimpan dalam bentuk hash, dirotasi tiap /auth/refresh)
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+id UUID PRIMARY KEY,
+user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+family_id UUID NOT NULL,
+token_hash TEXT NOT NULL UNIQUE,
+expires_at TIMESTAMPTZ NOT NULL,
+revoked_at TIMESTAMPTZ,
+replaced_by UUID,
+created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user ON refresh_tokens(user_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family ON refresh_tokens(family_id);
@@ -0,0 +1,10 @@
+-- password reset tokens (sekali pakai, disimpan dalam bentuk hash)
+CREATE TABLE IF NOT EXISTS password_reset_tokens (
+id UUID PRIMARY KEY,
+user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+token_hash TEXT NOT NULL UNIQUE,
+expires_at TIMESTAMPTZ NOT NULL,
+used_at TIMESTAMPTZ,
+created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_password_reset_tokens_user ON password_reset_tokens(user_id);
@@ -0,0 +1,11 @@
+-- verifikasi email
+-- user lama dianggap sudah terverifikasi (supaya tidak langsung kena mode restricted setelah deploy),
+-- backfill cuma jalan waktu kolom baru dibuat jadi aman kalau migration dijalankan ulang
+DO $$
+BEGIN
+IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'users' AND column_name = 'email_verified_at') THEN
+ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMPTZ;
+UPDATE users SET email_verified_at = created_at WHERE email_verified_at IS NULL;
+END IF;
+END $$;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS verification_sent_at TIMESTAMPTZ;
@@ -0,0 +1,15 @@
+-- personal API keys (disimpan dalam bentuk hash)
+CREATE TABLE IF NOT EXISTS api_keys (
+id UUID PRIMARY KEY,
+user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+name TEXT NOT NULL,
+prefix TEXT NOT NULL,
+key_hash TEXT NOT NULL UNIQUE,
+scopes JSONB NOT NULL DEFAULT '[]',
+expires_at TIMESTAMPTZ,
+last_used_at TIMESTAMPTZ,
+revoked_at TIMESTAMPTZ,
+created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_api_keys_user ON api_keys(user_id);
@@ -0,0 +1,22 @@
+-- akun OIDC (Google, Keycloak, ...) yang ter-link ke user
+CREATE TABLE IF NOT EXISTS user_identities (
+id UUID PRIMARY KEY,
+user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+provider TEXT NOT NULL,
+subject TEXT NOT NULL,
+email TEXT,
+created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+CONSTRAINT uq_identity_provider_subject UNIQUE (provider, subject)
+);
+CREATE INDEX IF NOT EXISTS idx_user_identities_user ON user_identities(user_id);
+
+-- state login OIDC yang sedang berjalan (PKCE verifier + nonce), sekali pakai
+CREATE TABLE IF NOT EXISTS oidc_states (
+id UUID PRIMARY KEY,
+state_hash TEXT NOT NULL UNIQUE,
+provider TEXT NOT NULL,
+code_verifier TEXT NOT NULL,
+nonce TEXT NOT NULL,
+expires_at TIMESTAMPTZ NOT NULL,
+created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,19 @@
+-- proteksi brute-force login
+ALTER TABLE users ADD COLUMN IF NOT EXISTS failed_login_count INT NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS last_failed_login_at TIMESTAMPTZ;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS locked_until TIMESTAMPTZ;
+
+-- riwayat event auth (login sukses / gagal / lockout / unlock)
+CREATE TABLE IF NOT EXISTS login_events (
+id UUID PRIMARY KEY,
+user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+email TEXT,
+event TEXT NOT NULL,
+method TEXT,
+reason TEXT,
+ip TEXT,
+user_agent TEXT,
+created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_login_events_user ON login_events(user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_login_events_ip ON login_events(ip, created_at);
@@ -0,0 +1,31 @@
+-- sesi login aktif (id = family_id refresh token, juga claim "sid" di JWT)
+CREATE TABLE IF NOT EXISTS sessions (
+id UUID PRIMARY KEY,
+user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+user_agent TEXT,
+ip TEXT,
+created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+last_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+expires_at TIMESTAMPTZ NOT NULL,
+revoked_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_sessions_user ON sessions(user_id);
+
+-- family refresh token yang sudah ada jadi sesi
+INSERT INTO sessions (id, user_id, created_at, last_seen_at, expires_at, revoked_at)
+SELECT family_id,
+MIN(user_id::text)::uuid,
+MIN(created_at),
+MAX(created_at),
+MAX(expires_at),
+CASE WHEN BOOL_AND(revoked_at IS NOT NULL) THEN MAX(revoked_at) END
+FROM refresh_tokens
+GROUP BY family_id
+ON CONFLICT (id) DO NOTHING;
+
+DO $$
+BEGIN
+IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'fk_refresh_tokens_session') THEN
+ALTER TABLE refresh_tokens ADD CONSTRAINT fk_refresh_tokens_session FOREIGN KEY (family_id) REFERENCES sessions(id) ON DELETE CASCADE;
+END IF;
+END $$;
@@ -0,0 +1,18 @@
+-- export data pribadi (zip), dibuat async oleh worker
+CREATE TABLE IF NOT EXISTS data_exports (
+id UUID PRIMARY KEY,
+user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+status TEXT NOT NULL DEFAULT 'pending',
+error TEXT,
+file_path TEXT,
+size_bytes BIGINT NOT NULL DEFAULT 0,
+created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+started_at TIMESTAMPTZ,
+completed_at TIMESTAMPTZ,
+expires_at TIMESTAMPTZ,
+CONSTRAINT chk_data_exports_status CHECK (status IN ('pending', 'running', 'ready', 'failed', 'expired'))
+);
+CREATE INDEX IF NOT EXISTS idx_data_exports_user ON data_exports(user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_data_exports_status ON data_exports(status, created_at);
+-- satu user cuma boleh punya satu export yang sedang diproses
+CREATE UNIQUE INDEX IF NOT EXISTS uq_data_exports_active ON data_exports(user_id) WHERE status IN ('pending', 'running');
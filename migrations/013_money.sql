@@ -0,0 +1,2 @@
+-- amount disimpan dengan 4 digit desimal supaya muat semua mata uang ISO 4217 (validasi per mata uang di aplikasi)
+ALTER TABLE expenses ALTER COLUMN amount TYPE NUMERIC(19,4);
@@ -0,0 +1,21 @@
+-- multi-currency: mata uang per expense & mata uang dasar per user
+-- 'IDR' = default DEFAULT_CURRENCY, sesuaikan sebelum migrate kalau server pakai mata uang lain
+ALTER TABLE users ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'IDR';
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS currency CHAR(3);
+UPDATE expenses e SET currency = u.currency FROM users u WHERE e.user_id = u.id AND e.currency IS NULL;
+ALTER TABLE expenses ALTER COLUMN currency SET NOT NULL;
+
+-- kurs harian: 1 base = rate quote
+CREATE TABLE IF NOT EXISTS exchange_rates (
+id UUID PRIMARY KEY,
+base CHAR(3) NOT NULL,
+quote CHAR(3) NOT NULL,
+rate NUMERIC(24,10) NOT NULL CHECK (rate > 0),
+rate_date DATE NOT NULL,
+source TEXT,
+created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+CONSTRAINT uq_exchange_rate UNIQUE (base, quote, rate_date)
+);
+CREATE INDEX IF NOT EXISTS idx_exchange_rates_base ON exchange_rates(base, rate_date);
+CREATE INDEX IF NOT EXISTS idx_exchange_rates_quote ON exchange_rates(quote, rate_date);
@@ -0,0 +1,9 @@
+-- tanggal transaksi terpisah dari waktu input; spent_tz zona IANA opsional dari user
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS spent_at TIMESTAMPTZ;
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS spent_tz TEXT;
+UPDATE expenses SET spent_at = created_at WHERE spent_at IS NULL;
+ALTER TABLE expenses ALTER COLUMN spent_at SET NOT NULL;
+ALTER TABLE expenses ALTER COLUMN spent_at SET DEFAULT NOW();
+
+-- filter tanggal & sort "date" sekarang lewat spent_at
+CREATE INDEX IF NOT EXISTS idx_expenses_user_spent_at ON expenses(user_id, spent_at DESC) WHERE deleted_at IS NULL;
@@ -0,0 +1,5 @@
+-- preferensi zona waktu, locale & awal minggu per user (0 = Minggu, 1 = Senin)
+-- default mengikuti DEFAULT_TIMEZONE / DEFAULT_LOCALE, sesuaikan sebelum migrate kalau beda
+ALTER TABLE users ADD COLUMN IF NOT EXISTS timezone TEXT NOT NULL DEFAULT 'Asia/Jakarta';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS locale TEXT NOT NULL DEFAULT 'id-ID';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS week_start SMALLINT NOT NULL DEFAULT 1 CHECK (week_start BETWEEN 0 AND 6);
@@ -0,0 +1,50 @@
+-- expense berulang: template + jadwal gaya RRULE, dibuat jadi expense oleh scheduler
+CREATE TABLE IF NOT EXISTS recurring_expenses (
+id UUID PRIMARY KEY,
+user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+category_id UUID NOT NULL REFERENCES categories(id) ON DELETE RESTRICT,
+title TEXT NOT NULL,
+description TEXT,
+amount NUMERIC(19,4) NOT NULL CHECK (amount > 0),
+currency CHAR(3) NOT NULL,
+freq TEXT NOT NULL,
+interval_count INT NOT NULL DEFAULT 1 CHECK (interval_count >= 1),
+starts_at TIMESTAMPTZ NOT NULL,
+timezone TEXT NOT NULL,
+until_date DATE,
+max_count INT CHECK (max_count > 0),
+next_index INT NOT NULL DEFAULT 0,
+next_run_at TIMESTAMPTZ,
+last_run_at TIMESTAMPTZ,
+created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+CONSTRAINT chk_recurring_expenses_freq CHECK (freq IN ('daily', 'weekly', 'monthly', 'yearly'))
+);
+CREATE INDEX IF NOT EXISTS idx_recurring_expenses_user ON recurring_expenses(user_id);
+-- next_run_at NULL = jadwal sudah selesai
+CREATE INDEX IF NOT EXISTS idx_recurring_expenses_due ON recurring_expenses(next_run_at) WHERE next_run_at IS NOT NULL;
+
+-- pengecualian per kejadian: dilewati atau diubah isinya
+CREATE TABLE IF NOT EXISTS recurring_expense_exceptions (
+recurring_id UUID NOT NULL REFERENCES recurring_expenses(id) ON DELETE CASCADE,
+occurrence_date DATE NOT NULL,
+action TEXT NOT NULL,
+title TEXT,
+description TEXT,
+amount NUMERIC(19,4) CHECK (amount > 0),
+category_id UUID REFERENCES categories(id) ON DELETE RESTRICT,
+created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+PRIMARY KEY (recurring_id, occurrence_date),
+CONSTRAINT chk_recurring_exceptions_action CHECK (action IN ('skip', 'override'))
+);
+
+-- expense hasil jadwal; unique supaya scheduler idempotent (kejadian yang sama tidak dibuat dua kali)
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS recurring_id UUID REFERENCES recurring_expenses(id) ON DELETE SET NULL;
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS occurrence_date DATE;
+DO $$
+BEGIN
+IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'uq_expenses_occurrence') THEN
+ALTER TABLE expenses ADD CONSTRAINT uq_expenses_occurrence UNIQUE (recurring_id, occurrence_date);
+END IF;
+END $$;
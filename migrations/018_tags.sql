@@ -0,0 +1,16 @@
+-- tag per user (label lintas kategori), nama disimpan huruf kecil
+CREATE TABLE IF NOT EXISTS tags (
+id UUID PRIMARY KEY,
+user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+name TEXT NOT NULL,
+created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+CONSTRAINT uq_user_tag UNIQUE (user_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS expense_tags (
+expense_id UUID NOT NULL REFERENCES expenses(id) ON DELETE CASCADE,
+tag_id UUID NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+PRIMARY KEY (expense_id, tag_id)
+);
+CREATE INDEX IF NOT EXISTS idx_expense_tags_tag ON expense_tags(tag_id);
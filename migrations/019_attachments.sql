@@ -0,0 +1,14 @@
+-- lampiran expense (foto / PDF struk), file-nya di blob store, tabel ini cuma metadata
+CREATE TABLE IF NOT EXISTS attachments (
+id UUID PRIMARY KEY,
+user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+expense_id UUID NOT NULL REFERENCES expenses(id) ON DELETE CASCADE,
+file_name TEXT NOT NULL,
+content_type TEXT NOT NULL,
+size_bytes BIGINT NOT NULL,
+sha256 TEXT NOT NULL,
+storage_key TEXT NOT NULL UNIQUE,
+created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_attachments_expense ON attachments(expense_id);
+CREATE INDEX IF NOT EXISTS idx_attachments_user ON attachments(user_id);
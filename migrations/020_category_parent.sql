@@ -0,0 +1,10 @@
+-- kategori bertingkat (mis. Food > Groceries), parent_id NULL = kategori utama.
+-- FK default (NO ACTION): parent yang masih punya anak tidak bisa dihapus, tapi hapus user
+-- (cascade ke semua kategorinya sekaligus) tetap jalan karena dicek di akhir statement
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS parent_id UUID REFERENCES categories(id);
+CREATE INDEX IF NOT EXISTS idx_categories_parent ON categories(parent_id);
+
+-- judul cukup unik di antara saudara, "Food > Other" dan "Transport > Other" boleh
+ALTER TABLE categories DROP CONSTRAINT IF EXISTS uq_user_category;
+CREATE UNIQUE INDEX IF NOT EXISTS uq_user_category_parent
+ON categories(user_id, COALESCE(parent_id, '00000000-0000-0000-0000-000000000000'::uuid), title);
@@ -0,0 +1,6 @@
+-- tampilan kategori untuk client: warna (#RRGGBB), key ikon, urutan manual, arsip
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS color TEXT;
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS icon TEXT;
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS sort_order INT NOT NULL DEFAULT 0;
+-- diarsipkan = disembunyikan dari pilihan kategori, expense lama tetap memakainya
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS archived_at TIMESTAMPTZ;
@@ -0,0 +1,24 @@
+-- budget bulanan: category_id NULL = budget total semua pengeluaran, selain itu termasuk subkategorinya.
+-- limit bisa berubah dari waktu ke waktu: satu baris per periode start_month..end_month (tanggal 1, inklusif, NULL = seterusnya);
+-- periode per kategori tidak boleh tumpang tindih, dicek di aplikasi (tanpa btree_gist)
+CREATE TABLE IF NOT EXISTS budgets (
+id UUID PRIMARY KEY,
+user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+category_id UUID REFERENCES categories(id) ON DELETE RESTRICT,
+amount NUMERIC(19,4) NOT NULL CHECK (amount > 0),
+currency CHAR(3) NOT NULL,
+start_month DATE NOT NULL CHECK (EXTRACT(DAY FROM start_month) = 1),
+end_month DATE CHECK (EXTRACT(DAY FROM end_month) = 1 AND end_month >= start_month),
+created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+-- database lama: FK masih CASCADE, hapus / merge kategori diam-diam ikut menghapus budget-nya
+DO $$
+BEGIN
+IF EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'budgets_category_id_fkey' AND confdeltype = 'c') THEN
+ALTER TABLE budgets DROP CONSTRAINT budgets_category_id_fkey,
+ADD CONSTRAINT budgets_category_id_fkey FOREIGN KEY (category_id) REFERENCES categories(id) ON DELETE RESTRICT;
+END IF;
+END $$;
+CREATE INDEX IF NOT EXISTS idx_budgets_user ON budgets(user_id, start_month);
+CREATE INDEX IF NOT EXISTS idx_budgets_category ON budgets(category_id);
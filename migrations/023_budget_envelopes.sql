@@ -0,0 +1,29 @@
+-- aturan rollover envelope per kategori: none = mulai dari nol tiap bulan,
+-- surplus = sisa dibawa ke bulan berikutnya, deficit = cuma kekurangan yang dibawa, full = dua-duanya
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS rollover TEXT NOT NULL DEFAULT 'none'
+CHECK (rollover IN ('none', 'surplus', 'deficit', 'full'));
+
+-- ledger alokasi envelope per bulan (tambahan di atas limit budget), tidak pernah diubah, cuma ditambah / dihapus.
+-- pindah uang antar envelope = dua baris (minus di asal, plus di tujuan) dengan transfer_id yang sama
+CREATE TABLE IF NOT EXISTS budget_allocations (
+id UUID PRIMARY KEY,
+user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+category_id UUID NOT NULL REFERENCES categories(id) ON DELETE RESTRICT,
+month DATE NOT NULL CHECK (EXTRACT(DAY FROM month) = 1),
+amount NUMERIC(19,4) NOT NULL CHECK (amount <> 0),
+currency CHAR(3) NOT NULL,
+transfer_id UUID,
+note TEXT,
+created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+-- database lama: FK masih CASCADE, hapus kategori diam-diam membuang satu sisi transfer
+DO $$
+BEGIN
+IF EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'budget_allocations_category_id_fkey' AND confdeltype = 'c') THEN
+ALTER TABLE budget_allocations DROP CONSTRAINT budget_allocations_category_id_fkey,
+ADD CONSTRAINT budget_allocations_category_id_fkey FOREIGN KEY (category_id) REFERENCES categories(id) ON DELETE RESTRICT;
+END IF;
+END $$;
+CREATE INDEX IF NOT EXISTS idx_budget_allocations_user ON budget_allocations(user_id, month);
+CREATE INDEX IF NOT EXISTS idx_budget_allocations_category ON budget_allocations(category_id);
+CREATE INDEX IF NOT EXISTS idx_budget_allocations_transfer ON budget_allocations(transfer_id) WHERE transfer_id IS NOT NULL;
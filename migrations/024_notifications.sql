@@ -0,0 +1,31 @@
+-- inbox notifikasi in-app, read_at NULL = belum dibaca
+CREATE TABLE IF NOT EXISTS notifications (
+id UUID PRIMARY KEY,
+user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+kind TEXT NOT NULL,
+title TEXT NOT NULL,
+body TEXT NOT NULL,
+data JSONB NOT NULL DEFAULT '{}',
+read_at TIMESTAMPTZ,
+created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_notifications_user ON notifications(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_notifications_unread ON notifications(user_id) WHERE read_at IS NULL;
+
+-- channel notifikasi per user (in-app selalu aktif), baris tidak ada = email aktif, tanpa webhook
+CREATE TABLE IF NOT EXISTS notification_settings (
+user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+email_enabled BOOLEAN NOT NULL DEFAULT TRUE,
+webhook_url TEXT,
+webhook_secret TEXT,
+updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- ambang batas budget yang sudah pernah dikirim, supaya tiap ambang cuma sekali per budget per bulan
+CREATE TABLE IF NOT EXISTS budget_alerts (
+budget_id UUID NOT NULL REFERENCES budgets(id) ON DELETE CASCADE,
+month DATE NOT NULL,
+threshold INT NOT NULL,
+created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+PRIMARY KEY (budget_id, month, threshold)
+);